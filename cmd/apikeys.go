@@ -0,0 +1,106 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/handler"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/auth"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/database/query"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/domain"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"github.com/gin-gonic/gin"
+)
+
+// apiKeyHeader carries the key of integrations calling admin routes.
+const apiKeyHeader = "X-API-Key"
+
+// apiKeyAuthorisation authenticates a request made with an API key as an admin holding only the
+// key's scopes, then records the call once it has been answered.
+func apiKeyAuthorisation(ctx *gin.Context, secret string) {
+	prefix, ok := auth.APIKeyPrefix(secret)
+	if !ok {
+		unauthorised(ctx, "invalid_api_key", "invalid API key")
+		return
+	}
+
+	if Client == nil {
+		_ = ctx.AbortWithError(http.StatusInternalServerError, errors.New("database client is not initialised"))
+		return
+	}
+
+	db := query.NewGoAppDB(&app, Client)
+
+	key, err := db.GetAPIKeyByPrefix(prefix)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			unauthorised(ctx, "invalid_api_key", "invalid API key")
+			return
+		}
+		_ = ctx.Error(err)
+		ctx.Abort()
+		return
+	}
+
+	if subtle.ConstantTimeCompare([]byte(auth.HashAPIKey(secret)), []byte(key.Hash)) != 1 {
+		unauthorised(ctx, "invalid_api_key", "invalid API key")
+		return
+	}
+
+	now := time.Now()
+
+	if key.RevokedAt != nil {
+		unauthorised(ctx, "api_key_revoked", "API key has been revoked")
+		return
+	}
+
+	if now.After(key.ExpiresAt) {
+		unauthorised(ctx, "api_key_expired", "API key has expired")
+		return
+	}
+
+	if err := db.TouchAPIKey(key.ID, ctx.ClientIP(), now); err != nil {
+		app.ErrorLogger.Printf("cannot record use of API key %s : %v", key.Prefix, err)
+	}
+
+	claims := &auth.GoAppClaims{
+		ID:          key.ID,
+		Name:        key.Name,
+		Principal:   auth.AdminPrincipal,
+		Role:        auth.APIKeyRole,
+		Permissions: key.Scopes,
+		Type:        auth.AccessToken,
+	}
+
+	ctx.Set("Claims", claims)
+	ctx.Set("UID", key.ID)
+	ctx.Set("Name", key.Name)
+	ctx.Set("Principal", claims.Principal)
+	ctx.Set("Role", claims.Role)
+	ctx.Set("APIKey", key)
+
+	ctx.Next()
+
+	// Errors left to ErrorHandler are only answered after this returns.
+	status := ctx.Writer.Status()
+	if last := ctx.Errors.Last(); last != nil && !ctx.Writer.Written() {
+		status = domain.Status(cause(last.Err))
+	}
+
+	call := &model.APIKeyCall{
+		KeyID:     key.ID,
+		Prefix:    key.Prefix,
+		Method:    ctx.Request.Method,
+		Path:      ctx.FullPath(),
+		Status:    status,
+		IP:        ctx.ClientIP(),
+		RequestID: handler.RequestID(ctx),
+		CreatedAt: now,
+	}
+
+	if err := db.RecordAPIKeyCall(call); err != nil {
+		app.ErrorLogger.Printf("cannot audit call made with API key %s : %v", key.Prefix, err)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/handler"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/auth"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/encrypt"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+)
+
+// BootstrapAdmin creates the very first admin from the BOOTSTRAP_ADMIN_* environment variables.
+// Every later admin has to be invited by an existing one, so it refuses to run once any admin exists.
+//
+//	BOOTSTRAP_ADMIN_EMAIL=... BOOTSTRAP_ADMIN_PASSWORD=... go run ./cmd bootstrap-admin
+func BootstrapAdmin(g *handler.GoApp) error {
+	email := strings.ToLower(os.Getenv("BOOTSTRAP_ADMIN_EMAIL"))
+	password := os.Getenv("BOOTSTRAP_ADMIN_PASSWORD")
+	name := os.Getenv("BOOTSTRAP_ADMIN_NAME")
+
+	if email == "" || password == "" {
+		return errors.New("BOOTSTRAP_ADMIN_EMAIL and BOOTSTRAP_ADMIN_PASSWORD must be set")
+	}
+
+	if name == "" {
+		name = "Administrator"
+	}
+
+	count, err := g.DB.CountAdmins()
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return errors.New("an admin already exists, invite further admins from /admin/invite-admin")
+	}
+
+	violations, err := encrypt.CheckPassword(password, email, name)
+	if err != nil {
+		return err
+	}
+
+	if len(violations) > 0 {
+		return fmt.Errorf("BOOTSTRAP_ADMIN_PASSWORD %s", violations[0].Message)
+	}
+
+	hashed, err := encrypt.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	admin := &model.Admin{
+		Name:      name,
+		Email:     email,
+		Password:  hashed,
+		Role:      auth.AdminRole,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	if _, _, err := g.DB.SignUpAdmin(admin); err != nil {
+		return err
+	}
+
+	app.InfoLogger.Printf("Bootstrapped admin %s", email)
+
+	return nil
+}
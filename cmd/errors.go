@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/handler"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorHandler answers requests that failed through ctx.Error without writing a body. The
+// status comes from the domain kind of the last error unless the handler already sent one,
+// and the body is the same envelope handlers answer errors with.
+func ErrorHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Next()
+
+		last := ctx.Errors.Last()
+		if last == nil || ctx.Writer.Size() > 0 {
+			return
+		}
+
+		err := cause(last.Err)
+
+		status := domain.Status(err)
+		code := domain.Code(err)
+		message := domain.Message(err)
+
+		if ctx.Writer.Written() && ctx.Writer.Status() != status {
+			status = ctx.Writer.Status()
+			code = handler.StatusCode(status)
+			if status < http.StatusInternalServerError {
+				message = err.Error()
+			}
+		}
+
+		handler.RespondError(ctx, status, code, message, nil)
+	}
+}
+
+// cause unwraps the gin.Error values handlers pass to AbortWithError, which do not unwrap
+// themselves, so the domain kind underneath can be matched.
+func cause(err error) error {
+	for {
+		ge, ok := err.(gin.Error)
+		if !ok {
+			break
+		}
+		err = ge.Err
+	}
+	if err == nil {
+		return errors.New(http.StatusText(http.StatusInternalServerError))
+	}
+	return err
+}
@@ -6,15 +6,24 @@ import (
 	"log"
 	"os"
 	"time"
+	// Sale time zones must load on hosts without a time zone database.
+	_ "time/tzdata"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 
 	"github.com/PraveenRajPurak/CarsGo-Backend/driver"
 	"github.com/PraveenRajPurak/CarsGo-Backend/handler"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/auth"
 	"github.com/PraveenRajPurak/CarsGo-Backend/modules/config"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/encrypt"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/mailer"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/oidc"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/pricing"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/search"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
 	"github.com/joho/godotenv"
 )
@@ -35,6 +44,15 @@ func main() {
 	app.ErrorLogger = ErrorLogger
 
 	validate = validator.New()
+	validate.SetTagName("binding")
+	handler.RegisterFieldNames(validate)
+	if err := handler.RegisterValidations(validate); err != nil {
+		app.ErrorLogger.Fatalf("cannot register validations : %v", err)
+	}
+
+	// Requests are bound into DTOs holding only what clients may set, so any other field is
+	// rejected rather than silently dropped.
+	binding.EnableDecoderDisallowUnknownFields = true
 
 	app.Validate = validate
 
@@ -44,6 +62,27 @@ func main() {
 	if err != nil {
 		app.ErrorLogger.Fatal("No .env file available")
 	}
+	if err := encrypt.ConfigureFromEnv(); err != nil {
+		app.ErrorLogger.Fatalf("cannot configure password hashing : %v", err)
+	}
+
+	if len(os.Args) > 2 && os.Args[1] == "generate-signing-key" {
+		file, err := auth.GenerateKeyFile(os.Getenv("JWT_KEY_DIR"), os.Args[2])
+		if err != nil {
+			app.ErrorLogger.Fatalf("cannot generate signing key : %v", err)
+		}
+		fmt.Println("Signing key written to", file)
+		return
+	}
+
+	if err := auth.LoadKeyring(os.Getenv("JWT_KEY_DIR"), os.Getenv("JWT_SIGNING_KID")); err != nil {
+		app.ErrorLogger.Fatalf("cannot load token signing keys : %v", err)
+	}
+
+	if os.Getenv("JWT_KEY_DIR") == "" {
+		app.ErrorLogger.Println("JWT_KEY_DIR is not set, signing tokens with an ephemeral key that is lost on restart")
+	}
+
 	URI := os.Getenv("MONGODB_URI")
 	fmt.Println("MongoDB URI : ", URI)
 
@@ -54,19 +93,87 @@ func main() {
 		fmt.Println("Connected to MongoDB!")
 	}
 
+	app.Mailer, err = mailer.FromEnv(app.InfoLogger)
+	if err != nil {
+		app.ErrorLogger.Fatalf("cannot configure the mailer : %v", err)
+	}
+
+	app.BaseURL = os.Getenv("APP_BASE_URL")
+	if app.BaseURL == "" {
+		app.BaseURL = "http://localhost:10010"
+	}
+
+	app.Pricing, err = pricing.FromEnv()
+	if err != nil {
+		app.ErrorLogger.Fatalf("cannot configure pricing : %v", err)
+	}
+
+	app.OIDC, err = oidc.FromEnv(app.BaseURL)
+	if err != nil {
+		app.ErrorLogger.Fatalf("cannot configure OIDC providers : %v", err)
+	}
+
+	app.Cookies, err = config.CookiesFromEnv()
+	if err != nil {
+		app.ErrorLogger.Fatalf("cannot configure cookies : %v", err)
+	}
+
+	if os.Getenv("SESSION_SECRETS") == "" {
+		app.ErrorLogger.Println("SESSION_SECRETS is not set, signing session cookies with an ephemeral key that is lost on restart")
+	}
+
+	if err := auth.InitDenylist(Client); err != nil {
+		app.ErrorLogger.Fatalf("cannot initialise the token denylist : %v", err)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "bootstrap-admin" {
+		if err := BootstrapAdmin(handler.NewGoApp(&app, Client)); err != nil {
+			app.ErrorLogger.Fatalf("cannot bootstrap the first admin : %v", err)
+		}
+		return
+	}
+
 	webserver := gin.New()
 
 	webserver.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:5173"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "PATCH", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "Admin_Authorization", "CSE_Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "Admin_Authorization", "CSE_Authorization", csrfHeader, apiKeyHeader, "X-Device-Name", "X-Request-ID"},
+		ExposeHeaders:    []string{"Content-Length", "X-Request-ID"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
 	GoApp := handler.NewGoApp(&app, Client)
 
+	if err := GoApp.DB.MigrateCategories(); err != nil {
+		app.ErrorLogger.Fatalf("cannot migrate categories : %v", err)
+	}
+
+	if err := GoApp.DB.MigrateSpecs(); err != nil {
+		app.ErrorLogger.Fatalf("cannot migrate product specs : %v", err)
+	}
+
+	if err := GoApp.DB.MigrateEmails(); err != nil {
+		app.ErrorLogger.Fatalf("cannot migrate emails : %v", err)
+	}
+
+	if err := GoApp.DB.EnsureIndexes(); err != nil {
+		app.ErrorLogger.Fatalf("cannot create database indexes : %v", err)
+	}
+
+	app.Search = search.New()
+	if err := GoApp.BuildSearchIndex(); err != nil {
+		app.ErrorLogger.Fatalf("cannot build the product search index : %v", err)
+	}
+
+	if err := GoApp.RefreshPrices(); err != nil {
+		app.ErrorLogger.Fatalf("cannot price products : %v", err)
+	}
+
+	GoApp.StartPriceScheduler()
+	app.InfoLogger.Println("Price scheduler started")
+
 	GoApp.StartIdleChatCloser()
 	app.InfoLogger.Println("Idle chat closer started")
 
@@ -2,53 +2,107 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
-	"fmt"
 	"net/http"
+	"regexp"
 	"strings"
 	"time"
 
+	"github.com/PraveenRajPurak/CarsGo-Backend/handler"
 	"github.com/PraveenRajPurak/CarsGo-Backend/modules/auth"
 	"github.com/PraveenRajPurak/CarsGo-Backend/modules/database/query"
-	"github.com/gin-contrib/sessions"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/domain"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func Authorisation() gin.HandlerFunc {
-
-	fmt.Println("Authorisation middleware")
+// requestIDPattern limits the request ids accepted from clients or proxies to ones safe to log and echo.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
 
+// RequestID tags every request with an id, reusing the X-Request-ID header when the caller sent a
+// usable one. The id is echoed back and included in error responses so they can be traced in the logs.
+func RequestID() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
+		id := ctx.GetHeader("X-Request-ID")
+		if !requestIDPattern.MatchString(id) {
+			b := make([]byte, 12)
+			if _, err := rand.Read(b); err == nil {
+				id = hex.EncodeToString(b)
+			} else {
+				id = primitive.NewObjectID().Hex()
+			}
+		}
 
-		cookieData := sessions.Default(ctx)
+		ctx.Set("RequestID", id)
+		ctx.Header("X-Request-ID", id)
 
-		fmt.Println("Inside Authorisation middleware. Cookie Data : ", cookieData)
+		ctx.Next()
+	}
+}
 
-		token_from_header := ctx.GetHeader("Authorization")
+// authorisationHeaders maps each principal type to the header its access token is sent in.
+var authorisationHeaders = map[string]string{
+	auth.UserPrincipal:  "Authorization",
+	auth.AdminPrincipal: "Admin_Authorization",
+	auth.CSEPrincipal:   "CSE_Authorization",
+}
 
-		fmt.Println("Inside Authorisation middleware. Token from header : ", token_from_header)
+// Authorisation authenticates the access token of the given principal type and makes its
+// claims available to the handlers. Admin routes also accept an API key in the X-API-Key header.
+// What the caller may do is decided by Permission.
+func Authorisation(principal string) gin.HandlerFunc {
 
-		token_from_header1 := strings.Replace(token_from_header, "Bearer ", "", 1)
+	header := authorisationHeaders[principal]
 
-		accessToken := token_from_header1
+	return func(ctx *gin.Context) {
 
-		if accessToken == "" {
+		accessToken := strings.Replace(ctx.GetHeader(header), "Bearer ", "", 1)
 
-			_ = ctx.AbortWithError(http.StatusUnauthorized, errors.New("unauthorized user"))
+		if key := ctx.GetHeader(apiKeyHeader); accessToken == "" && key != "" && principal == auth.AdminPrincipal {
+			apiKeyAuthorisation(ctx, key)
 			return
 		}
 
-		fmt.Println("Inside Authorisation middleware. Access Token : ", accessToken)
+		if accessToken == "" {
+			unauthorised(ctx, "token_missing", "unauthorized "+principal+" access")
+			return
+		}
 
 		claims, err := auth.Parse(accessToken)
 
-		fmt.Println("Inside Authorisation middleware. Claims : ", claims)
+		if err != nil {
+			unauthorised(ctx, auth.ErrorCode(err), err.Error())
+			return
+		}
+
+		if claims.Type != auth.AccessToken {
+			unauthorised(ctx, "wrong_token_type", "only access tokens can be used for access")
+			return
+		}
+
+		if claims.Principal != principal {
+			unauthorised(ctx, "wrong_principal", "unauthorized "+principal+" access")
+			return
+		}
 
+		revoked, err := auth.IsRevoked(claims)
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusUnauthorized, gin.Error{
-				Err: err})
+			_ = ctx.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+
+		if revoked {
+			unauthorised(ctx, "token_revoked", "token has been revoked")
+			return
+		}
+
+		if Client == nil {
+			_ = ctx.AbortWithError(http.StatusInternalServerError, errors.New("database client is not initialised"))
+			return
 		}
 
 		contex, cancel := context.WithTimeout(context.Background(), 100*time.Second)
@@ -59,183 +113,96 @@ func Authorisation() gin.HandlerFunc {
 
 		filter := bson.D{{Key: "email", Value: claims.Email}}
 
-		if Client == nil {
-			fmt.Print("Inside Authorisation middleware. Client is nil")
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{
-				Err: err})
-			fmt.Print("Inside Authorisation middleware. Client is nil. Exiting")
-			return
-		}
-
-		ins_err := query.User(Client, "user").FindOne(contex, filter).Decode(&res)
+		ins_err := query.Principal(Client, principal).FindOne(contex, filter).Decode(&res)
 
 		if ins_err != nil {
 			if ins_err == mongo.ErrNoDocuments {
-				_ = ctx.AbortWithError(http.StatusUnauthorized, errors.New("unauthorized user"))
+				unauthorised(ctx, "unknown_principal", "unauthorized "+principal)
 				return
 			}
 			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{
 				Err: ins_err,
 			})
+			return
 		}
 
-		ctx.Set("pass", accessToken)
-		ctx.Set("Email", claims.Email)
-		ctx.Set("UID", claims.ID)
-		ctx.Set("Name", claims.Name)
-
-		fmt.Println("Coming out of Authorisation middleware")
-		ctx.Next()
-	}
-}
-func Admin_Authorisation() gin.HandlerFunc {
-
-	fmt.Println("Admin Authorisation middleware")
-
-	return func(ctx *gin.Context) {
-
-		cookieData := sessions.Default(ctx)
-
-		fmt.Println("Inside Admin Authorisation middleware. Cookie Data : ", cookieData)
-
-		token_from_header := ctx.GetHeader("Admin_Authorization")
-
-		fmt.Println("Inside Admin Authorisation middleware. Token from header : ", token_from_header)
-
-		token_from_header1 := strings.Replace(token_from_header, "Bearer ", "", 1)
-
-		accessToken := token_from_header1
-
-		if accessToken == "" {
-
-			_ = ctx.AbortWithError(http.StatusUnauthorized, errors.New("unauthorized admin access"))
+		sessionID, err := primitive.ObjectIDFromHex(claims.Family)
+		if err != nil {
+			unauthorised(ctx, "session_revoked", "session has ended, sign in again")
 			return
 		}
 
-		fmt.Println("Inside Authorisation middleware. Access Token : ", accessToken)
-
-		claims, err := auth.Parse(accessToken)
-
-		fmt.Println("Inside Authorisation middleware. Claims : ", claims)
+		db := query.NewGoAppDB(&app, Client)
 
+		active, err := db.TouchLoginSession(sessionID, ctx.ClientIP(), time.Now())
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusUnauthorized, gin.Error{
-				Err: err})
+			_ = ctx.Error(err)
+			ctx.Abort()
+			return
 		}
 
-		contex, cancel := context.WithTimeout(context.Background(), 100*time.Second)
-
-		defer cancel()
-
-		var res bson.M
-
-		filter := bson.D{{Key: "email", Value: claims.Email}}
-
-		if Client == nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{
-				Err: err})
+		if !active {
+			unauthorised(ctx, "session_revoked", "session has ended, sign in again")
 			return
 		}
 
-		ins_err := query.User(Client, "admin").FindOne(contex, filter).Decode(&res)
-
-		if ins_err != nil {
+		// The role and permissions in the token are those of when it was issued. Roles are
+		// changed and reassigned without signing anyone out, so they are read again here.
+		role, _ := res["role"].(string)
+		if role == "" {
+			role = auth.DefaultRole(principal)
+		}
 
-			if ins_err == mongo.ErrNoDocuments {
-				fmt.Print("Inside Admin Authorisation middleware. Ins_err phase 2 problem-no document")
-				_ = ctx.AbortWithError(http.StatusUnauthorized, errors.New("unauthorized admin"))
+		permissions, err := handler.RolePermissions(db, role)
+		if err != nil {
+			if !errors.Is(err, domain.ErrNotFound) {
+				_ = ctx.Error(err)
+				ctx.Abort()
 				return
 			}
-			fmt.Print("Inside Admin Authorisation middleware. Ins_err phase 3 problem. Document found but still error")
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{
-				Err: ins_err,
-			})
+			// The role was deleted, which leaves it granting nothing.
+			permissions = nil
 		}
 
+		claims.Role = role
+		claims.Permissions = permissions
+
 		ctx.Set("pass", accessToken)
+		ctx.Set("Claims", claims)
 		ctx.Set("Email", claims.Email)
 		ctx.Set("UID", claims.ID)
 		ctx.Set("Name", claims.Name)
+		ctx.Set("Principal", claims.Principal)
+		ctx.Set("Role", claims.Role)
 
-		fmt.Println("Coming out of Authorisation middleware")
 		ctx.Next()
 	}
 }
 
-// In middlewares.go
-func CSE_Authorisation() gin.HandlerFunc {
-	fmt.Println("CSE Authorisation middleware")
+// unauthorised aborts the request with 401 and a machine readable code telling the client
+// whether to refresh its token, sign in again or give up.
+func unauthorised(ctx *gin.Context, code string, message string) {
+	handler.RespondError(ctx, http.StatusUnauthorized, code, message, nil)
+}
 
+// Permission only lets the request through when the caller's role, as Authorisation resolved
+// it, or its API key grants every listed permission. It must run after Authorisation.
+func Permission(permissions ...string) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		cookieData := sessions.Default(ctx)
-
-		fmt.Println("Inside CSE Authorisation middleware. Cookie Data : ", cookieData)
-
-		token_from_header := ctx.GetHeader("CSE_Authorization")
-
-		fmt.Println("Inside CSE Authorisation middleware. Token from header : ", token_from_header)
 
-		token_from_header1 := strings.Replace(token_from_header, "Bearer ", "", 1)
-
-		accessToken := token_from_header1
-
-		if accessToken == "" {
-			_ = ctx.AbortWithError(http.StatusUnauthorized, errors.New("unauthorized CSE access"))
+		claims, ok := ctx.MustGet("Claims").(*auth.GoAppClaims)
+		if !ok {
+			_ = ctx.AbortWithError(http.StatusUnauthorized, errors.New("unauthorized access"))
 			return
 		}
 
-		fmt.Println("Inside CSE Authorisation middleware. Access Token : ", accessToken)
-
-		claims, err := auth.Parse(accessToken)
-
-		fmt.Println("Inside CSE Authorisation middleware. Claims : ", claims)
-
-		if err != nil {
-			_ = ctx.AbortWithError(http.StatusUnauthorized, gin.Error{
-				Err: err})
-		}
-
-		contex, cancel := context.WithTimeout(context.Background(), 100*time.Second)
-
-		defer cancel()
-
-		var res bson.M
-
-		fmt.Println("Inside CSE Authorisation middleware. Email that is used for validation : ", claims.Email)
-
-		filter := bson.D{{Key: "email", Value: claims.Email}}
-
-		if Client == nil {
-			fmt.Print("Inside CSE Authorisation middleware. Client is nil")
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{
-				Err: err})
-			fmt.Print("Inside CSE Authorisation middleware. Client is nil. Exiting...")
-			return
-		}
-
-		ins_err := query.User(Client, "cses").FindOne(contex, filter).Decode(&res)
-
-		if ins_err != nil {
-			fmt.Print("Inside CSE Authorisation middleware. Ins_err phase 1 problem")
-			if ins_err == mongo.ErrNoDocuments {
-				fmt.Print("Inside CSE Authorisation middleware. Ins_err phase 2 problem-no document")
-				_ = ctx.AbortWithError(http.StatusUnauthorized, errors.New("unauthorized CSE"))
+		for _, p := range permissions {
+			if !claims.HasPermission(p) {
+				handler.RespondError(ctx, http.StatusForbidden, "missing_permission", "missing permission "+p, nil)
 				return
 			}
-			fmt.Print("Inside CSE Authorisation middleware. Ins_err phase 3 problem. Document found but still error")
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{
-				Err: ins_err,
-			})
 		}
 
-		ctx.Set("pass", accessToken)
-		ctx.Set("UID", claims.ID)
-		ctx.Set("Name", claims.Name)
-		ctx.Set("Email", claims.Email)
-
-		fmt.Print("Value successfully set in context : - ", ctx.Value("pass"), ctx.Value("UID"), ctx.Value("Name"), ctx.Value("Email"))
-
-		fmt.Println("Coming out of CSE Authorisation middleware")
 		ctx.Next()
 	}
 }
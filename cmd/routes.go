@@ -1,93 +1,154 @@
 package main
 
 import (
+	"net/http"
+
 	"github.com/PraveenRajPurak/CarsGo-Backend/handler"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/auth"
 	"github.com/gin-contrib/sessions"
-	"github.com/gin-contrib/sessions/cookie"
 	"github.com/gin-gonic/gin"
 )
 
 func Routes(r *gin.Engine, g *handler.GoApp) {
-	router := r.Use(gin.Logger(), gin.Recovery())
+	router := r.Use(RequestID(), gin.Logger(), gin.Recovery(), ErrorHandler())
+
+	r.NoRoute(func(ctx *gin.Context) {
+		handler.RespondError(ctx, http.StatusNotFound, "not_found", "route not found", nil)
+	})
 
-	userCookieStore := cookie.NewStore([]byte("user_cookie"))
-	adminCookieStore := cookie.NewStore([]byte("admin_cookie"))
-	cseCookieStore := cookie.NewStore([]byte("cse_cookie"))
-	router.Use(sessions.Sessions("user_session", userCookieStore))
+	userCookieStore := newCookieStore(g.App.Cookies)
+	adminCookieStore := newCookieStore(g.App.Cookies)
+	cseCookieStore := newCookieStore(g.App.Cookies)
+	router.Use(CSRF(g.App.Cookies))
+	router.Use(sessions.Sessions(userSessionCookie, userCookieStore))
 
 	router.GET("/", g.Home())
+	router.GET("/.well-known/jwks.json", g.JWKS())
+	router.GET("/csrf-token", g.CSRFToken())
 
 	router.POST("/sign-up", g.Sign_Up())
 	router.POST("/sign-in", g.Sign_In())
 	router.POST("/cse_login", g.CSELogin())
+	router.POST("/cse_login/2fa", g.VerifySecondFactor(auth.CSEPrincipal))
+	router.POST("/token/refresh", g.RefreshToken())
+	router.GET("/auth/oidc/:provider/login", g.OIDCLogin())
+	router.GET("/auth/oidc/:provider/callback", g.OIDCCallback())
+	router.POST("/forgot-password", g.RequestPasswordReset(auth.UserPrincipal))
+	router.POST("/reset-password", g.ConfirmPasswordReset(auth.UserPrincipal))
+	router.POST("/forgot-password-admin", g.RequestPasswordReset(auth.AdminPrincipal))
+	router.POST("/reset-password-admin", g.ConfirmPasswordReset(auth.AdminPrincipal))
+	router.GET("/verify-email", g.VerifyEmail())
 	router.POST("/get-single-product", g.Get_Single_Product())
-	router.GET("/get-all-users", g.Get_All_Users())
-	router.GET("/get-all-payments", g.Get_All_Payments())
 	router.GET("/get-all-categories", g.Get_All_Categories())
+	router.GET("/categories/:id", g.GetCategory())
 	router.GET("/view-all-products", g.ViewProducts())
-	router.GET("/get-cseData", g.GetAllCSEData())
-	router.GET("/get-all-orders", g.Get_All_The_Orders())
+	router.GET("/products/search", g.SearchProducts())
+	router.GET("/products/suggest", g.SuggestProducts())
+	router.GET("/products/compare", g.CompareProducts())
+	router.POST("/products/compare", g.SaveComparison())
+	router.GET("/products/compare/:id", g.GetComparison())
 	router.GET("/products/:productId/reviews", g.GetProductReviews())
+	router.GET("/products/:productId/price-history", g.GetPriceHistory())
 
 	router.POST("/sign-up-admin", g.Sign_Up_Admin())
-	router.POST("/sign-in-admin", sessions.Sessions("admin_session", adminCookieStore), g.Sign_In_Admin())
+	router.POST("/sign-in-admin", sessions.Sessions(adminSessionCookie, adminCookieStore), g.Sign_In_Admin())
+	router.POST("/sign-in-admin/2fa", sessions.Sessions(adminSessionCookie, adminCookieStore), g.VerifySecondFactor(auth.AdminPrincipal))
+	router.POST("/sign-in-admin/2fa/enroll", g.EnrollSecondFactorAtSignIn(auth.AdminPrincipal))
+	router.POST("/sign-in-admin/2fa/enroll/confirm", sessions.Sessions(adminSessionCookie, adminCookieStore), g.ConfirmSecondFactorAtSignIn(auth.AdminPrincipal))
 
 	protectedUsers := r.Group("/users")
-	protectedUsers.Use(Authorisation())
+	protectedUsers.Use(Authorisation(auth.UserPrincipal))
 
-	protectedUsers.POST("/forgot-password", g.ForgotPasswordUser())
-	protectedUsers.POST("update-email", g.Update_Email_User())
-	protectedUsers.POST("update-name", g.Update_Name_User())
-	protectedUsers.POST("update-phone", g.Update_Phone_User())
-	protectedUsers.POST("sign-out", g.SignOutUser())
-	protectedUsers.POST("add-to-wishlist", g.AddToWishList())
-	protectedUsers.POST("remove-from-wishlist", g.RemoveFromWishList())
-	protectedUsers.POST("add-to-cart", g.Add_To_Cart())
-	protectedUsers.POST("empty-cart", g.Empty_Cart())
-	protectedUsers.POST("remove-from-cart", g.Remove_From_Cart())
-	protectedUsers.POST("add-address", g.Add_Address())
-	protectedUsers.POST("initialize-user", g.Initialize_User())
-	protectedUsers.POST("place-order", g.Create_Order())
-	protectedUsers.POST("payment-creation", g.Payment_Creation())
-	protectedUsers.POST("shipment-creation", g.Shipment_Creation())
-	protectedUsers.GET("get-user-by-id", g.Get_User_By_Id())
-	protectedUsers.GET("get-user-orders", g.Get_User_Orders())
-	protectedUsers.POST("/create-chat", g.CreateChat())
-	protectedUsers.POST("/send-message", g.SendMessageAsUser())
-	protectedUsers.GET("/chat/:id", g.GetChatHistory())
-	protectedUsers.POST("/close-chat", g.CloseChat())
-	protectedUsers.POST("/reopen-chat", g.ReopenChat())
-	protectedUsers.POST("/reviews", g.CreateReview())
-	protectedUsers.GET("/reviews", g.GetUserReviews())
+	protectedUsers.POST("update-email", Permission(auth.PermAccountSelf), g.Update_Email_User())
+	protectedUsers.POST("/resend-verification", Permission(auth.PermAccountSelf), g.ResendVerification())
+	protectedUsers.POST("update-name", Permission(auth.PermAccountSelf), g.Update_Name_User())
+	protectedUsers.POST("update-phone", Permission(auth.PermAccountSelf), g.Update_Phone_User())
+	protectedUsers.POST("sign-out", Permission(auth.PermAccountSelf), g.SignOutUser())
+	protectedUsers.GET("/sessions", Permission(auth.PermAccountSelf), g.GetMySessions())
+	protectedUsers.DELETE("/sessions/:id", Permission(auth.PermAccountSelf), g.RevokeMySession())
+	protectedUsers.POST("add-to-wishlist", Permission(auth.PermCartWrite), g.AddToWishList())
+	protectedUsers.POST("remove-from-wishlist", Permission(auth.PermCartWrite), g.RemoveFromWishList())
+	protectedUsers.GET("cart", Permission(auth.PermCartWrite), g.GetCart())
+	protectedUsers.POST("add-to-cart", Permission(auth.PermCartWrite), g.Add_To_Cart())
+	protectedUsers.POST("empty-cart", Permission(auth.PermCartWrite), g.Empty_Cart())
+	protectedUsers.POST("remove-from-cart", Permission(auth.PermCartWrite), g.Remove_From_Cart())
+	protectedUsers.POST("add-address", Permission(auth.PermAccountSelf), g.Add_Address())
+	protectedUsers.POST("initialize-user", Permission(auth.PermAccountSelf), g.Initialize_User())
+	protectedUsers.POST("place-order", Permission(auth.PermOrdersPlace), g.Create_Order())
+	protectedUsers.POST("payment-creation", Permission(auth.PermOrdersPlace), g.Payment_Creation())
+	protectedUsers.POST("shipment-creation", Permission(auth.PermOrdersPlace), g.Shipment_Creation())
+	protectedUsers.GET("get-user-by-id", Permission(auth.PermAccountSelf), g.Get_User_By_Id())
+	protectedUsers.GET("get-user-orders", Permission(auth.PermAccountSelf), g.Get_User_Orders())
+	protectedUsers.POST("/create-chat", Permission(auth.PermChatUse), g.CreateChat())
+	protectedUsers.POST("/send-message", Permission(auth.PermChatUse), g.SendMessageAsUser())
+	protectedUsers.GET("/chat/:id", Permission(auth.PermChatUse), g.GetChatHistory())
+	protectedUsers.POST("/close-chat", Permission(auth.PermChatUse), g.CloseChat())
+	protectedUsers.POST("/reopen-chat", Permission(auth.PermChatUse), g.ReopenChat())
+	protectedUsers.POST("/reviews", Permission(auth.PermReviewsWrite), g.CreateReview())
+	protectedUsers.GET("/reviews", Permission(auth.PermAccountSelf), g.GetUserReviews())
 
 	protectedAdmin := r.Group("/admin")
-	protectedAdmin.Use(sessions.Sessions("admin_session", adminCookieStore))
-	protectedAdmin.Use(Admin_Authorisation())
-	protectedAdmin.POST("forgot-password", g.ForgotPasswordAdmin())
-	protectedAdmin.POST("create-category", g.CreateCategory())
-	protectedAdmin.POST("create-product", g.InsertProducts())
-	protectedAdmin.POST("create-products", g.InsertMultipleProducts())
-	protectedAdmin.POST("change-stock", g.Change_Stock())
-	protectedAdmin.POST("update-product", g.UpdateProduct())
-	protectedAdmin.POST("toggle-stock", g.ToggleStock())
-	protectedAdmin.POST("update-email", g.Update_Email_Admin())
-	protectedAdmin.POST("update-name", g.Update_Name_Admin())
-	protectedAdmin.POST("update-phone", g.Update_Phone_Admin())
-	protectedAdmin.POST("sign-out", g.SignOutAdmin())
-	protectedAdmin.POST("place-order", g.Create_Order())
-	protectedAdmin.GET("view-orders", g.Get_All_Orders())
-	protectedAdmin.DELETE("delete-product/:id", g.DeleteProduct())
-	protectedAdmin.POST("payment-creation", g.Payment_Creation())
-	protectedAdmin.DELETE("delete-order/:id", g.DeleteOrder())
-	protectedAdmin.POST("/create-cse", g.CreateCSE())
-	protectedAdmin.POST("/products/summarized-review", g.UpdateProductSummarizedReview())
+	protectedAdmin.Use(sessions.Sessions(adminSessionCookie, adminCookieStore))
+	protectedAdmin.Use(Authorisation(auth.AdminPrincipal))
+	protectedAdmin.POST("create-category", Permission(auth.PermCatalogWrite), g.CreateCategory())
+	protectedAdmin.PATCH("/categories/:id", Permission(auth.PermCatalogWrite), g.UpdateCategory())
+	protectedAdmin.POST("/categories/:id/move", Permission(auth.PermCatalogWrite), g.MoveCategory())
+	protectedAdmin.DELETE("/categories/:id", Permission(auth.PermCatalogWrite), g.DeleteCategory())
+	protectedAdmin.POST("create-product", Permission(auth.PermCatalogWrite), g.InsertProducts())
+	protectedAdmin.POST("create-products", Permission(auth.PermCatalogWrite), g.InsertMultipleProducts())
+	protectedAdmin.POST("change-stock", Permission(auth.PermCatalogWrite), g.Change_Stock())
+	protectedAdmin.POST("update-product", Permission(auth.PermCatalogWrite), g.UpdateProduct())
+	protectedAdmin.POST("/products/:id/variants", Permission(auth.PermCatalogWrite), g.AddVariant())
+	protectedAdmin.PATCH("/products/:id/variants/:variant_id", Permission(auth.PermCatalogWrite), g.UpdateVariant())
+	protectedAdmin.DELETE("/products/:id/variants/:variant_id", Permission(auth.PermCatalogWrite), g.DeleteVariant())
+	protectedAdmin.POST("toggle-stock", Permission(auth.PermCatalogWrite), g.ToggleStock())
+	protectedAdmin.POST("update-email", Permission(auth.PermAccountSelf), g.Update_Email_Admin())
+	protectedAdmin.POST("update-name", Permission(auth.PermAccountSelf), g.Update_Name_Admin())
+	protectedAdmin.POST("update-phone", Permission(auth.PermAccountSelf), g.Update_Phone_Admin())
+	protectedAdmin.POST("sign-out", Permission(auth.PermAccountSelf), g.SignOutAdmin())
+	protectedAdmin.GET("/sessions", Permission(auth.PermAccountSelf), g.GetMySessions())
+	protectedAdmin.DELETE("/sessions/:id", Permission(auth.PermAccountSelf), g.RevokeMySession())
+	protectedAdmin.POST("/2fa/enroll", Permission(auth.PermAccountSelf), g.StartTOTPEnrollment(auth.AdminPrincipal))
+	protectedAdmin.POST("/2fa/confirm", Permission(auth.PermAccountSelf), g.ConfirmTOTPEnrollment(auth.AdminPrincipal))
+	protectedAdmin.POST("/2fa/disable", Permission(auth.PermAccountSelf), g.DisableTOTP(auth.AdminPrincipal))
+	protectedAdmin.POST("place-order", Permission(auth.PermOrdersWrite), g.Create_Order())
+	protectedAdmin.GET("view-orders", Permission(auth.PermOrdersRead), g.Get_All_Orders())
+	protectedAdmin.DELETE("delete-product/:id", Permission(auth.PermCatalogDelete), g.DeleteProduct())
+	protectedAdmin.POST("payment-creation", Permission(auth.PermPaymentsWrite), g.Payment_Creation())
+	protectedAdmin.DELETE("delete-order/:id", Permission(auth.PermOrdersDelete), g.DeleteOrder())
+	protectedAdmin.POST("/create-cse", Permission(auth.PermCSEManage), g.CreateCSE())
+	protectedAdmin.POST("/revoke-user-sessions", Permission(auth.PermUsersManage), g.RevokeUserSessions())
+	protectedAdmin.GET("/users/:id/sessions", Permission(auth.PermUsersManage), g.GetUserSessions())
+	protectedAdmin.DELETE("/users/:id/sessions/:sessionId", Permission(auth.PermUsersManage), g.RevokeUserSession())
+	protectedAdmin.POST("/unlock-account", Permission(auth.PermUsersManage), g.UnlockAccount())
+	protectedAdmin.POST("/products/summarized-review", Permission(auth.PermCatalogWrite), g.UpdateProductSummarizedReview())
+	protectedAdmin.POST("/roles", Permission(auth.PermRolesManage), g.CreateRole())
+	protectedAdmin.GET("/roles", Permission(auth.PermRolesManage), g.GetAllRoles())
+	protectedAdmin.PUT("/roles", Permission(auth.PermRolesManage), g.UpdateRole())
+	protectedAdmin.DELETE("/roles/:name", Permission(auth.PermRolesManage), g.DeleteRole())
+	protectedAdmin.POST("/assign-role", Permission(auth.PermRolesManage), g.AssignRole())
+	protectedAdmin.POST("/invite-admin", Permission(auth.PermAdminsInvite), g.InviteAdmin())
+	protectedAdmin.GET("/get-all-users", Permission(auth.PermUsersRead), g.Get_All_Users())
+	protectedAdmin.GET("/get-all-payments", Permission(auth.PermPaymentsRead), g.Get_All_Payments())
+	protectedAdmin.GET("/get-cseData", Permission(auth.PermCSEManage), g.GetAllCSEData())
+	protectedAdmin.GET("/get-all-orders", Permission(auth.PermOrdersRead), g.Get_All_The_Orders())
+	protectedAdmin.GET("/security-policy", Permission(auth.PermSecurityManage), g.GetSecurityPolicy())
+	protectedAdmin.PUT("/security-policy", Permission(auth.PermSecurityManage), g.UpdateSecurityPolicy())
+	protectedAdmin.GET("/security-events", Permission(auth.PermSecurityManage), g.GetSecurityEvents())
+	protectedAdmin.POST("/api-keys", Permission(auth.PermAPIKeysManage), g.CreateAPIKey())
+	protectedAdmin.GET("/api-keys", Permission(auth.PermAPIKeysManage), g.GetAPIKeys())
+	protectedAdmin.DELETE("/api-keys/:id", Permission(auth.PermAPIKeysManage), g.RevokeAPIKey())
+	protectedAdmin.GET("/api-keys/:id/calls", Permission(auth.PermAPIKeysManage), g.GetAPIKeyCalls())
 
 	protectedCSE := r.Group("/cse")
-	protectedCSE.Use(sessions.Sessions("cse_session", cseCookieStore))
-	protectedCSE.Use(CSE_Authorisation())
-	protectedCSE.POST("/logout", g.CSELogout())
-	protectedCSE.POST("/send-message", g.SendMessageAsCSE())
-	protectedCSE.GET("/chat/:id", g.GetChatHistory())
-	protectedCSE.POST("/move-chat-to-active", g.MoveChatToActive())
+	protectedCSE.Use(sessions.Sessions(cseSessionCookie, cseCookieStore))
+	protectedCSE.Use(Authorisation(auth.CSEPrincipal))
+	protectedCSE.POST("/logout", Permission(auth.PermAccountSelf), g.CSELogout())
+	protectedCSE.POST("/2fa/enroll", Permission(auth.PermAccountSelf), g.StartTOTPEnrollment(auth.CSEPrincipal))
+	protectedCSE.POST("/2fa/confirm", Permission(auth.PermAccountSelf), g.ConfirmTOTPEnrollment(auth.CSEPrincipal))
+	protectedCSE.POST("/2fa/disable", Permission(auth.PermAccountSelf), g.DisableTOTP(auth.CSEPrincipal))
+	protectedCSE.POST("/send-message", Permission(auth.PermChatHandle), g.SendMessageAsCSE())
+	protectedCSE.GET("/chat/:id", Permission(auth.PermChatHandle), g.GetChatHistory())
+	protectedCSE.POST("/move-chat-to-active", Permission(auth.PermChatHandle), g.MoveChatToActive())
 
 }
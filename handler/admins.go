@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/auth"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/dto"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const adminInviteLifetime = 72 * time.Hour
+
+// redactedFields never leave the API, whichever endpoint returns the document.
+var redactedFields = []string{
+	"password", "token", "new_token", "token_family",
+	"totp_secret", "totp_pending_secret", "totp_last_step", "recovery_codes", "mfa_challenge", "mfa_challenge_failures",
+}
+
+// redact removes credentials and tokens from documents before they are returned.
+func redact(docs []primitive.M) []primitive.M {
+	for _, doc := range docs {
+		redactDoc(doc)
+	}
+	return docs
+}
+
+func redactDoc(doc primitive.M) primitive.M {
+	for _, field := range redactedFields {
+		delete(doc, field)
+	}
+	return doc
+}
+
+// InviteAdmin issues a signed, single use invite that Sign_Up_Admin must redeem to create an admin.
+func (ga *GoApp) InviteAdmin() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var input struct {
+			Email string `json:"email" binding:"required,email"`
+			Role  string `json:"role"`
+		}
+
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			badRequest(ctx, err)
+			return
+		}
+
+		if input.Role == "" {
+			input.Role = auth.AdminRole
+		}
+
+		if input.Role == auth.CustomerRole || input.Role == auth.CSERole {
+			respondError(ctx, http.StatusBadRequest, "Role cannot be assigned to an admin")
+			return
+		}
+
+		if _, err := ga.rolePermissions(input.Role); err != nil {
+			respondError(ctx, http.StatusNotFound, "Role not found")
+			return
+		}
+
+		invite := &model.AdminInvite{
+			Email:     dto.NormalizeEmail(input.Email),
+			Role:      input.Role,
+			InvitedBy: ctx.MustGet("UID").(primitive.ObjectID),
+			ExpiresAt: time.Now().Add(adminInviteLifetime),
+			CreatedAt: time.Now(),
+		}
+
+		if err := ga.DB.CreateAdminInvite(invite); err != nil {
+			respondError(ctx, http.StatusInternalServerError, "Failed to create invite")
+			return
+		}
+
+		token, err := auth.GenerateActionToken(auth.PurposeAdminInvite, invite.ID.Hex(), invite.Email, adminInviteLifetime)
+		if err != nil {
+			respondError(ctx, http.StatusInternalServerError, "Failed to sign invite")
+			return
+		}
+
+		ga.App.InfoLogger.Printf("Admin %s invited %s as %s", invite.InvitedBy.Hex(), invite.Email, invite.Role)
+
+		ctx.JSON(http.StatusCreated, gin.H{
+			"message":      "Invite created successfully",
+			"invite_token": token,
+			"email":        invite.Email,
+			"role":         invite.Role,
+			"expires_at":   invite.ExpiresAt,
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/auth"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// API keys live for defaultAPIKeyDays unless created with a lifetime of up to maxAPIKeyDays.
+const (
+	defaultAPIKeyDays = 90
+	maxAPIKeyDays     = 365
+)
+
+// CreateAPIKey issues a key for an integration. The key is only ever shown in this response;
+// an admin may only grant scopes it holds itself.
+func (ga *GoApp) CreateAPIKey() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var input struct {
+			Name          string   `json:"name" binding:"required"`
+			Scopes        []string `json:"scopes" binding:"required,min=1"`
+			ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,min=1,max=365"`
+		}
+
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			badRequest(ctx, err)
+			return
+		}
+
+		claims := ctx.MustGet("Claims").(*auth.GoAppClaims)
+
+		for _, scope := range input.Scopes {
+			if !auth.IsAPIKeyScope(scope) {
+				respondError(ctx, http.StatusBadRequest, fmt.Sprintf("API keys cannot be granted %q", scope))
+				return
+			}
+			if !claims.HasPermission(scope) {
+				respondError(ctx, http.StatusForbidden, fmt.Sprintf("cannot grant %q without holding it", scope))
+				return
+			}
+		}
+
+		days := input.ExpiresInDays
+		if days == 0 {
+			days = defaultAPIKeyDays
+		}
+
+		secret, prefix, err := auth.GenerateAPIKey()
+		if err != nil {
+			internalError(ctx, err, "Failed to generate API key")
+			return
+		}
+
+		now := time.Now()
+
+		key := &model.APIKey{
+			Name:      strings.TrimSpace(input.Name),
+			Prefix:    prefix,
+			Hash:      auth.HashAPIKey(secret),
+			Scopes:    input.Scopes,
+			CreatedBy: claims.ID,
+			CreatedAt: now,
+			ExpiresAt: now.AddDate(0, 0, days),
+		}
+
+		if err := ga.DB.CreateAPIKey(key); err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+
+		ga.securityEvent(&model.SecurityEvent{
+			Type:       "api_key_created",
+			Principal:  auth.AdminPrincipal,
+			Identifier: prefix,
+			IP:         ctx.ClientIP(),
+			Actor:      claims.ID,
+			Details:    map[string]interface{}{"name": key.Name, "scopes": key.Scopes},
+		})
+
+		ctx.JSON(http.StatusCreated, gin.H{
+			"message": "API key created, store it now as it cannot be shown again",
+			"key":     secret,
+			"data":    key,
+		})
+	}
+}
+
+// GetAPIKeys lists every API key without its secret.
+func (ga *GoApp) GetAPIKeys() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		keys, err := ga.DB.ListAPIKeys()
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "API keys fetched successfully", "data": keys})
+	}
+}
+
+// RevokeAPIKey stops a key from being accepted from now on.
+func (ga *GoApp) RevokeAPIKey() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+		if err != nil {
+			respondError(ctx, http.StatusBadRequest, "Invalid API key ID format")
+			return
+		}
+
+		if err := ga.DB.RevokeAPIKey(id); err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+
+		claims := ctx.MustGet("Claims").(*auth.GoAppClaims)
+
+		ga.securityEvent(&model.SecurityEvent{
+			Type:      "api_key_revoked",
+			Principal: auth.AdminPrincipal,
+			IP:        ctx.ClientIP(),
+			Actor:     claims.ID,
+			Details:   map[string]interface{}{"key_id": id.Hex()},
+		})
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "API key revoked successfully"})
+	}
+}
+
+// GetAPIKeyCalls returns the audit trail of the latest calls made with a key.
+func (ga *GoApp) GetAPIKeyCalls() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+		if err != nil {
+			respondError(ctx, http.StatusBadRequest, "Invalid API key ID format")
+			return
+		}
+
+		calls, err := ga.DB.GetAPIKeyCalls(id, 200)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "API key calls fetched successfully", "data": calls})
+	}
+}
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/domain"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/dto"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// categoryOf returns the category a product is filed under, answering the request when it does
+// not exist. field names the request field the id came from.
+func (ga *GoApp) categoryOf(ctx *gin.Context, field string, id primitive.ObjectID) (model.Category, bool) {
+	category, err := ga.DB.GetCategory(id)
+	if errors.Is(err, domain.ErrNotFound) {
+		RespondError(ctx, http.StatusUnprocessableEntity, "validation_failed", "Some fields are invalid", []FieldError{
+			{Field: field, Rule: "exists", Message: "must be an existing category"},
+		})
+		return category, false
+	}
+	if err != nil {
+		abortWithError(ctx, err)
+		return category, false
+	}
+	return category, true
+}
+
+// reindexCategory refreshes the search index for the products of a category, whose name they carry.
+func (ga *GoApp) reindexCategory(id primitive.ObjectID) {
+	products, err := ga.DB.GetProductsByCategory(id)
+	if err != nil {
+		ga.App.ErrorLogger.Printf("cannot reindex products of category %s : %v", id.Hex(), err)
+		return
+	}
+	for i := range products {
+		ga.indexProduct(&products[i])
+	}
+}
+
+// GetCategory returns a category with its product counts, the breadcrumbs leading to it and its
+// direct subcategories.
+func (ga *GoApp) GetCategory() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+		if err != nil {
+			respondError(ctx, http.StatusBadRequest, "Invalid category ID format")
+			return
+		}
+
+		categories, err := ga.DB.GetAllCategories()
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+
+		counts, err := ga.DB.CountProductsByCategory()
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+
+		var category *dto.CategoryView
+		children := []dto.CategoryView{}
+		for _, view := range dto.CategoryViews(categories, counts) {
+			switch {
+			case view.ID == id:
+				v := view
+				category = &v
+			case view.ParentID != nil && *view.ParentID == id:
+				children = append(children, view)
+			}
+		}
+
+		if category == nil {
+			respondError(ctx, http.StatusNotFound, "category not found")
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"data":        category,
+			"breadcrumbs": dto.Breadcrumbs(category.Category, categories),
+			"children":    children,
+		})
+	}
+}
+
+// UpdateCategory edits a category. Renaming it renames it on its products too, and a new slug
+// changes the paths of its whole subtree.
+func (ga *GoApp) UpdateCategory() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+		if err != nil {
+			respondError(ctx, http.StatusBadRequest, "Invalid category ID format")
+			return
+		}
+
+		var input dto.UpdateCategoryRequest
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			badRequest(ctx, err)
+			return
+		}
+
+		changes := input.ToChanges()
+
+		category, err := ga.DB.UpdateCategory(id, changes)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+
+		if changes.Name != nil {
+			ga.reindexCategory(id)
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "Category updated successfully", "data": category})
+	}
+}
+
+// MoveCategory moves a category and its subcategories under another parent, or to the root.
+func (ga *GoApp) MoveCategory() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+		if err != nil {
+			respondError(ctx, http.StatusBadRequest, "Invalid category ID format")
+			return
+		}
+
+		var input dto.MoveCategoryRequest
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			badRequest(ctx, err)
+			return
+		}
+
+		category, err := ga.DB.MoveCategory(id, input.ParentID)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+
+		ga.App.InfoLogger.Printf("Category %s moved to %s by %s", id.Hex(), category.Path, ctx.MustGet("UID").(primitive.ObjectID).Hex())
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "Category moved successfully", "data": category})
+	}
+}
+
+// DeleteCategory deletes a category. When it still holds products or subcategories, the
+// reassign_to query parameter must name the category taking them over, or it is kept.
+func (ga *GoApp) DeleteCategory() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+		if err != nil {
+			respondError(ctx, http.StatusBadRequest, "Invalid category ID format")
+			return
+		}
+
+		var reassignTo *primitive.ObjectID
+		if raw := ctx.Query("reassign_to"); raw != "" {
+			target, err := primitive.ObjectIDFromHex(raw)
+			if err != nil {
+				respondError(ctx, http.StatusBadRequest, "Invalid reassign_to category ID format")
+				return
+			}
+			reassignTo = &target
+		}
+
+		if err := ga.DB.DeleteCategory(id, reassignTo); err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+
+		if reassignTo != nil {
+			ga.reindexCategory(*reassignTo)
+		}
+
+		ga.App.InfoLogger.Printf("Category %s deleted by %s", id.Hex(), ctx.MustGet("UID").(primitive.ObjectID).Hex())
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "Category deleted successfully"})
+	}
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/dto"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/units"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// comparisonLifetime is how long a saved comparison can be opened from its link.
+const comparisonLifetime = 90 * 24 * time.Hour
+
+// comparedProducts returns the products to compare, answering the request when one of them
+// does not exist. field names the request field the ids came from.
+func (ga *GoApp) comparedProducts(ctx *gin.Context, field string, ids []primitive.ObjectID) ([]model.Product, bool) {
+	products, err := ga.DB.GetProducts(ids)
+	if err != nil {
+		abortWithError(ctx, err)
+		return nil, false
+	}
+
+	if len(products) < len(ids) {
+		found := make(map[primitive.ObjectID]bool, len(products))
+		for _, p := range products {
+			found[p.ID] = true
+		}
+		details := []FieldError{}
+		for i, id := range ids {
+			if !found[id] {
+				details = append(details, FieldError{Field: fmt.Sprintf("%s[%d]", field, i), Rule: "exists", Message: "must be an existing product"})
+			}
+		}
+		RespondError(ctx, http.StatusUnprocessableEntity, "validation_failed", "Some fields are invalid", details)
+		return nil, false
+	}
+
+	return products, true
+}
+
+func (ga *GoApp) comparisonLink(id primitive.ObjectID) string {
+	return strings.TrimRight(ga.App.BaseURL, "/") + "/products/compare/" + id.Hex()
+}
+
+// CompareProducts lines up two to four products side by side, e.g.
+// /products/compare?ids=...&ids=...&units=imperial.
+func (ga *GoApp) CompareProducts() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var input dto.CompareRequest
+		if err := ctx.ShouldBindQuery(&input); err != nil {
+			badRequest(ctx, err)
+			return
+		}
+
+		products, ok := ga.comparedProducts(ctx, "ids", input.ProductIDs())
+		if !ok {
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"data": dto.NewComparison(products, units.System(input.Units), ga.App.Pricing, time.Now())})
+	}
+}
+
+// SaveComparison saves a comparison and returns the link it can be shared with.
+func (ga *GoApp) SaveComparison() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var input dto.SaveComparisonRequest
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			badRequest(ctx, err)
+			return
+		}
+
+		products, ok := ga.comparedProducts(ctx, "product_ids", input.ProductIDs)
+		if !ok {
+			return
+		}
+
+		comparison := &model.Comparison{
+			ProductIDs: input.ProductIDs,
+			ExpiresAt:  time.Now().Add(comparisonLifetime),
+		}
+		if err := ga.DB.SaveComparison(comparison); err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+
+		view := dto.NewComparison(products, units.Metric, ga.App.Pricing, time.Now())
+		view.ID = &comparison.ID
+		view.Link = ga.comparisonLink(comparison.ID)
+
+		ctx.JSON(http.StatusCreated, gin.H{"message": "Comparison saved successfully", "data": view, "expires_at": comparison.ExpiresAt})
+	}
+}
+
+// GetComparison opens a saved comparison with today's prices and ratings. Products deleted since
+// it was saved are left out.
+func (ga *GoApp) GetComparison() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+		if err != nil {
+			respondError(ctx, http.StatusBadRequest, "Invalid comparison ID format")
+			return
+		}
+
+		var input dto.ComparisonQuery
+		if err := ctx.ShouldBindQuery(&input); err != nil {
+			badRequest(ctx, err)
+			return
+		}
+
+		comparison, err := ga.DB.GetComparison(id)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+
+		products, err := ga.DB.GetProducts(comparison.ProductIDs)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+
+		view := dto.NewComparison(products, units.System(input.Units), ga.App.Pricing, time.Now())
+		view.ID = &comparison.ID
+		view.Link = ga.comparisonLink(comparison.ID)
+
+		ctx.JSON(http.StatusOK, gin.H{"data": view, "expires_at": comparison.ExpiresAt})
+	}
+}
@@ -0,0 +1,18 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// abortWithError stops the request and leaves err to the error middleware, which answers
+// with the status and envelope matching the kind of err, e.g. 404 for a missing product.
+func abortWithError(ctx *gin.Context, err error) {
+	if err == nil {
+		err = errors.New(http.StatusText(http.StatusInternalServerError))
+	}
+	_ = ctx.Error(err)
+	ctx.Abort()
+}
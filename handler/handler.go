@@ -11,11 +11,13 @@ import (
 	"github.com/PraveenRajPurak/CarsGo-Backend/modules/config"
 	"github.com/PraveenRajPurak/CarsGo-Backend/modules/database"
 	"github.com/PraveenRajPurak/CarsGo-Backend/modules/database/query"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/domain"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/dto"
 	"github.com/PraveenRajPurak/CarsGo-Backend/modules/encrypt"
 	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/units"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
@@ -42,60 +44,53 @@ func (ga *GoApp) Home() gin.HandlerFunc {
 
 func (ga *GoApp) Sign_Up() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		var user *model.User
+		var input dto.SignUpRequest
 
-		err := ctx.ShouldBindJSON(&user)
+		err := ctx.ShouldBindJSON(&input)
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusBadRequest, gin.Error{
-				Err: err,
-			})
+			badRequest(ctx, err)
+			return
 		}
 
+		user := input.ToUser()
+
 		user.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		user.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 
-		user.Password, _ = encrypt.Hash(user.Password)
+		if weakPassword(ctx, user.Password, user.Email, user.Name) {
+			return
+		}
 
-		user.Addresses = []model.Address{}
-		user.Cart = []model.CartItems{}
-		user.Orders = []primitive.ObjectID{}
-		user.Payments = []primitive.ObjectID{}
-		user.Shipments = []primitive.ObjectID{}
-		user.Wishlist = []primitive.ObjectID{}
+		user.Password, err = encrypt.Hash(user.Password)
 
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
-		}
-
-		if err := ga.App.Validate.Struct(&user); err != nil {
-			if _, ok := err.(*validator.InvalidValidationError); !ok {
-				_ = ctx.AbortWithError(http.StatusBadRequest, gin.Error{Err: err})
-				ga.App.InfoLogger.Println(err)
-				return
-			}
+			abortWithError(ctx, err)
+			return
 		}
 
 		ok, status, err := ga.DB.InsertUser(user)
 
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, errors.New("error while adding new user"))
-			ctx.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			abortWithError(ctx, err)
 			return
 		}
 
 		if !ok {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, err)
+			abortWithError(ctx, err)
 			return
 		}
 
 		switch status {
 		case 1:
 			{
-				ctx.JSON(http.StatusCreated, gin.H{"message": "User created successfully"})
+				if err := ga.sendVerificationEmail(user.ID, user.Email); err != nil {
+					ga.App.ErrorLogger.Printf("Error sending verification email to %s: %v", user.Email, err)
+				}
+				ctx.JSON(http.StatusCreated, gin.H{"message": "User created successfully, please verify your email"})
 			}
 		case 2:
 			{
-				ctx.JSON(http.StatusConflict, gin.H{"message": "User already exists"})
+				respondError(ctx, http.StatusConflict, "User already exists")
 			}
 		}
 	}
@@ -104,20 +99,31 @@ func (ga *GoApp) Sign_Up() gin.HandlerFunc {
 func (ga *GoApp) Sign_In() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 
-		var user *model.User
+		var user dto.SignInRequest
 		if err := ctx.ShouldBindJSON(&user); err != nil {
-			_ = ctx.AbortWithError(http.StatusBadRequest, gin.Error{Err: err})
+			badRequest(ctx, err)
+			return
 		}
 
+		user.Normalize()
+
 		regMail := regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
 		ok := regMail.MatchString(user.Email)
 
 		if ok {
 
+			if ga.loginLocked(ctx, auth.UserPrincipal, user.Email) {
+				return
+			}
+
 			res, err := ga.DB.VerifyUser(user.Email)
 			if err != nil {
-				_ = ctx.AbortWithError(http.StatusInternalServerError, err)
-				ctx.JSON(http.StatusUnauthorized, gin.H{"message": "unregistered user"})
+				if !errors.Is(err, domain.ErrNotFound) {
+					abortWithError(ctx, err)
+					return
+				}
+				ga.recordLoginFailure(ctx, auth.UserPrincipal, user.Email)
+				respondError(ctx, http.StatusUnauthorized, "unregistered user")
 				return
 			}
 
@@ -126,13 +132,17 @@ func (ga *GoApp) Sign_In() gin.HandlerFunc {
 
 			verified, err := encrypt.VerifyPassword(user.Password, password)
 			if err != nil {
-				_ = ctx.AbortWithError(http.StatusInternalServerError, err)
-				ctx.JSON(http.StatusUnauthorized, gin.H{"message": "unregistered user detected using wrong password"})
+				ga.recordLoginFailure(ctx, auth.UserPrincipal, user.Email)
+				respondError(ctx, http.StatusUnauthorized, "unregistered user detected using wrong password")
 				return
 			}
 
 			if verified {
 
+				ga.clearLoginFailures(auth.UserPrincipal, user.Email)
+
+				ga.rehashPassword(auth.UserPrincipal, res["_id"].(primitive.ObjectID), user.Password, password)
+
 				cookieData := sessions.Default(ctx)
 
 				userInfo := map[string]interface{}{
@@ -141,35 +151,31 @@ func (ga *GoApp) Sign_In() gin.HandlerFunc {
 					"Name":  res["name"],
 				}
 
-				cookieData.Options(sessions.Options{
-					Path:     "/",
-					HttpOnly: true,
-					Secure:   false,
-					SameSite: http.SameSiteNoneMode,
-				})
-
 				cookieData.Set("userInfo", userInfo)
 				if err := cookieData.Save(); err != nil {
-					_ = ctx.AbortWithError(http.StatusInternalServerError, err)
-					ctx.JSON(http.StatusInternalServerError, gin.H{"message": "error while saving cookie"})
+					internalError(ctx, err, "error while saving cookie")
 					return
 				}
 
-				t1, t2, err := auth.Generate(user.Email, id, res["name"].(string))
+				subject, err := ga.subjectFor(auth.UserPrincipal, res)
 
 				if err != nil {
-					_ = ctx.AbortWithError(http.StatusInternalServerError, err)
-					ctx.JSON(http.StatusInternalServerError, gin.H{"message": "error while generating tokens"})
+					ga.App.ErrorLogger.Println(err)
+					respondError(ctx, http.StatusInternalServerError, "error while resolving role")
 					return
 				}
 
-				cookieData.Set("token", t1)
+				family, t1, t2, err := ga.startSession(ctx, subject)
+
+				if err != nil {
+					internalError(ctx, err, "error while generating tokens")
+					return
+				}
 
-				ctx.SetCookie("user_session", t1, 3600, "/", "localhost", false, true)
+				cookieData.Set("token", t1)
 
 				if err := cookieData.Save(); err != nil {
-					_ = ctx.AbortWithError(http.StatusInternalServerError, err)
-					ctx.JSON(http.StatusInternalServerError, gin.H{"message": "error while saving cookie"})
+					internalError(ctx, err, "error while saving cookie")
 					return
 				}
 
@@ -183,8 +189,7 @@ func (ga *GoApp) Sign_In() gin.HandlerFunc {
 				cookieData.Set("new_token", t2)
 
 				if err := cookieData.Save(); err != nil {
-					_ = ctx.AbortWithError(http.StatusInternalServerError, err)
-					ctx.JSON(http.StatusInternalServerError, gin.H{"message": "error while saving cookie"})
+					internalError(ctx, err, "error while saving cookie")
 					return
 				}
 
@@ -193,19 +198,18 @@ func (ga *GoApp) Sign_In() gin.HandlerFunc {
 				tk := map[string]string{
 					"token":    t1,
 					"newToken": t2,
+					"family":   family,
 				}
 
 				updated, err := ga.DB.UpdateUser(id, tk)
 
 				if err != nil {
-					_ = ctx.AbortWithError(http.StatusInternalServerError, err)
-					ctx.JSON(http.StatusInternalServerError, gin.H{"message": "error while updating tokens"})
+					internalError(ctx, err, "error while updating tokens")
 					return
 				}
 
 				if !updated {
-					_ = ctx.AbortWithError(http.StatusInternalServerError, err)
-					ctx.JSON(http.StatusInternalServerError, gin.H{"message": "error while updating tokens"})
+					internalError(ctx, err, "error while updating tokens")
 					return
 				}
 
@@ -215,108 +219,168 @@ func (ga *GoApp) Sign_In() gin.HandlerFunc {
 					"id":            id,
 					"name":          res["name"],
 					"session_token": t1,
+					"refresh_token": t2,
 				})
 			} else {
-				ctx.JSON(http.StatusUnauthorized, gin.H{"message": "unregistered user detected using wrong credentials"})
-				return
+				respondError(ctx, http.StatusUnauthorized, "unregistered user detected using wrong credentials")
 			}
 		}
 	}
 }
 
-func (ga *GoApp) ForgotPasswordUser() gin.HandlerFunc {
+// RefreshToken exchanges a refresh token for a new access/refresh pair. The refresh token must be
+// the latest one of its login session; presenting an already rotated token revokes the session.
+func (ga *GoApp) RefreshToken() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		email, _ := ctx.Get("Email")
-
-		var user *model.User
 
-		if err := ctx.ShouldBindJSON(&user); err != nil {
-			_ = ctx.AbortWithError(http.StatusBadRequest, gin.Error{Err: err})
+		var Input struct {
+			RefreshToken string `json:"refresh_token" binding:"required"`
 		}
 
-		user.Email = email.(string)
+		if err := ctx.ShouldBindJSON(&Input); err != nil {
+			badRequest(ctx, err)
+			return
+		}
 
-		updated, err := ga.DB.CreateNewPassword(user.Email, user.Password)
+		claims, err := auth.ParseRefresh(Input.RefreshToken)
+		if err != nil {
+			RespondError(ctx, http.StatusUnauthorized, auth.ErrorCode(err), "invalid refresh token", nil)
+			return
+		}
 
+		revoked, err := auth.IsRevoked(claims)
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, err)
+			respondError(ctx, http.StatusInternalServerError, "error while checking token revocation")
+			return
 		}
 
-		if !updated {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, err)
+		if revoked {
+			RespondError(ctx, http.StatusUnauthorized, "token_revoked", "refresh token has been revoked", nil)
+			return
 		}
 
-		ctx.JSON(http.StatusOK, gin.H{"message": "password changed successfully"})
-	}
-}
+		sessionID, err := primitive.ObjectIDFromHex(claims.Family)
+		if err != nil {
+			RespondError(ctx, http.StatusUnauthorized, "session_revoked", "session has ended, sign in again", nil)
+			return
+		}
 
-func (ga *GoApp) ForgotPasswordAdmin() gin.HandlerFunc {
-	return func(ctx *gin.Context) {
+		session, err := ga.DB.GetLoginSession(sessionID)
+		if err != nil {
+			if !errors.Is(err, domain.ErrNotFound) {
+				abortWithError(ctx, err)
+				return
+			}
+			RespondError(ctx, http.StatusUnauthorized, "session_revoked", "session has ended, sign in again", nil)
+			return
+		}
 
-		email, _ := ctx.Get("Email")
+		if session.RevokedAt != nil || session.SubjectID != claims.ID || session.Principal != claims.Principal {
+			RespondError(ctx, http.StatusUnauthorized, "session_revoked", "session has ended, sign in again", nil)
+			return
+		}
 
-		var admin *model.Admin
+		presented := hashToken(Input.RefreshToken)
 
-		if err := ctx.ShouldBindJSON(&admin); err != nil {
-			_ = ctx.AbortWithError(http.StatusBadRequest, gin.Error{Err: err})
+		if session.RefreshHash != presented {
+			ga.revokeReusedSession(claims)
+			RespondError(ctx, http.StatusUnauthorized, "session_revoked", "refresh token has already been used", nil)
+			return
 		}
 
-		admin.Email = email.(string)
+		res, err := ga.DB.GetPrincipalByID(claims.Principal, claims.ID)
+		if err != nil {
+			if !errors.Is(err, domain.ErrNotFound) {
+				abortWithError(ctx, err)
+				return
+			}
+			respondError(ctx, http.StatusUnauthorized, "invalid refresh token")
+			return
+		}
 
-		updated, err := ga.DB.CreateNewPassword(admin.Email, admin.Password)
+		subject, err := ga.subjectFor(claims.Principal, res)
+		if err != nil {
+			ga.App.ErrorLogger.Println(err)
+			respondError(ctx, http.StatusInternalServerError, "error while resolving role")
+			return
+		}
 
+		t1, t2, err := auth.Generate(subject, claims.Family)
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, err)
+			respondError(ctx, http.StatusInternalServerError, "error while generating tokens")
+			return
 		}
 
-		if !updated {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, err)
+		rotated, err := ga.DB.RotateSessionRefresh(sessionID, presented, hashToken(t2), time.Now().Add(auth.RefreshTokenLifetime))
+		if err != nil {
+			abortWithError(ctx, err)
+			return
 		}
 
-		ctx.JSON(http.StatusOK, gin.H{"message": "admin's password changed successfully"})
+		if !rotated {
+			// Another request rotated this refresh token first.
+			ga.revokeReusedSession(claims)
+			RespondError(ctx, http.StatusUnauthorized, "session_revoked", "refresh token has already been used", nil)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"message":       "Tokens refreshed successfully",
+			"session_token": t1,
+			"refresh_token": t2,
+		})
 	}
 }
 
+func (ga *GoApp) revokeReusedSession(claims *auth.GoAppClaims) {
+	ga.App.ErrorLogger.Printf("refresh token reuse detected for %s %s, revoking session %s", claims.Principal, claims.ID.Hex(), claims.Family)
+	ga.endSession(claims)
+}
+
 func (ga *GoApp) Update_Email_User() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 
-		current_email := ctx.MustGet("Email").(string)
+		userID := ctx.MustGet("UID").(primitive.ObjectID)
 
-		var Input struct {
-			New_Email string `json:"new_email"`
-		}
+		var Input dto.EmailChangeRequest
 
 		if err := ctx.ShouldBindJSON(&Input); err != nil {
-			_ = ctx.AbortWithError(http.StatusBadRequest, gin.Error{Err: err})
+			badRequest(ctx, err)
+			return
 		}
 
+		newEmail := Input.Email()
+
 		regMail := regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
-		ok := regMail.MatchString(Input.New_Email)
+		ok := regMail.MatchString(newEmail)
 
 		if !ok {
-			ctx.JSON(http.StatusBadRequest, gin.H{"message": "invalid email"})
+			respondError(ctx, http.StatusBadRequest, "invalid email")
 			return
 		}
 
-		updated, err := ga.DB.UpdateEmailUser(current_email, Input.New_Email)
-
-		if err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+		_, err := ga.DB.FindPrincipalByEmail(auth.UserPrincipal, newEmail)
+		if err == nil {
+			respondError(ctx, http.StatusConflict, "email is already in use")
+			return
 		}
-
-		if !updated {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+		if !errors.Is(err, domain.ErrNotFound) {
+			abortWithError(ctx, err)
+			return
 		}
 
-		cookieData := sessions.Default(ctx)
-		cookieData.Set("Email", Input.New_Email)
-		if err := cookieData.Save(); err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+		if err := ga.DB.SetPendingEmail(userID, newEmail); err != nil {
+			abortWithError(ctx, err)
+			return
 		}
 
-		ctx.Set("Email", Input.New_Email)
+		if err := ga.sendVerificationEmail(userID, newEmail); err != nil {
+			ga.App.ErrorLogger.Printf("Error sending verification email to %s: %v", newEmail, err)
+			respondError(ctx, http.StatusInternalServerError, "could not send verification email")
+			return
+		}
 
-		ctx.JSON(http.StatusOK, gin.H{"message": "email updated successfully"})
+		ctx.JSON(http.StatusAccepted, gin.H{"message": "verification link sent, your email changes once you open it"})
 
 	}
 }
@@ -326,39 +390,43 @@ func (ga *GoApp) Update_Email_Admin() gin.HandlerFunc {
 
 		current_email := ctx.MustGet("Email").(string)
 
-		var Input struct {
-			New_Email string `json:"new_email"`
-		}
+		var Input dto.EmailChangeRequest
 
 		if err := ctx.ShouldBindJSON(&Input); err != nil {
-			_ = ctx.AbortWithError(http.StatusBadRequest, gin.Error{Err: err})
+			badRequest(ctx, err)
+			return
 		}
 
+		newEmail := Input.Email()
+
 		regMail := regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
-		ok := regMail.MatchString(Input.New_Email)
+		ok := regMail.MatchString(newEmail)
 
 		if !ok {
-			ctx.JSON(http.StatusBadRequest, gin.H{"message": "invalid email"})
+			respondError(ctx, http.StatusBadRequest, "invalid email")
 			return
 		}
 
-		updated, err := ga.DB.UpdateEmailAdmin(current_email, Input.New_Email)
+		updated, err := ga.DB.UpdateEmailAdmin(current_email, newEmail)
 
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		if !updated {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		cookieData := sessions.Default(ctx)
-		cookieData.Set("Email", Input.New_Email)
+		cookieData.Set("Email", newEmail)
 		if err := cookieData.Save(); err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
-		ctx.Set("Email", Input.New_Email)
+		ctx.Set("Email", newEmail)
 
 		ctx.JSON(http.StatusOK, gin.H{"message": "Admin's email updated successfully"})
 
@@ -374,23 +442,27 @@ func (ga *GoApp) Update_Name_User() gin.HandlerFunc {
 		}
 
 		if err := ctx.ShouldBindJSON(&Input); err != nil {
-			_ = ctx.AbortWithError(http.StatusBadRequest, gin.Error{Err: err})
+			badRequest(ctx, err)
+			return
 		}
 
 		updated, err := ga.DB.UpdateNameUser(email, Input.New_Name)
 
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		if !updated {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		cookieData := sessions.Default(ctx)
 		cookieData.Set("Name", Input.New_Name)
 		if err := cookieData.Save(); err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		ctx.Set("Name", Input.New_Name)
@@ -410,23 +482,27 @@ func (ga *GoApp) Update_Name_Admin() gin.HandlerFunc {
 		}
 
 		if err := ctx.ShouldBindJSON(&Input); err != nil {
-			_ = ctx.AbortWithError(http.StatusBadRequest, gin.Error{Err: err})
+			badRequest(ctx, err)
+			return
 		}
 
 		updated, err := ga.DB.UpdateNameAdmin(email, Input.New_Name)
 
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		if !updated {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		cookieData := sessions.Default(ctx)
 		cookieData.Set("Name", Input.New_Name)
 		if err := cookieData.Save(); err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		ctx.Set("Name", Input.New_Name)
@@ -446,17 +522,20 @@ func (ga *GoApp) Update_Phone_User() gin.HandlerFunc {
 		}
 
 		if err := ctx.ShouldBindJSON(&Input); err != nil {
-			_ = ctx.AbortWithError(http.StatusBadRequest, gin.Error{Err: err})
+			badRequest(ctx, err)
+			return
 		}
 
 		updated, err := ga.DB.UpdatePhoneUser(email, Input.New_Phone)
 
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		if !updated {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		ctx.JSON(http.StatusOK, gin.H{"message": "phone updated successfully"})
@@ -474,17 +553,20 @@ func (ga *GoApp) Update_Phone_Admin() gin.HandlerFunc {
 		}
 
 		if err := ctx.ShouldBindJSON(&Input); err != nil {
-			_ = ctx.AbortWithError(http.StatusBadRequest, gin.Error{Err: err})
+			badRequest(ctx, err)
+			return
 		}
 
 		updated, err := ga.DB.UpdatePhoneAdmin(email, Input.New_Phone)
 
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		if !updated {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		ctx.JSON(http.StatusOK, gin.H{"message": "phone updated successfully"})
@@ -497,21 +579,32 @@ func (ga *GoApp) SignOutUser() gin.HandlerFunc {
 
 		userID := ctx.MustGet("UID").(primitive.ObjectID)
 
+		if err := auth.Revoke(ctx.MustGet("Claims").(*auth.GoAppClaims)); err != nil {
+			ga.App.ErrorLogger.Printf("Error revoking token: %v", err)
+			respondError(ctx, http.StatusInternalServerError, "Failed to revoke token")
+			return
+		}
+
 		status, err := ga.DB.SignOutUser(userID)
 
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		if !status {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
+		ga.endSession(ctx.MustGet("Claims").(*auth.GoAppClaims))
+
 		cookieData := sessions.Default(ctx)
 		cookieData.Clear()
 
 		if err := cookieData.Save(); err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		ctx.Set("UID", nil)
@@ -527,21 +620,32 @@ func (ga *GoApp) SignOutAdmin() gin.HandlerFunc {
 
 		adminID := ctx.MustGet("UID").(primitive.ObjectID)
 
+		if err := auth.Revoke(ctx.MustGet("Claims").(*auth.GoAppClaims)); err != nil {
+			ga.App.ErrorLogger.Printf("Error revoking token: %v", err)
+			respondError(ctx, http.StatusInternalServerError, "Failed to revoke token")
+			return
+		}
+
 		status, err := ga.DB.SignOutAdmin(adminID)
 
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		if !status {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
+		ga.endSession(ctx.MustGet("Claims").(*auth.GoAppClaims))
+
 		cookieData := sessions.Default(ctx)
 		cookieData.Clear()
 
 		if err := cookieData.Save(); err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		ctx.Set("UID", nil)
@@ -553,40 +657,89 @@ func (ga *GoApp) SignOutAdmin() gin.HandlerFunc {
 	}
 }
 
+// RevokeUserSessions invalidates every access and refresh token issued to a user so far.
+func (ga *GoApp) RevokeUserSessions() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+
+		var Input struct {
+			UserID string `json:"user_id" binding:"required"`
+		}
+
+		if err := ctx.ShouldBindJSON(&Input); err != nil {
+			badRequest(ctx, err)
+			return
+		}
+
+		userID, err := primitive.ObjectIDFromHex(Input.UserID)
+		if err != nil {
+			respondError(ctx, http.StatusBadRequest, "Invalid user ID format")
+			return
+		}
+
+		if err := auth.RevokeAll(userID); err != nil {
+			ga.App.ErrorLogger.Printf("Error revoking sessions of user %s: %v", userID.Hex(), err)
+			respondError(ctx, http.StatusInternalServerError, "Failed to revoke user sessions")
+			return
+		}
+
+		if _, err := ga.DB.SignOutUser(userID); err != nil {
+			respondError(ctx, http.StatusInternalServerError, "Failed to revoke user sessions")
+			return
+		}
+
+		if _, err := ga.DB.RevokeLoginSessions(auth.UserPrincipal, userID); err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+
+		ga.App.InfoLogger.Printf("All sessions of user %s revoked", userID.Hex())
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "User sessions revoked successfully"})
+	}
+}
+
 func (g *GoApp) InsertProducts() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 
-		var product *model.Product
+		var input dto.ProductRequest
 
-		if err := ctx.ShouldBindJSON(&product); err != nil {
-			_ = ctx.AbortWithError(http.StatusBadRequest, gin.Error{Err: err})
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			badRequest(ctx, err)
+			return
 		}
-		product.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 
-		product.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		product := input.ToProduct(g.App.Pricing.Zone(input.SaleTimezone))
+		if !saleWindowValid(ctx, "sale_ends", product) {
+			return
+		}
 
-		if err := g.App.Validate.Struct(&product); err != nil {
-			if _, ok := err.(*validator.InvalidValidationError); !ok {
-				_ = ctx.AbortWithError(http.StatusBadRequest, gin.Error{Err: err})
-				g.App.InfoLogger.Println(err)
-				return
-			}
+		category, ok := g.categoryOf(ctx, "category_id", product.CategoryID)
+		if !ok {
+			return
 		}
+		product.Category = category.Name
+
+		product.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		product.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 
 		ok, status, err := g.DB.InsertProduct(product)
 
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
-			ctx.JSON(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		if !ok {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
-			ctx.JSON(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		if status == 1 {
 
+			g.indexProduct(product)
+			g.priceProduct(product)
+
 			ctx.JSON(http.StatusOK, gin.H{"message": "Product created successfully"})
 		}
 
@@ -600,40 +753,60 @@ func (g *GoApp) InsertProducts() gin.HandlerFunc {
 
 func (g *GoApp) InsertMultipleProducts() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		var products []*model.Product
+		var input []dto.ProductRequest
 
-		if err := ctx.ShouldBindJSON(&products); err != nil {
-			ctx.AbortWithError(http.StatusBadRequest, gin.Error{Err: err})
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		if err := bindJSONList(ctx, &input); err != nil {
 			return
 		}
 
+		// Validate each product
+		for i := range input {
+			if err := g.App.Validate.Struct(&input[i]); err != nil {
+				badListItem(ctx, i, err)
+				return
+			}
+		}
+
 		// Process each product
 		currentTime, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		for i := range products {
-			products[i].CreatedAt = currentTime
-			products[i].UpdatedAt = currentTime
-
-			// Validate each product
-			if err := g.App.Validate.Struct(products[i]); err != nil {
-				if _, ok := err.(*validator.InvalidValidationError); !ok {
-					ctx.AbortWithError(http.StatusBadRequest, gin.Error{Err: err})
-					ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-					g.App.InfoLogger.Println(err)
+		products := make([]*model.Product, 0, len(input))
+		categories := map[primitive.ObjectID]model.Category{}
+		for i := range input {
+			product := input[i].ToProduct(g.App.Pricing.Zone(input[i].SaleTimezone))
+			if !saleWindowValid(ctx, fmt.Sprintf("[%d].sale_ends", i), product) {
+				return
+			}
+
+			category, found := categories[product.CategoryID]
+			if !found {
+				var ok bool
+				category, ok = g.categoryOf(ctx, fmt.Sprintf("[%d].category_id", i), product.CategoryID)
+				if !ok {
 					return
 				}
+				categories[category.ID] = category
 			}
+			product.Category = category.Name
+
+			product.CreatedAt = currentTime
+			product.UpdatedAt = currentTime
+			products = append(products, product)
 		}
 
 		// Insert all products
 		insertedCount, existingCount, err := g.DB.InsertMultipleProductsBulk(products)
 
 		if err != nil {
-			ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			abortWithError(ctx, err)
 			return
 		}
 
+		// Only the products that were inserted got an id.
+		for _, product := range products {
+			g.indexProduct(product)
+			g.priceProduct(product)
+		}
+
 		ctx.JSON(http.StatusOK, gin.H{
 			"message":  "Products processed",
 			"inserted": insertedCount,
@@ -642,19 +815,41 @@ func (g *GoApp) InsertMultipleProducts() gin.HandlerFunc {
 	}
 }
 
+// ViewProducts lists products a page at a time. Filters and sort come from the query string, and
+// the next page is fetched by passing back the next_cursor of the previous one.
 func (g *GoApp) ViewProducts() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 
-		var res []primitive.M
+		var input dto.ProductListRequest
+		if err := ctx.ShouldBindQuery(&input); err != nil {
+			badRequest(ctx, err)
+			return
+		}
+
+		if input.MinPrice != nil && input.MaxPrice != nil && *input.MinPrice > *input.MaxPrice {
+			RespondError(ctx, http.StatusUnprocessableEntity, "validation_failed", "Some fields are invalid", []FieldError{
+				{Field: "max_price", Rule: "gtefield", Message: "must be at least min_price"},
+			})
+			return
+		}
+
+		if spec, inverted := input.InvertedRange(); inverted {
+			RespondError(ctx, http.StatusUnprocessableEntity, "validation_failed", "Some fields are invalid", []FieldError{
+				{Field: "max_" + spec, Rule: "gtefield", Message: "must be at least min_" + spec},
+			})
+			return
+		}
 
-		res, err := g.DB.ViewProducts()
+		page, err := g.DB.ViewProducts(input.ToQuery())
 
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
-			ctx.JSON(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
-		ctx.JSON(http.StatusOK, gin.H{"data": res})
+		dto.ShowPrices(page.Products, g.App.Pricing, time.Now())
+
+		ctx.JSON(http.StatusOK, dto.NewProductPage(page, units.System(input.Units)))
 	}
 }
 
@@ -667,17 +862,20 @@ func (ga *GoApp) Change_Stock() gin.HandlerFunc {
 		}
 
 		if err := ctx.ShouldBindJSON(&Input); err != nil {
-			_ = ctx.AbortWithError(http.StatusBadRequest, gin.Error{Err: err})
+			badRequest(ctx, err)
+			return
 		}
 
 		ok, err := ga.DB.Update_Stock(Input.ProductID, Input.New_Stock)
 
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		if !ok {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		ctx.JSON(http.StatusOK, gin.H{"message": "stock updated successfully"})
@@ -687,42 +885,55 @@ func (ga *GoApp) Change_Stock() gin.HandlerFunc {
 
 func (ga *GoApp) Sign_Up_Admin() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		var admin *model.Admin
+		var Input dto.AdminSignUpRequest
 
-		err := ctx.ShouldBindJSON(&admin)
+		err := ctx.ShouldBindJSON(&Input)
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusBadRequest, gin.Error{
-				Err: err,
-			})
+			badRequest(ctx, err)
+			return
 		}
 
-		admin.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		admin.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		admin := Input.ToAdmin()
+
+		if weakPassword(ctx, admin.Password, admin.Email, admin.Name) {
+			return
+		}
 
-		admin.Password, _ = encrypt.Hash(admin.Password)
+		claims, err := auth.ParseActionToken(auth.PurposeAdminInvite, Input.InviteToken)
+		if err != nil || claims.Email != admin.Email {
+			respondError(ctx, http.StatusForbidden, "a valid admin invite is required")
+			return
+		}
 
+		inviteID, err := primitive.ObjectIDFromHex(claims.Subject)
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			respondError(ctx, http.StatusForbidden, "a valid admin invite is required")
+			return
 		}
 
-		if err := ga.App.Validate.Struct(&admin); err != nil {
-			if _, ok := err.(*validator.InvalidValidationError); !ok {
-				_ = ctx.AbortWithError(http.StatusBadRequest, gin.Error{Err: err})
-				ga.App.InfoLogger.Println(err)
-				return
-			}
+		invite, err := ga.DB.RedeemAdminInvite(inviteID, admin.Email)
+		if err != nil {
+			respondError(ctx, http.StatusForbidden, "admin invite is invalid, expired or already used")
+			return
 		}
 
-		ok, status, err := ga.DB.SignUpAdmin(admin)
+		admin.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		admin.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		admin.Password, err = encrypt.Hash(admin.Password)
+		admin.Role = invite.Role
 
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, errors.New("error while adding new admin"))
-			ctx.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			ga.releaseAdminInvite(invite.ID)
+			internalError(ctx, err, "error while adding new admin")
 			return
 		}
 
-		if !ok {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, err)
+		ok, status, err := ga.DB.SignUpAdmin(admin)
+
+		if err != nil || !ok {
+			ga.releaseAdminInvite(invite.ID)
+			respondError(ctx, http.StatusInternalServerError, "error while adding new admin")
 			return
 		}
 
@@ -733,147 +944,183 @@ func (ga *GoApp) Sign_Up_Admin() gin.HandlerFunc {
 			}
 		case 2:
 			{
-				ctx.JSON(http.StatusConflict, gin.H{"message": "Admin already exists"})
+				ga.releaseAdminInvite(invite.ID)
+				respondError(ctx, http.StatusConflict, "Admin already exists")
 			}
 		}
 	}
 }
 
+func (ga *GoApp) releaseAdminInvite(inviteID primitive.ObjectID) {
+	if err := ga.DB.ReleaseAdminInvite(inviteID); err != nil {
+		ga.App.ErrorLogger.Printf("Error releasing admin invite %s: %v", inviteID.Hex(), err)
+	}
+}
+
 func (ga *GoApp) Sign_In_Admin() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 
-		var admin *model.Admin
+		var admin dto.SignInRequest
 		if err := ctx.ShouldBindJSON(&admin); err != nil {
-			_ = ctx.AbortWithError(http.StatusBadRequest, gin.Error{Err: err})
+			badRequest(ctx, err)
+			return
 		}
 
+		admin.Normalize()
+
 		regMail := regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
 		ok := regMail.MatchString(admin.Email)
 
 		if ok {
 
+			if ga.loginLocked(ctx, auth.AdminPrincipal, admin.Email) {
+				return
+			}
+
 			res, err := ga.DB.VerifyAdmin(admin.Email)
 			if err != nil {
-				_ = ctx.AbortWithError(http.StatusInternalServerError, err)
-				ctx.JSON(http.StatusUnauthorized, gin.H{"message": "unregistered user"})
+				if !errors.Is(err, domain.ErrNotFound) {
+					abortWithError(ctx, err)
+					return
+				}
+				ga.recordLoginFailure(ctx, auth.AdminPrincipal, admin.Email)
+				respondError(ctx, http.StatusUnauthorized, "unregistered user")
 				return
 			}
 
-			id := res["_id"].(primitive.ObjectID)
 			password := res["password"].(string)
 
 			verified, err := encrypt.VerifyPassword(admin.Password, password)
 			if err != nil {
-				_ = ctx.AbortWithError(http.StatusInternalServerError, err)
-				ctx.JSON(http.StatusUnauthorized, gin.H{"message": "unregistered user detected using wrong password"})
+				ga.recordLoginFailure(ctx, auth.AdminPrincipal, admin.Email)
+				respondError(ctx, http.StatusUnauthorized, "unregistered user detected using wrong password")
 				return
 			}
 
 			if verified {
 
-				cookieData := sessions.Default(ctx)
-
-				adminInfo := map[string]interface{}{
-					"ID":    id,
-					"Email": admin.Email,
-					"Name":  res["name"],
-				}
+				ga.clearLoginFailures(auth.AdminPrincipal, admin.Email)
 
-				cookieData.Set("adminInfo", adminInfo)
+				ga.rehashPassword(auth.AdminPrincipal, res["_id"].(primitive.ObjectID), admin.Password, password)
 
-				if err := cookieData.Save(); err != nil {
-					_ = ctx.AbortWithError(http.StatusInternalServerError, err)
-					ctx.JSON(http.StatusInternalServerError, gin.H{"message": "error while saving cookie"})
+				if ga.secondFactorRequired(ctx, auth.AdminPrincipal, res) {
 					return
 				}
 
-				t1, t2, err := auth.Generate(admin.Email, id, res["name"].(string))
+				ga.completeAdminLogin(ctx, res, nil)
+			} else {
+				respondError(ctx, http.StatusUnauthorized, "unregistered admin detected using wrong credentials")
+			}
+		}
+	}
+}
 
-				if err != nil {
-					_ = ctx.AbortWithError(http.StatusInternalServerError, err)
-					ctx.JSON(http.StatusInternalServerError, gin.H{"message": "error while generating tokens"})
-					return
-				}
+// completeAdminLogin signs in an admin whose credentials, and second factor when enabled, have been verified.
+func (ga *GoApp) completeAdminLogin(ctx *gin.Context, res primitive.M, extra gin.H) {
+	id := res["_id"].(primitive.ObjectID)
+	email, _ := res["email"].(string)
 
-				cookieData.Set("admin_token", t1)
+	cookieData := sessions.Default(ctx)
 
-				if err := cookieData.Save(); err != nil {
-					_ = ctx.AbortWithError(http.StatusInternalServerError, err)
-					ctx.JSON(http.StatusInternalServerError, gin.H{"message": "error while saving cookie"})
-					return
-				}
+	adminInfo := map[string]interface{}{
+		"ID":    id,
+		"Email": email,
+		"Name":  res["name"],
+	}
 
-				cookieData.Set("new_admin_token", t2)
+	cookieData.Set("adminInfo", adminInfo)
 
-				if err := cookieData.Save(); err != nil {
-					_ = ctx.AbortWithError(http.StatusInternalServerError, err)
-					ctx.JSON(http.StatusInternalServerError, gin.H{"message": "error while saving cookie"})
-					return
-				}
+	if err := cookieData.Save(); err != nil {
+		internalError(ctx, err, "error while saving cookie")
+		return
+	}
 
-				tk := map[string]string{
-					"token":    t1,
-					"newToken": t2,
-				}
+	subject, err := ga.subjectFor(auth.AdminPrincipal, res)
 
-				updated, err := ga.DB.UpdateAdmin(id, tk)
+	if err != nil {
+		ga.App.ErrorLogger.Println(err)
+		respondError(ctx, http.StatusInternalServerError, "error while resolving role")
+		return
+	}
 
-				if err != nil {
-					_ = ctx.AbortWithError(http.StatusInternalServerError, err)
-					ctx.JSON(http.StatusInternalServerError, gin.H{"message": "error while updating tokens"})
-					return
-				}
+	family, t1, t2, err := ga.startSession(ctx, subject)
 
-				if !updated {
-					_ = ctx.AbortWithError(http.StatusInternalServerError, err)
-					ctx.JSON(http.StatusInternalServerError, gin.H{"message": "error while updating tokens"})
-					return
-				}
+	if err != nil {
+		internalError(ctx, err, "error while generating tokens")
+		return
+	}
 
-				ctx.JSON(http.StatusOK, gin.H{
-					"message":       "Successfully Logged in",
-					"email":         admin.Email,
-					"id":            id,
-					"name":          res["name"],
-					"session_token": t1,
-				})
-			} else {
-				ctx.JSON(http.StatusUnauthorized, gin.H{"message": "unregistered admin detected using wrong credentials"})
-				return
-			}
-		}
+	cookieData.Set("admin_token", t1)
+
+	if err := cookieData.Save(); err != nil {
+		internalError(ctx, err, "error while saving cookie")
+		return
+	}
+
+	cookieData.Set("new_admin_token", t2)
+
+	if err := cookieData.Save(); err != nil {
+		internalError(ctx, err, "error while saving cookie")
+		return
+	}
+
+	tk := map[string]string{
+		"token":    t1,
+		"newToken": t2,
+		"family":   family,
+	}
+
+	updated, err := ga.DB.UpdateAdmin(id, tk)
+
+	if err != nil {
+		internalError(ctx, err, "error while updating tokens")
+		return
+	}
+
+	if !updated {
+		internalError(ctx, err, "error while updating tokens")
+		return
+	}
+
+	response := gin.H{
+		"message":       "Successfully Logged in",
+		"email":         email,
+		"id":            id,
+		"name":          res["name"],
+		"session_token": t1,
+		"refresh_token": t2,
 	}
+
+	for k, v := range extra {
+		response[k] = v
+	}
+
+	ctx.JSON(http.StatusOK, response)
 }
 
 func (ga *GoApp) CreateCategory() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 
-		var category *model.Category
-		if err := ctx.ShouldBindJSON(&category); err != nil {
-			_ = ctx.AbortWithError(http.StatusBadRequest, gin.Error{Err: err})
+		var input dto.CreateCategoryRequest
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			badRequest(ctx, err)
+			return
 		}
 
+		category := input.ToCategory()
+
 		category.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		category.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 
-		if err := ga.App.Validate.Struct(&category); err != nil {
-			if _, ok := err.(*validator.InvalidValidationError); !ok {
-				_ = ctx.AbortWithError(http.StatusBadRequest, err)
-				ga.App.ErrorLogger.Println(err)
-				return
-			}
-		}
-
 		ok, status, err := ga.DB.CreateCategory(category)
 
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, errors.New("error while adding new category"))
-			ctx.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			abortWithError(ctx, err)
 			return
 		}
 
 		if !ok {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, err)
+			abortWithError(ctx, err)
 			return
 		}
 
@@ -884,7 +1131,7 @@ func (ga *GoApp) CreateCategory() gin.HandlerFunc {
 			}
 		case 2:
 			{
-				ctx.JSON(http.StatusConflict, gin.H{"message": "Category already exists"})
+				respondError(ctx, http.StatusConflict, "Category already exists")
 			}
 		}
 	}
@@ -893,22 +1140,38 @@ func (ga *GoApp) CreateCategory() gin.HandlerFunc {
 func (ga *GoApp) UpdateProduct() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 
-		var product *model.Product
+		var input dto.UpdateProductRequest
+
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			badRequest(ctx, err)
+			return
+		}
 
-		if err := ctx.ShouldBindJSON(&product); err != nil {
-			_ = ctx.AbortWithError(http.StatusBadRequest, gin.Error{Err: err})
+		product := input.ToProduct(ga.App.Pricing.Zone(input.SaleTimezone))
+		if !saleWindowValid(ctx, "sale_ends", product) {
+			return
 		}
 
+		category, ok := ga.categoryOf(ctx, "category_id", product.CategoryID)
+		if !ok {
+			return
+		}
+		product.Category = category.Name
+
 		ok, err := ga.DB.UpdateProduct(product)
 
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		if !ok {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
+		ga.reindexProduct(product.ID)
+
 		ga.App.InfoLogger.Println("Product updated successfully")
 
 		ctx.JSON(http.StatusOK, gin.H{"message": "Product updated successfully"})
@@ -924,17 +1187,20 @@ func (ga *GoApp) ToggleStock() gin.HandlerFunc {
 		}
 
 		if err := ctx.ShouldBindJSON(&Input); err != nil {
-			_ = ctx.AbortWithError(http.StatusBadRequest, gin.Error{Err: err})
+			badRequest(ctx, err)
+			return
 		}
 
 		ok, err := ga.DB.Toggle_Stock(Input.ProductID)
 
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		if !ok {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		ga.App.InfoLogger.Println("Stock toggled successfully")
@@ -954,17 +1220,20 @@ func (ga *GoApp) AddToWishList() gin.HandlerFunc {
 		}
 
 		if err := ctx.ShouldBindJSON(&Input); err != nil {
-			_ = ctx.AbortWithError(http.StatusBadRequest, gin.Error{Err: err})
+			badRequest(ctx, err)
+			return
 		}
 
 		ok, err := ga.DB.AddProductToWishlist(Input.ProductID, user_id)
 
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		if !ok {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		ga.App.InfoLogger.Println("Product added to wishlist successfully")
@@ -984,17 +1253,20 @@ func (ga *GoApp) RemoveFromWishList() gin.HandlerFunc {
 		}
 
 		if err := ctx.ShouldBindJSON(&Input); err != nil {
-			_ = ctx.AbortWithError(http.StatusBadRequest, gin.Error{Err: err})
+			badRequest(ctx, err)
+			return
 		}
 
 		ok, err := ga.DB.RemoveProductFromWishlist(Input.ProductID, user_id)
 
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		if !ok {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		ga.App.InfoLogger.Println("Product removed from wishlist successfully")
@@ -1006,24 +1278,28 @@ func (ga *GoApp) RemoveFromWishList() gin.HandlerFunc {
 func (ga *GoApp) Get_Single_Product() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 
-		var Input struct {
-			ProductID primitive.ObjectID `json:"product_id"`
-		}
+		var input dto.SingleProductRequest
 
-		if err := ctx.ShouldBindJSON(&Input); err != nil {
-			_ = ctx.AbortWithError(http.StatusBadRequest, gin.Error{Err: err})
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			badRequest(ctx, err)
+			return
 		}
 
-		product, err := ga.DB.GetSingleProduct(Input.ProductID)
+		product, err := ga.DB.GetSingleProduct(input.ProductID)
 
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		if product == nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
+		dto.ShowSpecs([]primitive.M{product}, units.System(input.Units))
+		dto.ShowPrices([]primitive.M{product}, ga.App.Pricing, time.Now())
+
 		ctx.JSON(http.StatusOK, gin.H{"data": product, "message": "Product fetched successfully"})
 	}
 }
@@ -1033,30 +1309,28 @@ func (ga *GoApp) Add_To_Cart() gin.HandlerFunc {
 
 		user_id := ctx.MustGet("UID").(primitive.ObjectID)
 
-		var cartitem *model.CartItems
+		var input dto.AddToCartRequest
 
-		if err := ctx.ShouldBindJSON(&cartitem); err != nil {
+		if err := ctx.ShouldBindJSON(&input); err != nil {
 			ga.App.ErrorLogger.Println("There is some problem in binding json : ", err)
-			_ = ctx.AbortWithError(http.StatusBadRequest, gin.Error{Err: err})
+			badRequest(ctx, err)
+			return
 		}
 
-		productID := cartitem.ProductID
-		prdID, err := primitive.ObjectIDFromHex(productID.Hex())
-
-		if err != nil {
-			ga.App.ErrorLogger.Println("There is some problem in getting product id : ", err)
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+		if !ga.checkVariant(ctx, "variant_id", input.ProductID, input.VariantID) {
+			return
 		}
-		cartitem.ProductID = prdID
 
-		ok, err := ga.DB.AddToCart(user_id, cartitem)
+		ok, err := ga.DB.AddToCart(user_id, input.ToCartItem())
 
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		if !ok {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		ga.App.InfoLogger.Println("Product added to cart successfully")
@@ -1074,11 +1348,13 @@ func (ga *GoApp) Empty_Cart() gin.HandlerFunc {
 		ok, err := ga.DB.Empty_the_Cart(user_id)
 
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		if !ok {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		ga.App.InfoLogger.Println("Cart emptied successfully")
@@ -1092,22 +1368,23 @@ func (ga *GoApp) Remove_From_Cart() gin.HandlerFunc {
 
 		user_id := ctx.MustGet("UID").(primitive.ObjectID)
 
-		var Input struct {
-			ProductID primitive.ObjectID `json:"product_id"`
-		}
+		var input dto.RemoveFromCartRequest
 
-		if err := ctx.ShouldBindJSON(&Input); err != nil {
-			_ = ctx.AbortWithError(http.StatusBadRequest, gin.Error{Err: err})
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			badRequest(ctx, err)
+			return
 		}
 
-		ok, err := ga.DB.RemoveFromCart(user_id, Input.ProductID)
+		ok, err := ga.DB.RemoveFromCart(user_id, input.ProductID, input.VariantID)
 
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		if !ok {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		ga.App.InfoLogger.Println("Product Removed from cart successfully")
@@ -1127,16 +1404,24 @@ func (ga *GoApp) Get_User_By_Id() gin.HandlerFunc {
 		user, err := ga.DB.GetUserByID(user_id)
 
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		if user == nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
-		ga.App.InfoLogger.Println("User fetched successfully : ", user)
+		ga.App.InfoLogger.Println("User fetched successfully : ", user["_id"])
+
+		profile, err := dto.UserProfileFromDoc(user)
+		if err != nil {
+			internalError(ctx, err, "Failed to read user")
+			return
+		}
 
-		ctx.JSON(http.StatusOK, gin.H{"data": user, "message": "User fetched successfully"})
+		ctx.JSON(http.StatusOK, gin.H{"data": profile, "message": "User fetched successfully"})
 	}
 }
 
@@ -1146,14 +1431,26 @@ func (ga *GoApp) Get_All_Users() gin.HandlerFunc {
 		users, err := ga.DB.GetAllUsers()
 
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		if users == nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
+		}
+
+		profiles := make([]*dto.UserProfile, 0, len(users))
+		for _, user := range users {
+			profile, err := dto.UserProfileFromDoc(user)
+			if err != nil {
+				internalError(ctx, err, "Failed to read users")
+				return
+			}
+			profiles = append(profiles, profile)
 		}
 
-		ctx.JSON(http.StatusOK, gin.H{"data": users, "message": "Users fetched successfully"})
+		ctx.JSON(http.StatusOK, gin.H{"data": profiles, "message": "Users fetched successfully"})
 	}
 }
 
@@ -1162,18 +1459,19 @@ func (ga *GoApp) Get_All_Categories() gin.HandlerFunc {
 
 		categories, err := ga.DB.GetAllCategories()
 
-		fmt.Println("Categories : ", categories)
-
 		if err != nil {
-			fmt.Println("We have reached here!")
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
-		if categories == nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+		counts, err := ga.DB.CountProductsByCategory()
+
+		if err != nil {
+			abortWithError(ctx, err)
+			return
 		}
 
-		ctx.JSON(http.StatusOK, gin.H{"data": categories, "message": "Categories fetched successfully"})
+		ctx.JSON(http.StatusOK, gin.H{"data": dto.CategoryViews(categories, counts), "message": "Categories fetched successfully"})
 	}
 }
 
@@ -1186,11 +1484,13 @@ func (ga *GoApp) Initialize_User() gin.HandlerFunc {
 
 		if er != nil {
 			ga.App.ErrorLogger.Println("There is some problem in initializing user : ", er)
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: er})
+			abortWithError(ctx, er)
+			return
 		}
 
 		if !status {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: er})
+			abortWithError(ctx, er)
+			return
 		}
 
 		ga.App.InfoLogger.Println("User initialized successfully")
@@ -1206,34 +1506,87 @@ func (ga *GoApp) FetchDetailsfromMail() gin.HandlerFunc {
 
 		if err := ctx.ShouldBindJSON(&Input); err != nil {
 			ga.App.ErrorLogger.Println("There is some problem in binding json : ", err)
-			_ = ctx.AbortWithError(http.StatusBadRequest, gin.Error{Err: err})
+			badRequest(ctx, err)
+			return
 		}
 
 		data, err := ga.DB.FindUserWithEmail(Input.Email)
 
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		if data == nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		ctx.JSON(http.StatusOK, gin.H{"data": data, "message": "User fetched successfully"})
 	}
 }
 
+// actingCustomer returns the customer a user or admin is acting for. Users act for themselves and
+// may not name anyone else in field; admins must name the customer.
+func actingCustomer(ctx *gin.Context, field string, requested primitive.ObjectID) (primitive.ObjectID, bool) {
+	if ctx.GetString("Principal") != auth.UserPrincipal {
+		if requested.IsZero() {
+			RespondError(ctx, http.StatusUnprocessableEntity, "validation_failed", "Some fields are invalid", []FieldError{
+				{Field: field, Rule: "required", Message: "is required"},
+			})
+			return primitive.NilObjectID, false
+		}
+		return requested, true
+	}
+
+	uid := ctx.MustGet("UID").(primitive.ObjectID)
+	if !requested.IsZero() && requested != uid {
+		respondError(ctx, http.StatusForbidden, "cannot act on behalf of another customer")
+		return primitive.NilObjectID, false
+	}
+	return uid, true
+}
+
 func (ga *GoApp) Create_Order() gin.HandlerFunc {
 
 	return func(ctx *gin.Context) {
 
-		var order *model.Order
+		var input dto.PlaceOrderRequest
 
-		if err := ctx.ShouldBindJSON(&order); err != nil {
+		if err := ctx.ShouldBindJSON(&input); err != nil {
 			ga.App.ErrorLogger.Println("There is some problem in binding json : ", err)
-			_ = ctx.AbortWithError(http.StatusBadRequest, gin.Error{Err: err})
+			badRequest(ctx, err)
+			return
+		}
+
+		customerID, ok := actingCustomer(ctx, "customer_id", input.CustomerID)
+		if !ok {
+			return
+		}
+
+		if !ga.requireVerifiedEmail(ctx, "customer_id", customerID) {
+			return
+		}
+
+		order := input.ToOrder(customerID)
+
+		if !ga.priceOrder(ctx, order, time.Now()) {
+			return
+		}
+
+		if input.OrderAmount != 0 && input.OrderAmount != order.OrderAmount {
+			RespondError(ctx, http.StatusConflict, "price_changed", "Prices changed since the order was totalled", gin.H{
+				"order_amount": order.OrderAmount,
+			})
+			return
+		}
+
+		if !ga.checkOrderPayment(ctx, order) {
+			return
 		}
 
+		order.OrderDate = time.Now()
+		order.OrderStatus = "placed"
 		order.CreatedAt = time.Now()
 		order.UpdatedAt = time.Now()
 
@@ -1243,12 +1596,14 @@ func (ga *GoApp) Create_Order() gin.HandlerFunc {
 
 		if err != nil {
 			ga.App.ErrorLogger.Println("There is some problem in creating order : ", err)
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		if !check {
 			ga.App.ErrorLogger.Println("There is some problem in creating order : ", err)
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		ga.App.InfoLogger.Println("Order added to user's order list successfully")
@@ -1257,28 +1612,32 @@ func (ga *GoApp) Create_Order() gin.HandlerFunc {
 
 		if err != nil {
 			ga.App.ErrorLogger.Println("There is some problem in creating order : ", err)
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		if res == nil {
 			ga.App.ErrorLogger.Println("There is some problem in creating order : ", err)
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		ga.App.InfoLogger.Println("Order created successfully", res)
 
 		txn_Id := res["transaction_id"].(primitive.ObjectID)
 
-		ok, err := ga.DB.UpdatePaymentToIncludeOrderId(txn_Id, order.ID)
+		ok, err = ga.DB.UpdatePaymentToIncludeOrderId(txn_Id, order.ID)
 
 		if err != nil {
 			ga.App.ErrorLogger.Println("There is some problem in creating order : ", err)
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		if !ok {
 			ga.App.ErrorLogger.Println("There is some problem in creating order : ", err)
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		ctx.JSON(http.StatusOK, gin.H{"message": "Order created successfully", "data": res})
@@ -1303,12 +1662,14 @@ func (ga *GoApp) Get_User_Orders() gin.HandlerFunc {
 
 		if err != nil {
 			ga.App.ErrorLogger.Println("There is some problem in getting user orders : ", err)
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		if orders == nil {
 			ga.App.ErrorLogger.Println("There is some problem in getting user orders as orders are nil : ", err)
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		ga.App.InfoLogger.Println("Orders fetched successfully : ", orders)
@@ -1325,12 +1686,14 @@ func (ga *GoApp) Get_All_Orders() gin.HandlerFunc {
 
 		if err != nil {
 			ga.App.ErrorLogger.Println("There is some problem in getting user orders : ", err)
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		if orders == nil {
 			ga.App.ErrorLogger.Println("There is some problem in getting user orders as orders are nil : ", err)
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		ga.App.InfoLogger.Println("Orders fetched successfully : ", orders)
@@ -1356,14 +1719,18 @@ func (ga *GoApp) DeleteProduct() gin.HandlerFunc {
 
 		if err != nil {
 			ga.App.ErrorLogger.Println("There is some problem in deleting product : ", err)
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		if !ok {
 			ga.App.ErrorLogger.Println("There is some problem in deleting product : ", err)
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
+		ga.unindexProduct(idObj)
+
 		ctx.JSON(http.StatusOK, gin.H{"message": "Product deleted successfully"})
 	}
 }
@@ -1385,12 +1752,14 @@ func (ga *GoApp) DeleteOrder() gin.HandlerFunc {
 
 		if err != nil {
 			ga.App.ErrorLogger.Println("There is some problem in deleting order : ", err)
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		if !ok {
 			ga.App.ErrorLogger.Println("There is some problem in deleting order : ", err)
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		ctx.JSON(http.StatusOK, gin.H{"message": "Order deleted successfully"})
@@ -1401,14 +1770,31 @@ func (ga *GoApp) Payment_Creation() gin.HandlerFunc {
 
 	return func(ctx *gin.Context) {
 
-		var payment *model.Payment
+		var input dto.PaymentRequest
 
-		if err := ctx.ShouldBindJSON(&payment); err != nil {
+		if err := ctx.ShouldBindJSON(&input); err != nil {
 			ga.App.ErrorLogger.Println("There is some problem in binding json : ", err)
-			_ = ctx.AbortWithError(http.StatusBadRequest, gin.Error{Err: err})
+			badRequest(ctx, err)
+			return
+		}
+
+		paidBy, ok := actingCustomer(ctx, "paid_by", input.PaidBy)
+		if !ok {
+			return
+		}
+
+		if !ga.requireVerifiedEmail(ctx, "paid_by", paidBy) {
+			return
+		}
+
+		payment := input.ToPayment(paidBy)
+
+		if !ga.checkPaidOrder(ctx, payment) {
+			return
 		}
 
 		payment.ID = primitive.NewObjectID()
+		payment.Paid_Date = time.Now()
 		payment.CreatedAt = time.Now()
 		payment.UpdatedAt = time.Now()
 
@@ -1416,12 +1802,14 @@ func (ga *GoApp) Payment_Creation() gin.HandlerFunc {
 
 		if err != nil {
 			ga.App.ErrorLogger.Println("There is some problem in creating payment : ", err)
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		if payment_details == nil {
 			ga.App.ErrorLogger.Println("There is some problem in creating payment : ", err)
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		ctx.JSON(http.StatusOK, gin.H{"message": "Payment created successfully", "data": payment_details})
@@ -1431,12 +1819,16 @@ func (ga *GoApp) Payment_Creation() gin.HandlerFunc {
 func (ga *GoApp) Shipment_Creation() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 
-		var shipment *model.Shipment
-		if err := ctx.ShouldBindJSON(&shipment); err != nil {
-			_ = ctx.AbortWithError(http.StatusBadRequest, gin.Error{Err: err})
+		var input dto.ShipmentRequest
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			badRequest(ctx, err)
+			return
 		}
 
+		shipment := input.ToShipment(ctx.MustGet("UID").(primitive.ObjectID))
+
 		shipment.ID = primitive.NewObjectID()
+		shipment.Shipment_Status = "pending"
 
 		shipment.CreatedAt = time.Now()
 		shipment.UpdatedAt = time.Now()
@@ -1446,12 +1838,14 @@ func (ga *GoApp) Shipment_Creation() gin.HandlerFunc {
 
 		if err != nil {
 			ga.App.ErrorLogger.Println("There is some problem in creating shipment : ", err)
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		if shipment_details == nil {
 			ga.App.ErrorLogger.Println("There is some problem in creating shipment : ", err)
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		ctx.JSON(http.StatusOK, gin.H{"message": "Shipment created successfully", "data": shipment_details})
@@ -1461,26 +1855,28 @@ func (ga *GoApp) Shipment_Creation() gin.HandlerFunc {
 func (ga *GoApp) Add_Address() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 
-		var Input struct {
-			UserId  primitive.ObjectID `json:"user_id"`
-			Address *model.Address     `json:"address"`
-		}
+		var Input dto.AddAddressRequest
 
 		if err := ctx.ShouldBindJSON(&Input); err != nil {
 			ga.App.ErrorLogger.Println("There is some problem in binding json : ", err)
-			_ = ctx.AbortWithError(http.StatusBadRequest, gin.Error{Err: err})
+			badRequest(ctx, err)
+			return
 		}
 
-		ok, err := ga.DB.AddAddress(Input.UserId, Input.Address)
+		address := Input.Address.ToAddress()
+
+		ok, err := ga.DB.AddAddress(ctx.MustGet("UID").(primitive.ObjectID), &address)
 
 		if err != nil {
 			ga.App.ErrorLogger.Println("There is some problem in adding address : ", err)
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		if !ok {
 			ga.App.ErrorLogger.Println("There is some problem in adding address : ", err)
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		ctx.JSON(http.StatusOK, gin.H{"message": "Address added successfully"})
@@ -1494,83 +1890,55 @@ func (ga *GoApp) Get_All_Payments() gin.HandlerFunc {
 
 		if err != nil {
 			ga.App.ErrorLogger.Println("There is some problem in getting all payments : ", err)
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
 		if payments == nil {
 			ga.App.ErrorLogger.Println("There is some problem in getting all payments : ", err)
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+			abortWithError(ctx, err)
+			return
 		}
 
-		ctx.JSON(http.StatusOK, gin.H{"message": "All payments fetched successfully", "data": payments})
+		ctx.JSON(http.StatusOK, gin.H{"message": "All payments fetched successfully", "data": redact(payments)})
 	}
 }
 
 func (ga *GoApp) CreateCSE() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		// Parse the request body
-		var cse *model.CSE
+		var input dto.CreateCSERequest
 
-		err := ctx.ShouldBindJSON(&cse)
+		err := ctx.ShouldBindJSON(&input)
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusBadRequest, gin.Error{
-				Err: err,
-			})
+			badRequest(ctx, err)
+			return
 		}
-		// Set up the CSE object with initial values		cse.Password = hashedPassword
+
+		cse := input.ToCSE()
+
 		cse.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		cse.UpdatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		cse.Password, _ = encrypt.Hash(cse.Password)
-		cse.Status = "offline"
-		cse.ActiveChats = []primitive.ObjectID{}
-		cse.PendingChats = []primitive.ObjectID{}
-		cse.ClosedChats = []primitive.ObjectID{}
-		cse.ActiveChatsCount = 0
-		cse.PendingChatsCount = 0
-
-		// Insert into database
-
-		fmt.Printf("Inserting CSE: %v\n", cse)
-
-		fmt.Printf("Information sent :- ")
-		fmt.Printf("ID: %v\n", cse.ID)
-		fmt.Printf("Name: %v\n", cse.Name)
-		fmt.Printf("Email: %v\n", cse.Email)
-		fmt.Printf("Password: %v\n", cse.Password)
-		fmt.Printf("Phone: %v\n", cse.PhoneNumber)
-		fmt.Printf("Status: %v\n", cse.Status)
-		fmt.Printf("ActiveChats: %v\n", cse.ActiveChats)
-		fmt.Printf("PendingChats: %v\n", cse.PendingChats)
-		fmt.Printf("ClosedChats: %v\n", cse.ClosedChats)
-		fmt.Printf("ActiveChatsCount: %v\n", cse.ActiveChatsCount)
-		fmt.Printf("PendingChatsCount: %v\n", cse.PendingChatsCount)
-		fmt.Printf("CreatedAt: %v\n", cse.CreatedAt)
-		fmt.Printf("UpdatedAt: %v\n", cse.UpdatedAt)
-
-		fmt.Printf("CSE Object:- \nCSE_ID: %v\nPassword: %v\nName: %v\nPhone_Number: %v\nEmail: %v\n", cse.CseID, cse.Password, cse.Name, cse.PhoneNumber, cse.Email)
-		// Insert CSE into database
-		if err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, gin.Error{Err: err})
+		if weakPassword(ctx, cse.Password, cse.Email, cse.Name, cse.CseID) {
+			return
 		}
 
-		if err := ga.App.Validate.Struct(&cse); err != nil {
-			if _, ok := err.(*validator.InvalidValidationError); !ok {
-				_ = ctx.AbortWithError(http.StatusBadRequest, gin.Error{Err: err})
-				ga.App.InfoLogger.Println(err)
-				return
-			}
+		cse.Password, err = encrypt.Hash(cse.Password)
+		if err != nil {
+			internalError(ctx, err, "error while adding new cse")
+			return
 		}
 
+		// Insert CSE into database
 		ok, status, err := ga.DB.InsertCSE(cse)
 
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, errors.New("error while adding new CSE"))
-			ctx.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+			abortWithError(ctx, err)
 			return
 		}
 
 		if !ok {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, err)
+			abortWithError(ctx, err)
 			return
 		}
 
@@ -1581,7 +1949,7 @@ func (ga *GoApp) CreateCSE() gin.HandlerFunc {
 			}
 		case 2:
 			{
-				ctx.JSON(http.StatusConflict, gin.H{"message": "CSE already exists"})
+				respondError(ctx, http.StatusConflict, "CSE already exists")
 			}
 		}
 	}
@@ -1591,13 +1959,13 @@ func (ga *GoApp) GetAllCSEData() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		cses, err := ga.DB.GetAllCSEs()
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get CSEs"})
+			respondError(ctx, http.StatusInternalServerError, "Failed to get CSEs")
 			ga.App.ErrorLogger.Printf("Error getting CSEs: %v", err)
 			return
 		}
 		ctx.JSON(http.StatusOK, gin.H{
 			"message": "CSEs fetched successfully",
-			"data":    cses,
+			"data":    redact(cses),
 		})
 	}
 }
@@ -1605,95 +1973,137 @@ func (ga *GoApp) GetAllCSEData() gin.HandlerFunc {
 func (ga *GoApp) CSELogin() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 
-		var cse *model.CSE
+		var cse dto.CSELoginRequest
 		if err := ctx.ShouldBindJSON(&cse); err != nil {
-			_ = ctx.AbortWithError(http.StatusBadRequest, gin.Error{Err: err})
+			badRequest(ctx, err)
+			return
+		}
+
+		if ga.loginLocked(ctx, auth.CSEPrincipal, cse.CseID) {
+			return
 		}
 
 		// Get CSE from database
 		res, err := ga.DB.GetCSEByCredentials(cse.CseID)
 		if err != nil {
-			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+			if !errors.Is(err, domain.ErrNotFound) {
+				abortWithError(ctx, err)
+				return
+			}
+			ga.recordLoginFailure(ctx, auth.CSEPrincipal, cse.CseID)
+			respondError(ctx, http.StatusUnauthorized, "Invalid credentials")
 			return
 		}
 
-		fmt.Print("cse from DB : ", res)
-
-		id := res["_id"].(primitive.ObjectID)
 		password := res["password"].(string)
 
-		fmt.Printf("ID: %v\n", id)
-		fmt.Printf("Password: %v\n", password)
-
 		verified, err := encrypt.VerifyPassword(cse.Password, password)
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusInternalServerError, err)
-			ctx.JSON(http.StatusUnauthorized, gin.H{"message": "unregistered cse detected using wrong password"})
+			ga.recordLoginFailure(ctx, auth.CSEPrincipal, cse.CseID)
+			respondError(ctx, http.StatusUnauthorized, "unregistered cse detected using wrong password")
 			return
 		}
 
 		if verified {
 
-			cookieData := sessions.Default(ctx)
-
-			cseInfo := map[string]interface{}{
-				"ID":    id,
-				"Email": cse.Email,
-				"Name":  res["name"],
-			}
+			ga.clearLoginFailures(auth.CSEPrincipal, cse.CseID)
 
-			cookieData.Set("cseInfo", cseInfo)
+			ga.rehashPassword(auth.CSEPrincipal, res["_id"].(primitive.ObjectID), cse.Password, password)
 
-			if err := cookieData.Save(); err != nil {
-				_ = ctx.AbortWithError(http.StatusInternalServerError, err)
-				ctx.JSON(http.StatusInternalServerError, gin.H{"message": "error while saving cookie"})
+			if ga.secondFactorRequired(ctx, auth.CSEPrincipal, res) {
 				return
 			}
 
-			t1, t2, err := auth.Generate(res["email"].(string), id, res["name"].(string))
+			ga.completeCSELogin(ctx, res, nil)
+		} else {
+			respondError(ctx, http.StatusUnauthorized, "unregistered admin detected using wrong credentials")
+		}
+	}
+}
 
-			if err != nil {
-				_ = ctx.AbortWithError(http.StatusInternalServerError, err)
-				ctx.JSON(http.StatusInternalServerError, gin.H{"message": "error while generating tokens"})
-				return
-			}
+// completeCSELogin signs in a CSE whose credentials, and second factor when enabled, have been verified.
+func (ga *GoApp) completeCSELogin(ctx *gin.Context, res primitive.M, extra gin.H) {
+	id := res["_id"].(primitive.ObjectID)
 
-			cookieData.Set("cse_token", t1)
+	cookieData := sessions.Default(ctx)
 
-			if err := cookieData.Save(); err != nil {
-				_ = ctx.AbortWithError(http.StatusInternalServerError, err)
-				ctx.JSON(http.StatusInternalServerError, gin.H{"message": "error while saving cookie"})
-				return
-			}
+	cseInfo := map[string]interface{}{
+		"ID":    id,
+		"Email": res["email"],
+		"Name":  res["name"],
+	}
 
-			cookieData.Set("new_cse_token", t2)
+	cookieData.Set("cseInfo", cseInfo)
 
-			if err := cookieData.Save(); err != nil {
-				_ = ctx.AbortWithError(http.StatusInternalServerError, err)
-				ctx.JSON(http.StatusInternalServerError, gin.H{"message": "error while saving cookie"})
-				return
-			}
+	if err := cookieData.Save(); err != nil {
+		internalError(ctx, err, "error while saving cookie")
+		return
+	}
 
-			err = ga.DB.UpdateCSEStatus(id, "online")
-			if err != nil {
-				ga.App.ErrorLogger.Printf("Error updating CSE status: %v", err)
-				// Continue anyway, as login was successful
-			}
+	subject, err := ga.subjectFor(auth.CSEPrincipal, res)
 
-			ctx.JSON(http.StatusOK, gin.H{
-				"message":       "Successfully Logged in",
-				"email":         res["email"],
-				"id":            id,
-				"name":          res["name"],
-				"session_token": t1,
-				"cse_id":        cse.CseID,
-				"phone_number":  res["phone_number"],
-			})
-		} else {
-			ctx.JSON(http.StatusUnauthorized, gin.H{"message": "unregistered admin detected using wrong credentials"})
-			return
-		}
+	if err != nil {
+		ga.App.ErrorLogger.Println(err)
+		respondError(ctx, http.StatusInternalServerError, "error while resolving role")
+		return
+	}
+
+	family, t1, t2, err := ga.startSession(ctx, subject)
+
+	if err != nil {
+		internalError(ctx, err, "error while generating tokens")
+		return
+	}
+
+	cookieData.Set("cse_token", t1)
+
+	if err := cookieData.Save(); err != nil {
+		internalError(ctx, err, "error while saving cookie")
+		return
+	}
+
+	cookieData.Set("new_cse_token", t2)
+
+	if err := cookieData.Save(); err != nil {
+		internalError(ctx, err, "error while saving cookie")
+		return
+	}
+
+	tk := map[string]string{
+		"token":    t1,
+		"newToken": t2,
+		"family":   family,
+	}
+
+	updated, err := ga.DB.UpdateCSE(id, tk)
+
+	if err != nil || !updated {
+		respondError(ctx, http.StatusInternalServerError, "error while updating tokens")
+		return
 	}
+
+	err = ga.DB.UpdateCSEStatus(id, "online")
+	if err != nil {
+		ga.App.ErrorLogger.Printf("Error updating CSE status: %v", err)
+		// Continue anyway, as login was successful
+	}
+
+	response := gin.H{
+		"message":       "Successfully Logged in",
+		"email":         res["email"],
+		"id":            id,
+		"name":          res["name"],
+		"session_token": t1,
+		"refresh_token": t2,
+		"cse_id":        res["cse_id"],
+		"phone_number":  res["phone_number"],
+	}
+
+	for k, v := range extra {
+		response[k] = v
+	}
+
+	ctx.JSON(http.StatusOK, response)
 }
 
 // Also add a logout handler
@@ -1702,17 +2112,27 @@ func (ga *GoApp) CSELogout() gin.HandlerFunc {
 		// Get CSE ID from context (set by middleware)
 		uidInterface, exists := ctx.Get("UID")
 		if !exists {
-			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			respondError(ctx, http.StatusUnauthorized, "Unauthorized")
 			return
 		}
 
 		fmt.Print("uidInterface : ", uidInterface)
 
 		uid := uidInterface.(primitive.ObjectID)
+		claims := ctx.MustGet("Claims").(*auth.GoAppClaims)
+
+		if err := auth.Revoke(claims); err != nil {
+			ga.App.ErrorLogger.Printf("Error revoking token: %v", err)
+			respondError(ctx, http.StatusInternalServerError, "Failed to revoke token")
+			return
+		}
+
+		ga.endSession(claims)
+
 		// Update CSE status to offline
 		err := ga.DB.UpdateCSEStatus(uid, "offline")
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update status"})
+			respondError(ctx, http.StatusInternalServerError, "Failed to update status")
 			return
 		}
 
@@ -1723,26 +2143,24 @@ func (ga *GoApp) CSELogout() gin.HandlerFunc {
 func (ga *GoApp) CreateChat() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		// Parse request body
-		var chat *model.Chat
+		var input dto.CreateChatRequest
 
-		err := ctx.ShouldBindJSON(&chat)
+		err := ctx.ShouldBindJSON(&input)
 		if err != nil {
-			_ = ctx.AbortWithError(http.StatusBadRequest, gin.Error{
-				Err: err,
-			})
+			badRequest(ctx, err)
+			return
 		}
 
-		fmt.Print("Chat in phase 1(input): ", chat)
+		chat := input.ToChat(ctx.MustGet("UID").(primitive.ObjectID))
 
 		chat.DateCreated, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		chat.LastMessageTime, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		chat.Messages = []primitive.ObjectID{}
 		chat.Status = "waiting"
 
 		// Create the chat
 		chatID, err := ga.DB.CreateChat(chat)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create chat"})
+			abortWithError(ctx, err)
 			return
 		}
 		// Return success response
@@ -1761,10 +2179,10 @@ func (ga *GoApp) Get_All_The_Orders() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		orders, err := ga.DB.GetAllTheOrders()
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get orders"})
+			respondError(ctx, http.StatusInternalServerError, "Failed to get orders")
 			return
 		}
-		ctx.JSON(http.StatusOK, gin.H{"orders": orders})
+		ctx.JSON(http.StatusOK, gin.H{"orders": redact(orders)})
 	}
 }
 
@@ -1851,7 +2269,7 @@ func (ga *GoApp) SendMessageAsUser() gin.HandlerFunc {
 		}
 
 		if err := ctx.ShouldBindJSON(&input); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			badRequest(ctx, err)
 			return
 		}
 
@@ -1859,19 +2277,19 @@ func (ga *GoApp) SendMessageAsUser() gin.HandlerFunc {
 
 		chatID, err := primitive.ObjectIDFromHex(input.ChatID)
 		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chat ID format"})
+			respondError(ctx, http.StatusBadRequest, "Invalid chat ID format")
 			return
 		}
 
 		receiverID, err := primitive.ObjectIDFromHex(input.ReceiverID)
 		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid receiver ID format"})
+			respondError(ctx, http.StatusBadRequest, "Invalid receiver ID format")
 			return
 		}
 
 		senderID, err := primitive.ObjectIDFromHex(input.SenderID)
 		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sender ID format"})
+			respondError(ctx, http.StatusBadRequest, "Invalid sender ID format")
 			return
 		}
 
@@ -1879,7 +2297,7 @@ func (ga *GoApp) SendMessageAsUser() gin.HandlerFunc {
 		// Get chat to verify access
 		chat, err := ga.DB.GetChatByID(chatID)
 		if err != nil {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "Chat not found"})
+			respondError(ctx, http.StatusNotFound, "Chat not found")
 			return
 		}
 
@@ -1887,12 +2305,12 @@ func (ga *GoApp) SendMessageAsUser() gin.HandlerFunc {
 
 		// Verify that the user has access to this chat
 		if chat.UserID != senderID {
-			ctx.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this chat"})
+			respondError(ctx, http.StatusForbidden, "You don't have access to this chat")
 			return
 		}
 		// Verify chat status
 		if chat.Status == "closed" {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot send messages to a closed chat"})
+			respondError(ctx, http.StatusBadRequest, "Cannot send messages to a closed chat")
 			return
 		}
 		// If chat is pending and CSE is sending a message, move it to activ
@@ -1908,7 +2326,7 @@ func (ga *GoApp) SendMessageAsUser() gin.HandlerFunc {
 
 		messageID, err := ga.DB.AddMessage(message)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send message"})
+			respondError(ctx, http.StatusInternalServerError, "Failed to send message")
 			return
 		}
 
@@ -1932,7 +2350,7 @@ func (ga *GoApp) SendMessageAsCSE() gin.HandlerFunc {
 		}
 
 		if err := ctx.ShouldBindJSON(&input); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			badRequest(ctx, err)
 			return
 		}
 
@@ -1940,19 +2358,19 @@ func (ga *GoApp) SendMessageAsCSE() gin.HandlerFunc {
 
 		chatID, err := primitive.ObjectIDFromHex(input.ChatID)
 		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chat ID format"})
+			respondError(ctx, http.StatusBadRequest, "Invalid chat ID format")
 			return
 		}
 
 		receiverID, err := primitive.ObjectIDFromHex(input.ReceiverID)
 		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid receiver ID format"})
+			respondError(ctx, http.StatusBadRequest, "Invalid receiver ID format")
 			return
 		}
 
 		senderID, err := primitive.ObjectIDFromHex(input.SenderID)
 		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sender ID format"})
+			respondError(ctx, http.StatusBadRequest, "Invalid sender ID format")
 			return
 		}
 
@@ -1961,7 +2379,7 @@ func (ga *GoApp) SendMessageAsCSE() gin.HandlerFunc {
 		// Get chat to verify access
 		chat, err := ga.DB.GetChatByID(chatID)
 		if err != nil {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "Chat not found"})
+			respondError(ctx, http.StatusNotFound, "Chat not found")
 			return
 		}
 
@@ -1969,12 +2387,12 @@ func (ga *GoApp) SendMessageAsCSE() gin.HandlerFunc {
 
 		// Verify that the user has access to this chat
 		if chat.CseID != senderID {
-			ctx.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this chat"})
+			respondError(ctx, http.StatusForbidden, "You don't have access to this chat")
 			return
 		}
 		// Verify chat status
 		if chat.Status == "closed" || chat.Status == "pending" {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Cannot send messages to a closed or pending chat"})
+			respondError(ctx, http.StatusBadRequest, "Cannot send messages to a closed or pending chat")
 			return
 		}
 		// If chat is pending and CSE is sending a message, move it to activ
@@ -1990,7 +2408,7 @@ func (ga *GoApp) SendMessageAsCSE() gin.HandlerFunc {
 
 		messageID, err := ga.DB.AddMessage(message)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send message"})
+			respondError(ctx, http.StatusInternalServerError, "Failed to send message")
 			return
 		}
 
@@ -2009,7 +2427,7 @@ func (ga *GoApp) GetChatHistory() gin.HandlerFunc {
 		// Get user ID from context (set by middleware)// Get chat ID from URL parameter
 		chatID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
 		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chat ID format"})
+			respondError(ctx, http.StatusBadRequest, "Invalid chat ID format")
 			return
 		}
 
@@ -2017,7 +2435,7 @@ func (ga *GoApp) GetChatHistory() gin.HandlerFunc {
 		// Get chat to verify access
 		chat, err := ga.DB.GetChatByID(chatID)
 		if err != nil {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "Chat not found"})
+			respondError(ctx, http.StatusNotFound, "Chat not found")
 			return
 		}
 
@@ -2026,7 +2444,7 @@ func (ga *GoApp) GetChatHistory() gin.HandlerFunc {
 		// Get messages for this chat
 		messages, err := ga.DB.GetMessagesByChat(chatID)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve chat history"})
+			respondError(ctx, http.StatusInternalServerError, "Failed to retrieve chat history")
 			return
 		}
 
@@ -2052,26 +2470,26 @@ func (ga *GoApp) MoveChatToActive() gin.HandlerFunc {
 		}
 
 		if err := ctx.ShouldBindJSON(&input); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			badRequest(ctx, err)
 			return
 		}
 
 		chatID, err := primitive.ObjectIDFromHex(input.ChatID)
 		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chat ID format"})
+			respondError(ctx, http.StatusBadRequest, "Invalid chat ID format")
 			return
 		}
 
 		cseID, err := primitive.ObjectIDFromHex(input.CseID)
 		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid CSE ID format"})
+			respondError(ctx, http.StatusBadRequest, "Invalid CSE ID format")
 			return
 		}
 
 		// Move chat from pending to active
 		err = ga.DB.MoveChatFromPendingToActive(chatID, cseID)
 		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			abortWithError(ctx, err)
 			return
 		}
 
@@ -2091,20 +2509,20 @@ func (ga *GoApp) CloseChat() gin.HandlerFunc {
 		fmt.Print("Chat in phase 1 in handler: ", input)
 
 		if err := ctx.ShouldBindJSON(&input); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			badRequest(ctx, err)
 			return
 		}
 
 		chatID, err := primitive.ObjectIDFromHex(input.ChatID)
 		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chat ID format"})
+			respondError(ctx, http.StatusBadRequest, "Invalid chat ID format")
 			return
 		}
 
 		// Get chat to verify access
 		chat, err := ga.DB.GetChatByID(chatID)
 		if err != nil {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "Chat not found"})
+			respondError(ctx, http.StatusNotFound, "Chat not found")
 			return
 		}
 
@@ -2113,7 +2531,7 @@ func (ga *GoApp) CloseChat() gin.HandlerFunc {
 		// Close the chat
 		err = ga.DB.CloseChat(chatID)
 		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			abortWithError(ctx, err)
 			return
 		}
 
@@ -2137,19 +2555,19 @@ func (ga *GoApp) ReopenChat() gin.HandlerFunc {
 		fmt.Print("Phase 1, Inputs : ", input)
 
 		if err := ctx.ShouldBindJSON(&input); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			badRequest(ctx, err)
 			return
 		}
 
 		chatID, err := primitive.ObjectIDFromHex(input.ChatID)
 		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chat ID format"})
+			respondError(ctx, http.StatusBadRequest, "Invalid chat ID format")
 			return
 		}
 
 		userID, err := primitive.ObjectIDFromHex(input.UserID)
 		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+			respondError(ctx, http.StatusBadRequest, "Invalid user ID format")
 			return
 		}
 
@@ -2158,7 +2576,7 @@ func (ga *GoApp) ReopenChat() gin.HandlerFunc {
 		// Reopen the chat
 		err = ga.DB.ReopenChat(chatID, userID)
 		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			abortWithError(ctx, err)
 			return
 		}
 
@@ -2201,12 +2619,13 @@ func (ga *GoApp) CreateReview() gin.HandlerFunc {
 			Review     string `json:"review" binding:"required"`
 			Rating     int    `json:"rating" binding:"required,min=1,max=5"`
 			OrderID    string `json:"order_id" binding:"required"`
+			VariantID  string `json:"variant_id" binding:"omitempty,mongodb"`
 		}
 
 		fmt.Print("Review in phase 1 in handler: ", reviewInput)
 
 		if err := ctx.ShouldBindJSON(&reviewInput); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			badRequest(ctx, err)
 			return
 		}
 
@@ -2220,7 +2639,7 @@ func (ga *GoApp) CreateReview() gin.HandlerFunc {
 		// Convert string ID to ObjectID
 		productObjID, err := primitive.ObjectIDFromHex(reviewInput.ProductID)
 		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID format"})
+			respondError(ctx, http.StatusBadRequest, "Invalid product ID format")
 			return
 		}
 
@@ -2228,7 +2647,7 @@ func (ga *GoApp) CreateReview() gin.HandlerFunc {
 
 		customerObjID, err := primitive.ObjectIDFromHex(reviewInput.CustomerID)
 		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID format"})
+			respondError(ctx, http.StatusBadRequest, "Invalid customer ID format")
 			return
 		}
 
@@ -2236,15 +2655,19 @@ func (ga *GoApp) CreateReview() gin.HandlerFunc {
 
 		orderObjID, err := primitive.ObjectIDFromHex(reviewInput.OrderID)
 		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID format"})
+			respondError(ctx, http.StatusBadRequest, "Invalid order ID format")
 			return
 		}
 
 		fmt.Print("Review in phase 2 in handler: Order ID : ", orderObjID)
 
+		// The rating counts for the product as a whole; the variant only says what was bought.
+		variantObjID, _ := primitive.ObjectIDFromHex(reviewInput.VariantID)
+
 		// Create new review object
 		review := &model.Review{
 			ProductID:  productObjID,
+			VariantID:  variantObjID,
 			Review:     reviewInput.Review,
 			Rating:     reviewInput.Rating,
 			CustomerID: customerObjID,
@@ -2256,7 +2679,7 @@ func (ga *GoApp) CreateReview() gin.HandlerFunc {
 		// Save review to database
 		savedReview, err := ga.DB.CreateReview(review)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create review"})
+			respondError(ctx, http.StatusInternalServerError, "Failed to create review")
 			return
 		}
 
@@ -2265,13 +2688,13 @@ func (ga *GoApp) CreateReview() gin.HandlerFunc {
 		// Update product with the new review
 		err = ga.DB.UpdateProductWithReview(productObjID, savedReview)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update product with review"})
+			respondError(ctx, http.StatusInternalServerError, "Failed to update product with review")
 			return
 		}
 
 		err = ga.DB.UpdateOrderWithRated(orderObjID)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order with rated value"})
+			respondError(ctx, http.StatusInternalServerError, "Failed to update order with rated value")
 			return
 		}
 
@@ -2291,13 +2714,13 @@ func (ga *GoApp) GetProductReviews() gin.HandlerFunc {
 
 		productObjID, err := primitive.ObjectIDFromHex(productID)
 		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID format"})
+			respondError(ctx, http.StatusBadRequest, "Invalid product ID format")
 			return
 		}
 
 		reviews, err := ga.DB.GetReviewsByProductID(productObjID)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reviews"})
+			respondError(ctx, http.StatusInternalServerError, "Failed to fetch reviews")
 			return
 		}
 
@@ -2315,19 +2738,19 @@ func (ga *GoApp) GetUserReviews() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		customerID, exists := ctx.Get("customer_id")
 		if !exists {
-			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			respondError(ctx, http.StatusUnauthorized, "User not authenticated")
 			return
 		}
 
 		customerObjID, err := primitive.ObjectIDFromHex(customerID.(string))
 		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID format"})
+			respondError(ctx, http.StatusBadRequest, "Invalid customer ID format")
 			return
 		}
 
 		reviews, err := ga.DB.GetReviewsByCustomerID(customerObjID)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user reviews"})
+			respondError(ctx, http.StatusInternalServerError, "Failed to fetch user reviews")
 			return
 		}
 
@@ -2345,13 +2768,13 @@ func (ga *GoApp) GetUserReviews() gin.HandlerFunc {
 
 //         reviewObjID, err := primitive.ObjectIDFromHex(reviewID)
 //         if err != nil {
-//             ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID format"})
+//             respondError(ctx, http.StatusBadRequest, "Invalid review ID format")
 //             return
 //         }
 
 //         err = ga.DB.DeleteReview(reviewObjID)
 //         if err != nil {
-//             ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete review"})
+//             respondError(ctx, http.StatusInternalServerError, "Failed to delete review")
 //             return
 //         }
 
@@ -2373,7 +2796,7 @@ func (ga *GoApp) UpdateProductSummarizedReview() gin.HandlerFunc {
 		}
 
 		if err := ctx.ShouldBindJSON(&input); err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			badRequest(ctx, err)
 			return
 		}
 
@@ -2382,14 +2805,14 @@ func (ga *GoApp) UpdateProductSummarizedReview() gin.HandlerFunc {
 		// Convert string ID to ObjectID
 		productObjID, err := primitive.ObjectIDFromHex(input.ProductID)
 		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid product ID format"})
+			respondError(ctx, http.StatusBadRequest, "Invalid product ID format")
 			return
 		}
 
 		// Update the product with the summarized review
 		err = ga.DB.UpdateProductSummarizedReview(productObjID, input.SummarizedReview)
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update product with summarized review: " + err.Error()})
+			abortWithError(ctx, err)
 			return
 		}
 
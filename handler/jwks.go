@@ -0,0 +1,16 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// JWKS publishes the public keys tokens are signed with, so other services can verify them.
+func (ga *GoApp) JWKS() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Header("Cache-Control", "public, max-age=300")
+		ctx.JSON(http.StatusOK, auth.JWKS())
+	}
+}
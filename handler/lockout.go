@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/auth"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Sign in throttling. An account is locked after accountLockThreshold failures and an IP address
+// after ipLockThreshold, for baseLockout doubled with every further failure up to maxLockout.
+const (
+	accountLockThreshold = 5
+	ipLockThreshold      = 20
+	baseLockout          = time.Minute
+	maxLockout           = time.Hour
+	failureWindow        = time.Hour
+)
+
+func accountAttemptKey(principal string, identifier string) string {
+	return principal + ":account:" + strings.ToLower(identifier)
+}
+
+func ipAttemptKey(ip string) string {
+	return "ip:" + ip
+}
+
+// lockoutFor returns how long failures locks a counter with the given threshold for.
+func lockoutFor(failures int, threshold int) time.Duration {
+	if failures < threshold {
+		return 0
+	}
+
+	exponent := failures - threshold
+	if exponent > 16 {
+		exponent = 16
+	}
+
+	lockout := time.Duration(float64(baseLockout) * math.Pow(2, float64(exponent)))
+	if lockout > maxLockout {
+		lockout = maxLockout
+	}
+
+	return lockout
+}
+
+// loginLocked answers the request with 429 when the account or the client's IP address is locked.
+// It reports whether it has answered the request.
+func (ga *GoApp) loginLocked(ctx *gin.Context, principal string, identifier string) bool {
+	attempts, err := ga.DB.GetLoginAttempts([]string{accountAttemptKey(principal, identifier), ipAttemptKey(ctx.ClientIP())})
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, "error while checking sign in attempts")
+		return true
+	}
+
+	var lockedUntil time.Time
+	for _, attempt := range attempts {
+		if attempt.LockedUntil.After(lockedUntil) {
+			lockedUntil = attempt.LockedUntil
+		}
+	}
+
+	retryAfter := time.Until(lockedUntil)
+	if retryAfter <= 0 {
+		return false
+	}
+
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+
+	ctx.Header("Retry-After", fmt.Sprint(seconds))
+	RespondError(ctx, http.StatusTooManyRequests, "login_locked", "too many failed sign in attempts, try again later", gin.H{"retry_after": seconds})
+	return true
+}
+
+// recordLoginFailure counts a failed sign in against the account and the client's IP address and
+// locks whichever has failed too often.
+func (ga *GoApp) recordLoginFailure(ctx *gin.Context, principal string, identifier string) {
+	counters := []*model.LoginAttempt{
+		{ID: accountAttemptKey(principal, identifier), Kind: "account", Principal: principal, Identifier: strings.ToLower(identifier)},
+		{ID: ipAttemptKey(ctx.ClientIP()), Kind: "ip", Identifier: ctx.ClientIP()},
+	}
+
+	for _, counter := range counters {
+		attempt, err := ga.DB.RecordLoginFailure(counter, failureWindow)
+		if err != nil {
+			ga.App.ErrorLogger.Printf("Error recording failed login of %s %s: %v", counter.Kind, counter.Identifier, err)
+			continue
+		}
+
+		threshold := accountLockThreshold
+		if attempt.Kind == "ip" {
+			threshold = ipLockThreshold
+		}
+
+		lockout := lockoutFor(attempt.Failures, threshold)
+		if lockout == 0 {
+			continue
+		}
+
+		lockedUntil := time.Now().Add(lockout)
+		if err := ga.DB.LockLogin(attempt.ID, lockedUntil, failureWindow); err != nil {
+			ga.App.ErrorLogger.Printf("Error locking sign in of %s %s: %v", attempt.Kind, attempt.Identifier, err)
+			continue
+		}
+
+		ga.securityEvent(&model.SecurityEvent{
+			Type:       "login_lockout",
+			Principal:  principal,
+			Identifier: attempt.Identifier,
+			IP:         ctx.ClientIP(),
+			Details: map[string]interface{}{
+				"kind":         attempt.Kind,
+				"failures":     attempt.Failures,
+				"locked_until": lockedUntil,
+			},
+		})
+	}
+}
+
+// clearLoginFailures resets the account's counter after a successful sign in. The IP counter is
+// left alone so one valid account cannot be used to keep guessing others.
+func (ga *GoApp) clearLoginFailures(principal string, identifier string) {
+	if _, err := ga.DB.ClearLoginAttempts(accountAttemptKey(principal, identifier)); err != nil {
+		ga.App.ErrorLogger.Printf("Error clearing login attempts of %s: %v", identifier, err)
+	}
+}
+
+// securityEvent stores the event and writes it to the log as a single JSON line.
+func (ga *GoApp) securityEvent(event *model.SecurityEvent) {
+	event.CreatedAt = time.Now()
+
+	if err := ga.DB.RecordSecurityEvent(event); err != nil {
+		ga.App.ErrorLogger.Printf("Error storing security event %s: %v", event.Type, err)
+	}
+
+	line, err := json.Marshal(gin.H{"security_event": event})
+	if err != nil {
+		ga.App.ErrorLogger.Printf("Error encoding security event %s: %v", event.Type, err)
+		return
+	}
+
+	ga.App.InfoLogger.Println(string(line))
+}
+
+// UnlockAccount lifts the sign in lockout of an account, or of an IP address.
+func (ga *GoApp) UnlockAccount() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var input struct {
+			Principal  string `json:"principal"`
+			Identifier string `json:"identifier"`
+			IP         string `json:"ip"`
+		}
+
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			badRequest(ctx, err)
+			return
+		}
+
+		var key string
+
+		switch {
+		case input.IP != "":
+			key = ipAttemptKey(input.IP)
+		case input.Identifier != "" && (input.Principal == auth.UserPrincipal || input.Principal == auth.AdminPrincipal || input.Principal == auth.CSEPrincipal):
+			key = accountAttemptKey(input.Principal, input.Identifier)
+		default:
+			respondError(ctx, http.StatusBadRequest, "Either ip, or principal (user, admin or cse) and identifier are required")
+			return
+		}
+
+		cleared, err := ga.DB.ClearLoginAttempts(key)
+		if err != nil {
+			respondError(ctx, http.StatusInternalServerError, "Failed to unlock")
+			return
+		}
+
+		if !cleared {
+			respondError(ctx, http.StatusNotFound, "No failed sign ins recorded")
+			return
+		}
+
+		ga.securityEvent(&model.SecurityEvent{
+			Type:       "login_unlocked",
+			Principal:  input.Principal,
+			Identifier: strings.ToLower(input.Identifier),
+			IP:         input.IP,
+			Actor:      ctx.MustGet("UID").(primitive.ObjectID),
+		})
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "Unlocked successfully"})
+	}
+}
+
+func (ga *GoApp) GetSecurityEvents() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		events, err := ga.DB.GetSecurityEvents(100)
+		if err != nil {
+			respondError(ctx, http.StatusInternalServerError, "Failed to fetch security events")
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "Security events fetched successfully", "data": events})
+	}
+}
@@ -0,0 +1,496 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/auth"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/database/query"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/encrypt"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/totp"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	mfaTokenLifetime  = 5 * time.Minute
+	recoveryCodeCount = 10
+	totpIssuer        = "CarsGo"
+)
+
+// mfaPurpose ties an mfa token to the principal type it was issued for, so an admin challenge
+// cannot be answered on the cse endpoint and vice versa.
+func mfaPurpose(purpose string, principal string) string {
+	return purpose + ":" + principal
+}
+
+func int64Field(doc primitive.M, key string) int64 {
+	switch v := doc[key].(type) {
+	case int64:
+		return v
+	case int32:
+		return int64(v)
+	}
+	return 0
+}
+
+// secondFactorRequired answers a sign in whose password was correct with an mfa token when the
+// account has two factor authentication enabled, or when policy requires the admin to enroll first.
+// It reports whether it has answered the request.
+func (ga *GoApp) secondFactorRequired(ctx *gin.Context, principal string, res primitive.M) bool {
+	id := res["_id"].(primitive.ObjectID)
+	email, _ := res["email"].(string)
+
+	purpose := auth.PurposeMFALogin
+
+	if enabled, _ := res["totp_enabled"].(bool); !enabled {
+		if principal != auth.AdminPrincipal {
+			return false
+		}
+
+		settings, err := ga.DB.GetSecuritySettings()
+		if err != nil {
+			respondError(ctx, http.StatusInternalServerError, "error while checking security policy")
+			return true
+		}
+
+		if !settings.RequireAdmin2FA {
+			return false
+		}
+
+		purpose = auth.PurposeMFAEnroll
+	}
+
+	token, jti, err := auth.IssueActionToken(mfaPurpose(purpose, principal), id.Hex(), email, mfaTokenLifetime)
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, "error while generating tokens")
+		return true
+	}
+
+	if err := ga.DB.StartMFAChallenge(principal, id, jti); err != nil {
+		respondError(ctx, http.StatusInternalServerError, "error while starting two factor challenge")
+		return true
+	}
+
+	if purpose == auth.PurposeMFAEnroll {
+		ctx.JSON(http.StatusOK, gin.H{
+			"message":                 "Two factor authentication must be set up before signing in",
+			"mfa_enrollment_required": true,
+			"mfa_token":               token,
+		})
+		return true
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":      "Two factor code required",
+		"mfa_required": true,
+		"mfa_token":    token,
+	})
+	return true
+}
+
+func challengeExpired(ctx *gin.Context) {
+	RespondError(ctx, http.StatusUnauthorized, "mfa_challenge_expired", "Two factor challenge is invalid or has expired, please sign in again", nil)
+}
+
+// resolveChallenge returns the account an mfa token was issued for, as long as the challenge it
+// belongs to is still open. It answers the request itself when it is not.
+func (ga *GoApp) resolveChallenge(ctx *gin.Context, principal string, purpose string, mfaToken string) (*auth.ActionClaims, primitive.M, bool) {
+	claims, err := auth.ParseActionToken(mfaPurpose(purpose, principal), mfaToken)
+	if err != nil {
+		challengeExpired(ctx)
+		return nil, nil, false
+	}
+
+	id, err := primitive.ObjectIDFromHex(claims.Subject)
+	if err != nil {
+		challengeExpired(ctx)
+		return nil, nil, false
+	}
+
+	res, err := ga.DB.GetPrincipalByID(principal, id)
+	if err != nil {
+		challengeExpired(ctx)
+		return nil, nil, false
+	}
+
+	if challenge, _ := res["mfa_challenge"].(string); challenge != claims.ID || int64Field(res, "mfa_challenge_failures") >= query.MaxMFAFailures {
+		challengeExpired(ctx)
+		return nil, nil, false
+	}
+
+	return claims, res, true
+}
+
+// verifySecondFactor checks a TOTP code, or failing that a recovery code, and uses it up.
+func (ga *GoApp) verifySecondFactor(principal string, res primitive.M, code string, recoveryCode string) (bool, error) {
+	id := res["_id"].(primitive.ObjectID)
+
+	if code != "" {
+		secret, _ := res["totp_secret"].(string)
+
+		step, ok := totp.Validate(code, secret, time.Now(), int64Field(res, "totp_last_step"))
+		if !ok {
+			return false, nil
+		}
+
+		return ga.DB.RecordTOTPStep(principal, id, step)
+	}
+
+	if recoveryCode != "" {
+		hashes, _ := res["recovery_codes"].(primitive.A)
+		recoveryCode = totp.NormalizeRecoveryCode(recoveryCode)
+
+		for _, h := range hashes {
+			hashed, _ := h.(string)
+			if ok, _ := encrypt.VerifyPassword(recoveryCode, hashed); ok {
+				return ga.DB.ConsumeRecoveryCode(principal, id, hashed)
+			}
+		}
+	}
+
+	return false, nil
+}
+
+func (ga *GoApp) completeLogin(ctx *gin.Context, principal string, res primitive.M, extra gin.H) {
+	switch principal {
+	case auth.AdminPrincipal:
+		ga.completeAdminLogin(ctx, res, extra)
+	case auth.CSEPrincipal:
+		ga.completeCSELogin(ctx, res, extra)
+	}
+}
+
+// beginEnrollment generates a new secret for the account and returns it with its provisioning URI.
+func (ga *GoApp) beginEnrollment(ctx *gin.Context, principal string, res primitive.M) {
+	id := res["_id"].(primitive.ObjectID)
+
+	account, _ := res["email"].(string)
+	if account == "" {
+		account, _ = res["cse_id"].(string)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		respondError(ctx, http.StatusInternalServerError, "Failed to generate secret")
+		return
+	}
+
+	if err := ga.DB.SetPendingTOTPSecret(principal, id, secret); err != nil {
+		respondError(ctx, http.StatusInternalServerError, "Failed to start enrollment")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":          "Scan the provisioning URI with an authenticator app and confirm with a code",
+		"secret":           secret,
+		"provisioning_uri": totp.ProvisioningURI(secret, totpIssuer, account),
+	})
+}
+
+// finishEnrollment activates the pending secret once code proves the authenticator app has it, and
+// returns the recovery codes. They are only ever shown here; the database keeps their hashes.
+func (ga *GoApp) finishEnrollment(principal string, res primitive.M, code string) ([]string, bool, error) {
+	id := res["_id"].(primitive.ObjectID)
+
+	secret, _ := res["totp_pending_secret"].(string)
+	if secret == "" {
+		return nil, false, nil
+	}
+
+	step, ok := totp.Validate(code, secret, time.Now(), 0)
+	if !ok {
+		return nil, false, nil
+	}
+
+	codes, err := totp.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, false, err
+	}
+
+	hashed := make([]string, 0, len(codes))
+	for _, c := range codes {
+		h, err := encrypt.Hash(c)
+		if err != nil {
+			return nil, false, err
+		}
+		hashed = append(hashed, h)
+	}
+
+	enabled, err := ga.DB.EnableTOTP(principal, id, secret, hashed, step)
+	if err != nil || !enabled {
+		return nil, false, err
+	}
+
+	return codes, true, nil
+}
+
+// VerifySecondFactor is the second sign in step. It issues the tokens once the TOTP or recovery code
+// for the mfa token returned by the first step is correct.
+func (ga *GoApp) VerifySecondFactor(principal string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var input struct {
+			MFAToken     string `json:"mfa_token" binding:"required"`
+			Code         string `json:"code"`
+			RecoveryCode string `json:"recovery_code"`
+		}
+
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			badRequest(ctx, err)
+			return
+		}
+
+		claims, res, ok := ga.resolveChallenge(ctx, principal, auth.PurposeMFALogin, input.MFAToken)
+		if !ok {
+			return
+		}
+
+		id := res["_id"].(primitive.ObjectID)
+
+		verified, err := ga.verifySecondFactor(principal, res, input.Code, input.RecoveryCode)
+		if err != nil {
+			respondError(ctx, http.StatusInternalServerError, "Failed to verify two factor code")
+			return
+		}
+
+		if !verified {
+			if err := ga.DB.RecordMFAFailure(principal, id, claims.ID); err != nil {
+				ga.App.ErrorLogger.Printf("Error recording mfa failure for %s: %v", id.Hex(), err)
+			}
+			RespondError(ctx, http.StatusUnauthorized, "invalid_mfa_code", "Invalid two factor code", nil)
+			return
+		}
+
+		completed, err := ga.DB.CompleteMFAChallenge(principal, id, claims.ID)
+		if err != nil || !completed {
+			RespondError(ctx, http.StatusUnauthorized, "mfa_challenge_expired", "Two factor challenge is invalid or has expired, please sign in again", nil)
+			return
+		}
+
+		ga.completeLogin(ctx, principal, res, nil)
+	}
+}
+
+// EnrollSecondFactorAtSignIn starts enrollment for an admin who has to set up two factor
+// authentication before the policy lets them sign in.
+func (ga *GoApp) EnrollSecondFactorAtSignIn(principal string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var input struct {
+			MFAToken string `json:"mfa_token" binding:"required"`
+		}
+
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			badRequest(ctx, err)
+			return
+		}
+
+		_, res, ok := ga.resolveChallenge(ctx, principal, auth.PurposeMFAEnroll, input.MFAToken)
+		if !ok {
+			return
+		}
+
+		ga.beginEnrollment(ctx, principal, res)
+	}
+}
+
+// ConfirmSecondFactorAtSignIn finishes the enrollment started by EnrollSecondFactorAtSignIn and signs
+// the admin in.
+func (ga *GoApp) ConfirmSecondFactorAtSignIn(principal string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var input struct {
+			MFAToken string `json:"mfa_token" binding:"required"`
+			Code     string `json:"code" binding:"required"`
+		}
+
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			badRequest(ctx, err)
+			return
+		}
+
+		claims, res, ok := ga.resolveChallenge(ctx, principal, auth.PurposeMFAEnroll, input.MFAToken)
+		if !ok {
+			return
+		}
+
+		id := res["_id"].(primitive.ObjectID)
+
+		codes, enabled, err := ga.finishEnrollment(principal, res, input.Code)
+		if err != nil {
+			respondError(ctx, http.StatusInternalServerError, "Failed to enable two factor authentication")
+			return
+		}
+
+		if !enabled {
+			if err := ga.DB.RecordMFAFailure(principal, id, claims.ID); err != nil {
+				ga.App.ErrorLogger.Printf("Error recording mfa failure for %s: %v", id.Hex(), err)
+			}
+			RespondError(ctx, http.StatusUnauthorized, "invalid_mfa_code", "Invalid two factor code", nil)
+			return
+		}
+
+		completed, err := ga.DB.CompleteMFAChallenge(principal, id, claims.ID)
+		if err != nil || !completed {
+			RespondError(ctx, http.StatusUnauthorized, "mfa_challenge_expired", "Two factor challenge is invalid or has expired, please sign in again", nil)
+			return
+		}
+
+		ga.completeLogin(ctx, principal, res, gin.H{"recovery_codes": codes})
+	}
+}
+
+// StartTOTPEnrollment begins two factor enrollment for the signed in admin or cse.
+func (ga *GoApp) StartTOTPEnrollment(principal string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		res, err := ga.DB.GetPrincipalByID(principal, ctx.MustGet("UID").(primitive.ObjectID))
+		if err != nil {
+			respondError(ctx, http.StatusInternalServerError, "Failed to fetch account")
+			return
+		}
+
+		if enabled, _ := res["totp_enabled"].(bool); enabled {
+			respondError(ctx, http.StatusConflict, "Two factor authentication is already enabled")
+			return
+		}
+
+		ga.beginEnrollment(ctx, principal, res)
+	}
+}
+
+// ConfirmTOTPEnrollment enables two factor authentication for the signed in admin or cse.
+func (ga *GoApp) ConfirmTOTPEnrollment(principal string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var input struct {
+			Code string `json:"code" binding:"required"`
+		}
+
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			badRequest(ctx, err)
+			return
+		}
+
+		res, err := ga.DB.GetPrincipalByID(principal, ctx.MustGet("UID").(primitive.ObjectID))
+		if err != nil {
+			respondError(ctx, http.StatusInternalServerError, "Failed to fetch account")
+			return
+		}
+
+		codes, enabled, err := ga.finishEnrollment(principal, res, input.Code)
+		if err != nil {
+			respondError(ctx, http.StatusInternalServerError, "Failed to enable two factor authentication")
+			return
+		}
+
+		if !enabled {
+			RespondError(ctx, http.StatusBadRequest, "invalid_mfa_code", "Invalid two factor code", nil)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"message":        "Two factor authentication enabled, store the recovery codes somewhere safe",
+			"recovery_codes": codes,
+		})
+	}
+}
+
+// DisableTOTP turns two factor authentication off after checking a current code. Admins cannot turn
+// it off while the policy requires it.
+func (ga *GoApp) DisableTOTP(principal string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var input struct {
+			Code         string `json:"code"`
+			RecoveryCode string `json:"recovery_code"`
+		}
+
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			badRequest(ctx, err)
+			return
+		}
+
+		if principal == auth.AdminPrincipal {
+			settings, err := ga.DB.GetSecuritySettings()
+			if err != nil {
+				respondError(ctx, http.StatusInternalServerError, "Failed to check security policy")
+				return
+			}
+
+			if settings.RequireAdmin2FA {
+				RespondError(ctx, http.StatusForbidden, "mfa_required_by_policy", "Two factor authentication is required for admins", nil)
+				return
+			}
+		}
+
+		id := ctx.MustGet("UID").(primitive.ObjectID)
+
+		res, err := ga.DB.GetPrincipalByID(principal, id)
+		if err != nil {
+			respondError(ctx, http.StatusInternalServerError, "Failed to fetch account")
+			return
+		}
+
+		if enabled, _ := res["totp_enabled"].(bool); !enabled {
+			respondError(ctx, http.StatusBadRequest, "Two factor authentication is not enabled")
+			return
+		}
+
+		verified, err := ga.verifySecondFactor(principal, res, input.Code, input.RecoveryCode)
+		if err != nil {
+			respondError(ctx, http.StatusInternalServerError, "Failed to verify two factor code")
+			return
+		}
+
+		if !verified {
+			RespondError(ctx, http.StatusUnauthorized, "invalid_mfa_code", "Invalid two factor code", nil)
+			return
+		}
+
+		if err := ga.DB.DisableTOTP(principal, id); err != nil {
+			respondError(ctx, http.StatusInternalServerError, "Failed to disable two factor authentication")
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "Two factor authentication disabled"})
+	}
+}
+
+func (ga *GoApp) GetSecurityPolicy() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		settings, err := ga.DB.GetSecuritySettings()
+		if err != nil {
+			respondError(ctx, http.StatusInternalServerError, "Failed to fetch security policy")
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "Security policy fetched successfully", "data": settings})
+	}
+}
+
+// UpdateSecurityPolicy changes the security policies. Requiring 2FA for admins takes effect at their
+// next sign in; admins without it are then walked through enrollment before they get a token.
+func (ga *GoApp) UpdateSecurityPolicy() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var input struct {
+			RequireAdmin2FA *bool `json:"require_admin_2fa" binding:"required"`
+		}
+
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			badRequest(ctx, err)
+			return
+		}
+
+		settings := &model.SecuritySettings{
+			RequireAdmin2FA: *input.RequireAdmin2FA,
+			UpdatedBy:       ctx.MustGet("UID").(primitive.ObjectID),
+			UpdatedAt:       time.Now(),
+		}
+
+		if err := ga.DB.UpdateSecuritySettings(settings); err != nil {
+			respondError(ctx, http.StatusInternalServerError, "Failed to update security policy")
+			return
+		}
+
+		ga.App.InfoLogger.Printf("Admin %s set require_admin_2fa to %v", settings.UpdatedBy.Hex(), settings.RequireAdmin2FA)
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "Security policy updated successfully", "data": settings})
+	}
+}
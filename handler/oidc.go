@@ -0,0 +1,354 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/auth"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/domain"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/dto"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/encrypt"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/oidc"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// oidcLoginLifetime is how long a customer has to finish signing in at the provider.
+const oidcLoginLifetime = 10 * time.Minute
+
+// oidcStateCookie binds a sign in to the browser that started it, so a provider response
+// cannot be replayed into another browser.
+const oidcStateCookie = "oidc_state"
+
+func (ga *GoApp) oidcProvider(ctx *gin.Context) (*oidc.Provider, bool) {
+	provider, ok := ga.App.OIDC[ctx.Param("provider")]
+	if !ok {
+		RespondError(ctx, http.StatusNotFound, "unknown_provider", "sign in provider is not configured", nil)
+		return nil, false
+	}
+	return provider, true
+}
+
+func (ga *GoApp) setOIDCStateCookie(ctx *gin.Context, value string, maxAge int) {
+	// Lax at most, the cookie has to come along on the provider's redirect back to us.
+	sameSite := ga.App.Cookies.SameSite
+	if sameSite == http.SameSiteStrictMode {
+		sameSite = http.SameSiteLaxMode
+	}
+
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    value,
+		Path:     "/auth/oidc",
+		Domain:   ga.App.Cookies.Domain,
+		MaxAge:   maxAge,
+		Secure:   ga.App.Cookies.Secure,
+		HttpOnly: true,
+		SameSite: sameSite,
+	})
+}
+
+// OIDCLogin starts signing a customer in at an OpenID Connect provider by redirecting to it
+// with a fresh state, nonce and PKCE challenge.
+func (ga *GoApp) OIDCLogin() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+
+		provider, ok := ga.oidcProvider(ctx)
+		if !ok {
+			return
+		}
+
+		var secrets [3]string
+		for i := range secrets {
+			token, err := oidc.RandomToken()
+			if err != nil {
+				internalError(ctx, err, "Failed to start sign in")
+				return
+			}
+			secrets[i] = token
+		}
+		state, nonce, verifier := secrets[0], secrets[1], secrets[2]
+
+		redirect, err := provider.AuthCodeURL(ctx.Request.Context(), state, nonce, verifier)
+		if err != nil {
+			ga.App.ErrorLogger.Printf("Error contacting OIDC provider %s: %v", provider.Name, err)
+			RespondError(ctx, http.StatusBadGateway, "oidc_provider_error", "sign in provider is unavailable", nil)
+			return
+		}
+
+		err = ga.DB.CreateOIDCLogin(&model.OIDCLogin{
+			ID:        hashToken(state),
+			Provider:  provider.Name,
+			Nonce:     nonce,
+			Verifier:  verifier,
+			ExpiresAt: time.Now().Add(oidcLoginLifetime),
+		})
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+
+		ga.setOIDCStateCookie(ctx, state, int(oidcLoginLifetime.Seconds()))
+
+		ctx.Redirect(http.StatusFound, redirect)
+	}
+}
+
+// OIDCCallback finishes a sign in at an OpenID Connect provider. The customer is found by the
+// linked provider account, or else by the provider verified email, which links the account;
+// customers with neither get a new account.
+func (ga *GoApp) OIDCCallback() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+
+		provider, ok := ga.oidcProvider(ctx)
+		if !ok {
+			return
+		}
+
+		if reason := ctx.Query("error"); reason != "" {
+			ga.setOIDCStateCookie(ctx, "", -1)
+			RespondError(ctx, http.StatusUnauthorized, "oidc_denied", "sign in was not completed at the provider", gin.H{"error": reason})
+			return
+		}
+
+		state := ctx.Query("state")
+		code := ctx.Query("code")
+
+		cookie, err := ctx.Cookie(oidcStateCookie)
+		ga.setOIDCStateCookie(ctx, "", -1)
+
+		if err != nil || state == "" || code == "" || subtle.ConstantTimeCompare([]byte(cookie), []byte(state)) != 1 {
+			RespondError(ctx, http.StatusBadRequest, "invalid_oidc_state", "sign in could not be matched to this browser, please start again", nil)
+			return
+		}
+
+		login, err := ga.DB.ConsumeOIDCLogin(hashToken(state))
+		if err != nil {
+			if !errors.Is(err, domain.ErrNotFound) {
+				abortWithError(ctx, err)
+				return
+			}
+			RespondError(ctx, http.StatusBadRequest, "invalid_oidc_state", "sign in has expired, please start again", nil)
+			return
+		}
+
+		if login.Provider != provider.Name || time.Now().After(login.ExpiresAt) {
+			RespondError(ctx, http.StatusBadRequest, "invalid_oidc_state", "sign in has expired, please start again", nil)
+			return
+		}
+
+		token, err := provider.Exchange(ctx.Request.Context(), code, login.Verifier)
+		if err != nil {
+			ga.App.ErrorLogger.Printf("Error exchanging OIDC code with %s: %v", provider.Name, err)
+			RespondError(ctx, http.StatusBadGateway, "oidc_provider_error", "sign in provider rejected the sign in", nil)
+			return
+		}
+
+		claims, err := provider.Verify(ctx.Request.Context(), token.IDToken, login.Nonce)
+		if err != nil {
+			ga.App.ErrorLogger.Printf("Rejected ID token from %s: %v", provider.Name, err)
+			RespondError(ctx, http.StatusUnauthorized, "invalid_id_token", "sign in provider sent an invalid identity", nil)
+			return
+		}
+
+		email := dto.NormalizeEmail(claims.Email)
+		if email == "" || !bool(claims.EmailVerified) {
+			RespondError(ctx, http.StatusForbidden, "email_not_verified", "the provider has not verified the email of this account", nil)
+			return
+		}
+
+		identity := &model.Identity{
+			Provider: provider.Name,
+			Subject:  claims.Subject,
+			Email:    email,
+			LinkedAt: time.Now(),
+		}
+
+		res, outcome, err := ga.userForIdentity(identity, claims.Name)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+
+		ga.securityEvent(&model.SecurityEvent{
+			Type:       "oidc_" + outcome,
+			Principal:  auth.UserPrincipal,
+			Identifier: email,
+			IP:         ctx.ClientIP(),
+			Details:    map[string]interface{}{"provider": provider.Name},
+		})
+
+		ga.completeUserLogin(ctx, res, gin.H{"provider": provider.Name, "account": outcome})
+	}
+}
+
+// userForIdentity returns the user an OIDC account belongs to and whether it was "signed_in"
+// with an already linked account, "linked" to the user with the same email or "created".
+func (ga *GoApp) userForIdentity(identity *model.Identity, name string) (primitive.M, string, error) {
+	res, err := ga.DB.FindUserByIdentity(identity.Provider, identity.Subject)
+	if err == nil {
+		return res, "signed_in", nil
+	}
+	if !errors.Is(err, domain.ErrNotFound) {
+		return nil, "", err
+	}
+
+	res, err = ga.DB.FindPrincipalByEmail(auth.UserPrincipal, identity.Email)
+	switch {
+	case err == nil:
+		return ga.linkIdentity(res, identity)
+	case !errors.Is(err, domain.ErrNotFound):
+		return nil, "", err
+	}
+
+	// The password is never told to anyone; the customer can set one through password reset.
+	password, err := oidc.RandomToken()
+	if err != nil {
+		return nil, "", err
+	}
+	hashed, err := encrypt.Hash(password)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if name == "" {
+		name = strings.SplitN(identity.Email, "@", 2)[0]
+	}
+
+	now := time.Now()
+
+	user := &model.User{
+		Name:       name,
+		Email:      identity.Email,
+		Password:   hashed,
+		Verified:   true,
+		Addresses:  []model.Address{},
+		Cart:       []model.CartItems{},
+		Orders:     []primitive.ObjectID{},
+		Payments:   []primitive.ObjectID{},
+		Shipments:  []primitive.ObjectID{},
+		Wishlist:   []primitive.ObjectID{},
+		Identities: []model.Identity{*identity},
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	_, status, err := ga.DB.InsertUser(user)
+	if err != nil {
+		return nil, "", err
+	}
+
+	res, err = ga.DB.FindPrincipalByEmail(auth.UserPrincipal, identity.Email)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if status == 2 {
+		// Registered with this email between our lookup and the insert.
+		return ga.linkIdentity(res, identity)
+	}
+
+	return res, "created", nil
+}
+
+// linkIdentity links an OIDC account to the user with its verified email. A user who never
+// proved owning the email may have been registered by someone else, so its password is
+// replaced and its sessions are ended before the provider account takes it over.
+func (ga *GoApp) linkIdentity(res primitive.M, identity *model.Identity) (primitive.M, string, error) {
+	id := res["_id"].(primitive.ObjectID)
+
+	linked, err := ga.DB.LinkIdentity(id, identity)
+	if err != nil {
+		return nil, "", err
+	}
+	if !linked {
+		return nil, "", domain.Conflict("another " + identity.Provider + " account is already linked to this email")
+	}
+
+	if verified, _ := res["verified"].(bool); !verified {
+		password, err := oidc.RandomToken()
+		if err != nil {
+			return nil, "", err
+		}
+		hashed, err := encrypt.Hash(password)
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err := ga.DB.ResetPassword(auth.UserPrincipal, id, hashed); err != nil {
+			return nil, "", err
+		}
+		if err := auth.RevokeAll(id); err != nil {
+			ga.App.ErrorLogger.Printf("Error revoking sessions of %s before linking: %v", id.Hex(), err)
+		}
+		ga.revokeSessions(auth.UserPrincipal, id)
+
+		if _, err := ga.DB.MarkEmailVerified(id, identity.Email); err != nil {
+			return nil, "", err
+		}
+	}
+
+	return res, "linked", nil
+}
+
+// completeUserLogin signs in a user whose identity has been established and answers with its tokens.
+func (ga *GoApp) completeUserLogin(ctx *gin.Context, res primitive.M, extra gin.H) {
+	id := res["_id"].(primitive.ObjectID)
+	email, _ := res["email"].(string)
+
+	cookieData := sessions.Default(ctx)
+
+	cookieData.Set("userInfo", map[string]interface{}{
+		"ID":    id,
+		"Email": email,
+		"Name":  res["name"],
+	})
+
+	if err := cookieData.Save(); err != nil {
+		internalError(ctx, err, "error while saving cookie")
+		return
+	}
+
+	subject, err := ga.subjectFor(auth.UserPrincipal, res)
+	if err != nil {
+		ga.App.ErrorLogger.Println(err)
+		respondError(ctx, http.StatusInternalServerError, "error while resolving role")
+		return
+	}
+
+	family, t1, t2, err := ga.startSession(ctx, subject)
+	if err != nil {
+		internalError(ctx, err, "error while generating tokens")
+		return
+	}
+
+	tk := map[string]string{
+		"token":    t1,
+		"newToken": t2,
+		"family":   family,
+	}
+
+	if _, err := ga.DB.UpdateUser(id, tk); err != nil {
+		internalError(ctx, err, "error while updating tokens")
+		return
+	}
+
+	response := gin.H{
+		"message":       "Successfully Logged in",
+		"email":         email,
+		"id":            id,
+		"name":          res["name"],
+		"session_token": t1,
+		"refresh_token": t2,
+	}
+
+	for k, v := range extra {
+		response[k] = v
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,482 @@
+package handler
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/json"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/auth"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/config"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/database"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/domain"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/oidc"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const testClientID = "carsgo-test"
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	gob.Register(map[string]interface{}{})
+	gob.Register(primitive.NewObjectID())
+
+	if err := auth.LoadKeyring("", ""); err != nil {
+		log.Fatal(err)
+	}
+
+	m.Run()
+}
+
+// mockProvider is an OpenID Connect provider serving discovery, its JWKS and a token endpoint
+// that answers every code with the ID token built by idToken.
+type mockProvider struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mu      sync.Mutex
+	idToken func(nonce string) string
+	nonce   string
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &mockProvider{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, oidc.Metadata{
+			Issuer:                        p.URL,
+			AuthorizationEndpoint:         p.URL + "/authorize",
+			TokenEndpoint:                 p.URL + "/token",
+			JWKSURI:                       p.URL + "/jwks",
+			CodeChallengeMethodsSupported: []string{"S256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("code") == "" || r.PostForm.Get("code_verifier") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			writeJSON(w, map[string]string{"error": "invalid_request"})
+			return
+		}
+
+		p.mu.Lock()
+		token := p.idToken(p.nonce)
+		p.mu.Unlock()
+
+		writeJSON(w, oidc.Token{AccessToken: "access", TokenType: "Bearer", IDToken: token, ExpiresIn: 3600})
+	})
+
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+
+	return p
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// claims returns the claims of a valid ID token for subject, for tests to spoil as they need.
+func (p *mockProvider) claims(subject string, email string, nonce string) *oidc.IDClaims {
+	now := time.Now()
+	return &oidc.IDClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    p.URL,
+			Subject:   subject,
+			Audience:  jwt.ClaimStrings{testClientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		},
+		Nonce:         nonce,
+		Email:         email,
+		EmailVerified: true,
+		Name:          "Test Customer",
+	}
+}
+
+func signIDToken(t *testing.T, key *rsa.PrivateKey, claims jwt.Claims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test"
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// oidcTestDB keeps the users and sign ins of a test in memory. Methods the flow does not use
+// are left to the embedded nil DBRepo and panic when called.
+type oidcTestDB struct {
+	database.DBRepo
+
+	mu     sync.Mutex
+	logins map[string]model.OIDCLogin
+	users  []primitive.M
+	linked []model.Identity
+}
+
+func newOIDCTestDB() *oidcTestDB {
+	return &oidcTestDB{logins: map[string]model.OIDCLogin{}}
+}
+
+func (db *oidcTestDB) CreateOIDCLogin(login *model.OIDCLogin) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.logins[login.ID] = *login
+	return nil
+}
+
+func (db *oidcTestDB) ConsumeOIDCLogin(id string) (model.OIDCLogin, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	login, ok := db.logins[id]
+	if !ok {
+		return login, domain.NotFound("sign in")
+	}
+	delete(db.logins, id)
+	return login, nil
+}
+
+func (db *oidcTestDB) FindUserByIdentity(provider string, subject string) (primitive.M, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for _, user := range db.users {
+		identities, _ := user["identities"].([]model.Identity)
+		for _, identity := range identities {
+			if identity.Provider == provider && identity.Subject == subject {
+				return user, nil
+			}
+		}
+	}
+	return nil, domain.NotFound("user")
+}
+
+func (db *oidcTestDB) FindPrincipalByEmail(principal string, email string) (primitive.M, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for _, user := range db.users {
+		if user["email"] == email {
+			return user, nil
+		}
+	}
+	return nil, domain.NotFound(principal)
+}
+
+func (db *oidcTestDB) LinkIdentity(userID primitive.ObjectID, identity *model.Identity) (bool, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for _, user := range db.users {
+		if user["_id"] == userID {
+			identities, _ := user["identities"].([]model.Identity)
+			user["identities"] = append(identities, *identity)
+			db.linked = append(db.linked, *identity)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (db *oidcTestDB) InsertUser(user *model.User) (bool, int, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.users = append(db.users, primitive.M{
+		"_id":        primitive.NewObjectID(),
+		"name":       user.Name,
+		"email":      user.Email,
+		"verified":   user.Verified,
+		"identities": user.Identities,
+	})
+	return true, 1, nil
+}
+
+func (db *oidcTestDB) UpdateUser(userID primitive.ObjectID, tk map[string]string) (bool, error) {
+	return true, nil
+}
+
+func (db *oidcTestDB) CreateLoginSession(session *model.LoginSession) error {
+	return nil
+}
+
+func (db *oidcTestDB) RecordSecurityEvent(event *model.SecurityEvent) error {
+	return nil
+}
+
+// oidcTestApp serves the OIDC routes of an app signing in with provider as "mock".
+func oidcTestApp(provider *mockProvider, db *oidcTestDB) *gin.Engine {
+	quiet := log.New(io.Discard, "", 0)
+
+	ga := &GoApp{
+		App: &config.GoAppTools{
+			ErrorLogger: quiet,
+			InfoLogger:  quiet,
+			Cookies:     config.CookieConfig{SameSite: http.SameSiteLaxMode},
+			OIDC: map[string]*oidc.Provider{
+				"mock": oidc.New(oidc.Config{
+					Name:        "mock",
+					Issuer:      provider.URL,
+					ClientID:    testClientID,
+					RedirectURL: "http://localhost/auth/oidc/mock/callback",
+				}, provider.Client()),
+			},
+		},
+		DB: db,
+	}
+
+	router := gin.New()
+	router.Use(sessions.Sessions("user_session", cookie.NewStore([]byte("oidc-test-session-key-0123456789"))))
+	router.GET("/auth/oidc/:provider/login", ga.OIDCLogin())
+	router.GET("/auth/oidc/:provider/callback", ga.OIDCCallback())
+	return router
+}
+
+// startLogin begins a sign in and returns its state and the cookie binding it to the browser.
+// The nonce sent to the provider is recorded on it for the ID token.
+func startLogin(t *testing.T, router *gin.Engine, provider *mockProvider) (string, *http.Cookie) {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/oidc/mock/login", nil))
+
+	if w.Code != http.StatusFound {
+		t.Fatalf("login: got status %d, want %d: %s", w.Code, http.StatusFound, w.Body)
+	}
+
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := location.Query()
+	if q.Get("client_id") != testClientID || q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		t.Fatalf("login: unexpected authorization request %s", location)
+	}
+
+	var state *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == oidcStateCookie {
+			state = c
+		}
+	}
+	if state == nil || state.Value != q.Get("state") {
+		t.Fatalf("login: state cookie does not carry the state sent to the provider")
+	}
+
+	provider.mu.Lock()
+	provider.nonce = q.Get("nonce")
+	provider.mu.Unlock()
+
+	return q.Get("state"), state
+}
+
+func callback(router *gin.Engine, state string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/auth/oidc/mock/callback?"+url.Values{
+		"state": {state},
+		"code":  {"authorization-code"},
+	}.Encode(), nil)
+	req.AddCookie(cookie)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func decodeBody(t *testing.T, w *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("cannot decode response %q: %v", w.Body, err)
+	}
+	return body
+}
+
+func expectError(t *testing.T, w *httptest.ResponseRecorder, status int, code string) {
+	t.Helper()
+
+	if w.Code != status {
+		t.Fatalf("got status %d, want %d: %s", w.Code, status, w.Body)
+	}
+	if got := decodeBody(t, w)["code"]; got != code {
+		t.Fatalf("got error code %v, want %s", got, code)
+	}
+}
+
+func TestOIDCLoginCreatesAccount(t *testing.T) {
+	provider := newMockProvider(t)
+	db := newOIDCTestDB()
+	router := oidcTestApp(provider, db)
+
+	provider.idToken = func(nonce string) string {
+		return signIDToken(t, provider.key, provider.claims("subject-1", "new@example.com", nonce))
+	}
+
+	state, cookie := startLogin(t, router, provider)
+	w := callback(router, state, cookie)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	body := decodeBody(t, w)
+	if body["account"] != "created" || body["email"] != "new@example.com" || body["session_token"] == "" {
+		t.Fatalf("unexpected response %v", body)
+	}
+	if len(db.users) != 1 {
+		t.Fatalf("got %d users, want 1", len(db.users))
+	}
+
+	// The provider account signs in to the same user from now on.
+	state, cookie = startLogin(t, router, provider)
+	w = callback(router, state, cookie)
+
+	if w.Code != http.StatusOK || decodeBody(t, w)["account"] != "signed_in" {
+		t.Fatalf("second sign in: got status %d: %s", w.Code, w.Body)
+	}
+	if len(db.users) != 1 {
+		t.Fatalf("second sign in created another user")
+	}
+}
+
+func TestOIDCLoginRejectsBadSignature(t *testing.T) {
+	provider := newMockProvider(t)
+	db := newOIDCTestDB()
+	router := oidcTestApp(provider, db)
+
+	forger, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider.idToken = func(nonce string) string {
+		return signIDToken(t, forger, provider.claims("subject-1", "new@example.com", nonce))
+	}
+
+	state, cookie := startLogin(t, router, provider)
+	expectError(t, callback(router, state, cookie), http.StatusUnauthorized, "invalid_id_token")
+
+	if len(db.users) != 0 {
+		t.Fatalf("a forged token created a user")
+	}
+}
+
+func TestOIDCLoginRejectsWrongAudience(t *testing.T) {
+	provider := newMockProvider(t)
+	db := newOIDCTestDB()
+	router := oidcTestApp(provider, db)
+
+	provider.idToken = func(nonce string) string {
+		claims := provider.claims("subject-1", "new@example.com", nonce)
+		claims.Audience = jwt.ClaimStrings{"another-client"}
+		return signIDToken(t, provider.key, claims)
+	}
+
+	state, cookie := startLogin(t, router, provider)
+	expectError(t, callback(router, state, cookie), http.StatusUnauthorized, "invalid_id_token")
+
+	if len(db.users) != 0 {
+		t.Fatalf("a token for another client created a user")
+	}
+}
+
+func TestOIDCLoginRejectsStateMismatch(t *testing.T) {
+	provider := newMockProvider(t)
+	db := newOIDCTestDB()
+	router := oidcTestApp(provider, db)
+
+	provider.idToken = func(nonce string) string {
+		return signIDToken(t, provider.key, provider.claims("subject-1", "new@example.com", nonce))
+	}
+
+	_, cookie := startLogin(t, router, provider)
+	other, _ := startLogin(t, router, provider)
+
+	// A provider response started in another browser.
+	expectError(t, callback(router, other, cookie), http.StatusBadRequest, "invalid_oidc_state")
+
+	if len(db.users) != 0 {
+		t.Fatalf("a mismatched state created a user")
+	}
+}
+
+func TestOIDCLoginRejectsNonceMismatch(t *testing.T) {
+	provider := newMockProvider(t)
+	db := newOIDCTestDB()
+	router := oidcTestApp(provider, db)
+
+	provider.idToken = func(nonce string) string {
+		return signIDToken(t, provider.key, provider.claims("subject-1", "new@example.com", "replayed-nonce"))
+	}
+
+	state, cookie := startLogin(t, router, provider)
+	expectError(t, callback(router, state, cookie), http.StatusUnauthorized, "invalid_id_token")
+
+	if len(db.users) != 0 {
+		t.Fatalf("a replayed token created a user")
+	}
+}
+
+func TestOIDCLoginLinksExistingAccount(t *testing.T) {
+	provider := newMockProvider(t)
+	db := newOIDCTestDB()
+	router := oidcTestApp(provider, db)
+
+	id := primitive.NewObjectID()
+	db.users = append(db.users, primitive.M{
+		"_id":      id,
+		"name":     "Existing Customer",
+		"email":    "existing@example.com",
+		"verified": true,
+	})
+
+	provider.idToken = func(nonce string) string {
+		return signIDToken(t, provider.key, provider.claims("subject-2", "existing@example.com", nonce))
+	}
+
+	state, cookie := startLogin(t, router, provider)
+	w := callback(router, state, cookie)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	body := decodeBody(t, w)
+	if body["account"] != "linked" || body["id"] != id.Hex() {
+		t.Fatalf("unexpected response %v", body)
+	}
+	if len(db.users) != 1 {
+		t.Fatalf("linking created another user")
+	}
+	if len(db.linked) != 1 || db.linked[0].Provider != "mock" || db.linked[0].Subject != "subject-2" {
+		t.Fatalf("got linked identities %v", db.linked)
+	}
+}
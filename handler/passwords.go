@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/auth"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/dto"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/encrypt"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	passwordResetLifetime    = 15 * time.Minute
+	passwordResetMaxAttempts = 5
+)
+
+// newResetCode returns a random six digit code.
+func newResetCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// weakPassword answers 422 listing the password policy rules password breaks and reports whether
+// it did. personal holds the account's email and name, which the password must not contain.
+func weakPassword(ctx *gin.Context, password string, personal ...string) bool {
+	violations, err := encrypt.CheckPassword(password, personal...)
+	if err != nil {
+		internalError(ctx, err, "Failed to check password")
+		return true
+	}
+
+	if len(violations) == 0 {
+		return false
+	}
+
+	fields := make([]FieldError, 0, len(violations))
+	for _, v := range violations {
+		fields = append(fields, FieldError{Field: "password", Rule: v.Rule, Message: v.Message})
+	}
+
+	RespondError(ctx, http.StatusUnprocessableEntity, "validation_failed", "Some fields are invalid", fields)
+	return true
+}
+
+// rehashPassword upgrades the stored hash of a password that was just verified when it was made
+// with a lower bcrypt cost than is configured now.
+func (ga *GoApp) rehashPassword(principal string, id primitive.ObjectID, password string, hash string) {
+	if !encrypt.NeedsRehash(hash) {
+		return
+	}
+
+	rehashed, err := encrypt.Hash(password)
+	if err != nil {
+		ga.App.ErrorLogger.Printf("Error rehashing password of %s %s: %v", principal, id.Hex(), err)
+		return
+	}
+
+	if _, err := ga.DB.UpdatePasswordHash(principal, id, hash, rehashed); err != nil {
+		ga.App.ErrorLogger.Printf("Error storing rehashed password of %s %s: %v", principal, id.Hex(), err)
+	}
+}
+
+// RequestPasswordReset mails a one time reset code to a user or admin. It answers the same way whether
+// or not the account exists, so it cannot be used to find out which emails are registered.
+func (ga *GoApp) RequestPasswordReset(principal string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var input struct {
+			Email string `json:"email" binding:"required,email"`
+		}
+
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			badRequest(ctx, err)
+			return
+		}
+
+		email := dto.NormalizeEmail(input.Email)
+		accepted := gin.H{"message": "If the account exists, a reset code has been sent to its email"}
+
+		account, err := ga.DB.FindPrincipalByEmail(principal, email)
+		if err != nil {
+			ga.App.InfoLogger.Printf("Password reset requested for unknown %s %s", principal, email)
+			ctx.JSON(http.StatusOK, accepted)
+			return
+		}
+
+		code, err := newResetCode()
+		if err != nil {
+			respondError(ctx, http.StatusInternalServerError, "Failed to create reset code")
+			return
+		}
+
+		hashed, err := encrypt.Hash(code)
+		if err != nil {
+			respondError(ctx, http.StatusInternalServerError, "Failed to create reset code")
+			return
+		}
+
+		reset := &model.PasswordReset{
+			Principal: principal,
+			AccountID: account["_id"].(primitive.ObjectID),
+			Email:     email,
+			CodeHash:  hashed,
+			ExpiresAt: time.Now().Add(passwordResetLifetime),
+			CreatedAt: time.Now(),
+		}
+
+		if err := ga.DB.CreatePasswordReset(reset); err != nil {
+			respondError(ctx, http.StatusInternalServerError, "Failed to create reset code")
+			return
+		}
+
+		body := fmt.Sprintf("Your CarsGo password reset code is %s.\n\nIt expires in %d minutes and can only be used once. "+
+			"If you did not ask to reset your password, you can ignore this email.", code, int(passwordResetLifetime.Minutes()))
+
+		if err := ga.App.Mailer.Send(email, "Reset your CarsGo password", body); err != nil {
+			ga.App.ErrorLogger.Printf("Error sending password reset email to %s: %v", email, err)
+		}
+
+		ctx.JSON(http.StatusOK, accepted)
+	}
+}
+
+// ConfirmPasswordReset sets a new password for the holder of a valid reset code and signs the account
+// out everywhere.
+func (ga *GoApp) ConfirmPasswordReset(principal string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var input struct {
+			Email    string `json:"email" binding:"required,email"`
+			Code     string `json:"code" binding:"required"`
+			Password string `json:"password" binding:"required"`
+		}
+
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			badRequest(ctx, err)
+			return
+		}
+
+		reset, err := ga.DB.GetPasswordReset(principal, dto.NormalizeEmail(input.Email))
+		if err != nil || reset.Attempts >= passwordResetMaxAttempts {
+			RespondError(ctx, http.StatusBadRequest, "invalid_reset_code", "Reset code is invalid or has expired", nil)
+			return
+		}
+
+		if ok, _ := encrypt.VerifyPassword(strings.TrimSpace(input.Code), reset.CodeHash); !ok {
+			if err := ga.DB.RecordPasswordResetAttempt(reset.ID); err != nil {
+				respondError(ctx, http.StatusInternalServerError, "Failed to verify reset code")
+				return
+			}
+			RespondError(ctx, http.StatusBadRequest, "invalid_reset_code", "Reset code is invalid or has expired", nil)
+			return
+		}
+
+		if weakPassword(ctx, input.Password, input.Email) {
+			return
+		}
+
+		hashed, err := encrypt.Hash(input.Password)
+		if err != nil {
+			internalError(ctx, err, "Failed to reset password")
+			return
+		}
+
+		consumed, err := ga.DB.ConsumePasswordReset(reset.ID)
+		if err != nil {
+			respondError(ctx, http.StatusInternalServerError, "Failed to reset password")
+			return
+		}
+
+		if !consumed {
+			RespondError(ctx, http.StatusBadRequest, "invalid_reset_code", "Reset code is invalid or has expired", nil)
+			return
+		}
+
+		updated, err := ga.DB.ResetPassword(principal, reset.AccountID, hashed)
+		if err != nil || !updated {
+			respondError(ctx, http.StatusInternalServerError, "Failed to reset password")
+			return
+		}
+
+		if err := auth.RevokeAll(reset.AccountID); err != nil {
+			ga.App.ErrorLogger.Printf("Error revoking sessions after password reset for %s: %v", reset.AccountID.Hex(), err)
+		}
+
+		ga.revokeSessions(principal, reset.AccountID)
+
+		ga.App.InfoLogger.Printf("Password reset for %s %s", principal, reset.AccountID.Hex())
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "Password reset successfully, please sign in again"})
+	}
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/domain"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"github.com/gin-gonic/gin"
+)
+
+// checkOrderPayment makes sure the payment an order names pays for it: it must be one the
+// customer made, not used for another order yet, and of the amount the order was priced at. It
+// answers the request when not.
+func (ga *GoApp) checkOrderPayment(ctx *gin.Context, order *model.Order) bool {
+	invalid := func(rule string, message string) bool {
+		RespondError(ctx, http.StatusUnprocessableEntity, "validation_failed", "Some fields are invalid", []FieldError{
+			{Field: "transaction_id", Rule: rule, Message: message},
+		})
+		return false
+	}
+
+	payment, err := ga.DB.GetPayment(order.TransactionID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return invalid("exists", "must be an existing payment")
+	}
+	if err != nil {
+		abortWithError(ctx, err)
+		return false
+	}
+
+	if payment.PaidBy != order.CustomerID {
+		return invalid("exists", "must be a payment of the customer")
+	}
+	if !payment.OrderID.IsZero() {
+		return invalid("unused", "must be a payment not used for another order")
+	}
+	if payment.Paid_Amount != order.OrderAmount {
+		return invalid("amount", fmt.Sprintf("must be a payment of %d, the order amount", order.OrderAmount))
+	}
+	return true
+}
+
+// checkPaidOrder makes sure a payment naming an order pays for it: the order must be one of the
+// payer's, and the payment of the amount the order was priced at. It answers the request when not.
+func (ga *GoApp) checkPaidOrder(ctx *gin.Context, payment *model.Payment) bool {
+	if payment.OrderID.IsZero() {
+		return true
+	}
+
+	invalid := func(field string, rule string, message string) bool {
+		RespondError(ctx, http.StatusUnprocessableEntity, "validation_failed", "Some fields are invalid", []FieldError{
+			{Field: field, Rule: rule, Message: message},
+		})
+		return false
+	}
+
+	order, err := ga.DB.GetOrder(payment.OrderID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return invalid("order_id", "exists", "must be an existing order")
+	}
+	if err != nil {
+		abortWithError(ctx, err)
+		return false
+	}
+
+	if order.CustomerID != payment.PaidBy {
+		return invalid("order_id", "exists", "must be an order of the customer")
+	}
+	if payment.Paid_Amount != order.OrderAmount {
+		return invalid("paid_amount", "amount", fmt.Sprintf("must be %d, the order amount", order.OrderAmount))
+	}
+	return true
+}
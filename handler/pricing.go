@@ -0,0 +1,217 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/dto"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// priceCheckInterval is how often sales are checked for having started or ended.
+const priceCheckInterval = time.Minute
+
+// defaultPriceHistoryLimit is how many price changes are listed unless asked otherwise.
+const defaultPriceHistoryLimit = 50
+
+// repriceProduct stores what product sells for at now when that changed, recording the change in
+// its price history, and reports whether it did.
+func (ga *GoApp) repriceProduct(product *model.Product, now time.Time) (bool, error) {
+	change, changed := ga.App.Pricing.Reprice(*product, now)
+	if !changed {
+		return false, nil
+	}
+	return ga.DB.SetProductPrice(product, &change)
+}
+
+// priceProduct stores what a product just added or edited sells for, logging what goes wrong.
+func (ga *GoApp) priceProduct(product *model.Product) {
+	if product.ID.IsZero() {
+		return
+	}
+	if _, err := ga.repriceProduct(product, time.Now()); err != nil {
+		ga.App.ErrorLogger.Printf("cannot price product %s : %v", product.ID.Hex(), err)
+	}
+}
+
+// RefreshPrices brings the price and sale badge stored on products in line with their sales,
+// recording each change in their price history.
+func (ga *GoApp) RefreshPrices() error {
+	products, err := ga.DB.GetProductsToReprice()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	repriced := 0
+	for i := range products {
+		changed, err := ga.repriceProduct(&products[i], now)
+		if err != nil {
+			ga.App.ErrorLogger.Printf("cannot reprice product %s : %v", products[i].ID.Hex(), err)
+			continue
+		}
+		if changed {
+			repriced++
+		}
+	}
+
+	if repriced > 0 {
+		ga.App.InfoLogger.Printf("Repriced %d products", repriced)
+	}
+	return nil
+}
+
+// StartPriceScheduler checks every minute for sales that started or ended, turning the sale
+// badges of their products on and off.
+func (ga *GoApp) StartPriceScheduler() {
+	ticker := time.NewTicker(priceCheckInterval)
+
+	go func() {
+		for range ticker.C {
+			if err := ga.RefreshPrices(); err != nil {
+				ga.App.ErrorLogger.Printf("Error in price scheduler: %v", err)
+			}
+		}
+	}()
+}
+
+// saleWindowValid makes sure the sale of product, if it ends, ends after it starts. It answers
+// the request when not. field names the request field the end came from.
+func saleWindowValid(ctx *gin.Context, field string, product *model.Product) bool {
+	if product.SaleEnds.IsZero() || product.SaleEnds.After(product.SaleStarts) {
+		return true
+	}
+	RespondError(ctx, http.StatusUnprocessableEntity, "validation_failed", "Some fields are invalid", []FieldError{
+		{Field: field, Rule: "gtfield", Message: "must be after sale_starts"},
+	})
+	return false
+}
+
+// uniqueIDs returns ids without repeats, in the order they first appear.
+func uniqueIDs(ids []primitive.ObjectID) []primitive.ObjectID {
+	seen := make(map[primitive.ObjectID]bool, len(ids))
+	unique := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// priceOrder sets the unit price of every item of order to what it sells for at now, and the
+// amount of the order to their total. It answers the request when an item names nothing that
+// can be bought.
+func (ga *GoApp) priceOrder(ctx *gin.Context, order *model.Order, now time.Time) bool {
+	items := order.OrderItems.OrderItems
+
+	ids := make([]primitive.ObjectID, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ProductID)
+	}
+	products, err := ga.DB.GetProducts(uniqueIDs(ids))
+	if err != nil {
+		abortWithError(ctx, err)
+		return false
+	}
+	byID := make(map[primitive.ObjectID]model.Product, len(products))
+	for _, p := range products {
+		byID[p.ID] = p
+	}
+
+	details := []FieldError{}
+	total := 0
+	for i := range items {
+		product, found := byID[items[i].ProductID]
+		if !found {
+			details = append(details, FieldError{
+				Field: fmt.Sprintf("order_items.order_items[%d].product_id", i), Rule: "exists", Message: "must be an existing product",
+			})
+			continue
+		}
+		if rule, message, ok := variantProblem(product, items[i].VariantID); !ok {
+			details = append(details, FieldError{
+				Field: fmt.Sprintf("order_items.order_items[%d].variant_id", i), Rule: rule, Message: message,
+			})
+			continue
+		}
+
+		price, _ := ga.App.Pricing.ItemPrice(product, items[i].VariantID, now)
+		items[i].UnitPrice = price.EffectivePrice
+		total += price.EffectivePrice * items[i].Quantity
+	}
+
+	if len(details) > 0 {
+		RespondError(ctx, http.StatusUnprocessableEntity, "validation_failed", "Some fields are invalid", details)
+		return false
+	}
+
+	order.OrderAmount = total
+	return true
+}
+
+// GetCart returns the cart of the user with what its items sell for now and their total.
+func (ga *GoApp) GetCart() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userID := ctx.MustGet("UID").(primitive.ObjectID)
+
+		items, err := ga.DB.GetCart(userID)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+
+		ids := make([]primitive.ObjectID, 0, len(items))
+		for _, item := range items {
+			ids = append(ids, item.ProductID)
+		}
+
+		products := []model.Product{}
+		if len(ids) > 0 {
+			products, err = ga.DB.GetProducts(uniqueIDs(ids))
+			if err != nil {
+				abortWithError(ctx, err)
+				return
+			}
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"data": dto.NewCartView(items, products, ga.App.Pricing, time.Now())})
+	}
+}
+
+// GetPriceHistory lists how the price of a product changed, newest first.
+func (ga *GoApp) GetPriceHistory() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		productID, err := primitive.ObjectIDFromHex(ctx.Param("productId"))
+		if err != nil {
+			respondError(ctx, http.StatusBadRequest, "Invalid product ID format")
+			return
+		}
+
+		var input dto.PriceHistoryRequest
+		if err := ctx.ShouldBindQuery(&input); err != nil {
+			badRequest(ctx, err)
+			return
+		}
+		if input.Limit == 0 {
+			input.Limit = defaultPriceHistoryLimit
+		}
+
+		if _, err := ga.DB.GetProduct(productID); err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+
+		changes, err := ga.DB.GetPriceHistory(productID, input.Limit)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"data": changes})
+	}
+}
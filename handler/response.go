@@ -0,0 +1,248 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/pricing"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/units"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// ErrorBody is the envelope of every error response.
+type ErrorBody struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Details   any    `json:"details,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// FieldError tells the client why one field of its request was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// RequestID returns the id the RequestID middleware gave the request.
+func RequestID(ctx *gin.Context) string {
+	return ctx.GetString("RequestID")
+}
+
+// RespondError aborts the request with the error envelope. Callers must return right after it.
+func RespondError(ctx *gin.Context, status int, code string, message string, details any) {
+	ctx.AbortWithStatusJSON(status, ErrorBody{
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		RequestID: RequestID(ctx),
+	})
+}
+
+// respondError is RespondError with the code derived from status.
+func respondError(ctx *gin.Context, status int, message string) {
+	RespondError(ctx, status, StatusCode(status), message, nil)
+}
+
+// internalError answers with 500 and message, keeping err for the request log.
+func internalError(ctx *gin.Context, err error, message string) {
+	if err != nil {
+		_ = ctx.Error(err)
+	}
+	RespondError(ctx, http.StatusInternalServerError, "internal", message, nil)
+}
+
+// badRequest answers a request whose body could not be bound or failed validation. Validation
+// failures list every rejected field, and so do fields the endpoint does not accept.
+func badRequest(ctx *gin.Context, err error) {
+	var invalid validator.ValidationErrors
+	if errors.As(err, &invalid) {
+		RespondError(ctx, http.StatusUnprocessableEntity, "validation_failed", "Some fields are invalid", FieldErrors(invalid))
+		return
+	}
+
+	if field, ok := unknownField(err); ok {
+		RespondError(ctx, http.StatusBadRequest, "unknown_field", fmt.Sprintf("Field %q is not accepted", field), []FieldError{
+			{Field: field, Rule: "unknown", Message: "is not allowed"},
+		})
+		return
+	}
+
+	var mistyped *json.UnmarshalTypeError
+	if errors.As(err, &mistyped) && mistyped.Field != "" {
+		RespondError(ctx, http.StatusBadRequest, "invalid_request", "Request body is malformed", []FieldError{
+			{Field: mistyped.Field, Rule: "type", Message: fmt.Sprintf("must be a %s", mistyped.Type)},
+		})
+		return
+	}
+
+	RespondError(ctx, http.StatusBadRequest, "invalid_request", "Request body is malformed", nil)
+}
+
+// unknownField returns the field named by the error encoding/json reports for fields a request
+// type does not have, which it only does as text.
+func unknownField(err error) (string, bool) {
+	rest, ok := strings.CutPrefix(err.Error(), "json: unknown field ")
+	if !ok {
+		return "", false
+	}
+	field, err := strconv.Unquote(rest)
+	if err != nil {
+		return "", false
+	}
+	return field, true
+}
+
+// bindJSONList decodes a JSON array body into list, rejecting unknown fields like ShouldBindJSON
+// does. Its items are left for the caller to validate one by one with badListItem, so that
+// failures can name the item they come from. It answers the request itself when it fails.
+func bindJSONList(ctx *gin.Context, list any) error {
+	if ctx.Request.Body == nil {
+		err := errors.New("missing request body")
+		badRequest(ctx, err)
+		return err
+	}
+
+	decoder := json.NewDecoder(ctx.Request.Body)
+	if binding.EnableDecoderDisallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+
+	if err := decoder.Decode(list); err != nil {
+		badRequest(ctx, err)
+		return err
+	}
+	return nil
+}
+
+// badListItem answers a list request whose item at index failed validation, prefixing the fields
+// with the item index, e.g. "[2].name".
+func badListItem(ctx *gin.Context, index int, err error) {
+	var invalid validator.ValidationErrors
+	if !errors.As(err, &invalid) {
+		badRequest(ctx, err)
+		return
+	}
+
+	fields := FieldErrors(invalid)
+	for i := range fields {
+		fields[i].Field = fmt.Sprintf("[%d].%s", index, fields[i].Field)
+	}
+	RespondError(ctx, http.StatusUnprocessableEntity, "validation_failed", "Some fields are invalid", fields)
+}
+
+// StatusCode derives a machine readable code from an HTTP status, e.g. "bad_request".
+func StatusCode(status int) string {
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
+
+// FieldErrors describes validation failures field by field.
+func FieldErrors(invalid validator.ValidationErrors) []FieldError {
+	fields := make([]FieldError, 0, len(invalid))
+	for _, fe := range invalid {
+		fields = append(fields, FieldError{
+			Field:   fe.Field(),
+			Rule:    fe.Tag(),
+			Message: ruleMessage(fe),
+		})
+	}
+	return fields
+}
+
+func ruleMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "min", "gte":
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "max", "lte":
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "len":
+		return fmt.Sprintf("must have a length of %s", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of %s", fe.Param())
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", fe.Param())
+	case "url":
+		return "must be a valid URL"
+	case "ltfield":
+		return fmt.Sprintf("must be lower than %s", fe.Param())
+	case "gtfield":
+		return fmt.Sprintf("must be after %s", fe.Param())
+	case "spec":
+		return fmt.Sprintf("must be a %s with a known unit", strings.ReplaceAll(fe.Param(), "_", " "))
+	case "sale_time":
+		return "must be a date, a date and time, or an RFC 3339 timestamp"
+	case "timezone":
+		return "must be an IANA time zone, e.g. Asia/Kolkata"
+	case "unique":
+		return fmt.Sprintf("must not repeat the same %s", strings.ToLower(fe.Param()))
+	case "required_without_all":
+		return fmt.Sprintf("is required unless one of %s is set", fe.Param())
+	default:
+		return fmt.Sprintf("failed the %s rule", fe.Tag())
+	}
+}
+
+// RegisterFieldNames makes validation errors of validate, and of the validator gin binds
+// requests with, name fields by the JSON keys clients send.
+func RegisterFieldNames(validate *validator.Validate) {
+	validate.RegisterTagNameFunc(jsonFieldName)
+	if engine, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		engine.RegisterTagNameFunc(jsonFieldName)
+	}
+}
+
+// RegisterValidations adds the rules of this API to validate, and to the validator gin binds
+// requests with. spec=<quantity> checks a text reads as a measurement of units.Quantity, and
+// sale_time that it reads as a sale time.
+func RegisterValidations(validate *validator.Validate) error {
+	validators := []*validator.Validate{validate}
+	if engine, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		validators = append(validators, engine)
+	}
+	for _, v := range validators {
+		if err := v.RegisterValidation("spec", validSpec); err != nil {
+			return err
+		}
+		if err := v.RegisterValidation("sale_time", validSaleTime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validSpec(fl validator.FieldLevel) bool {
+	quantity := units.Quantity(fl.Param())
+	if !units.Known(quantity) {
+		return false
+	}
+	_, err := units.Parse(fl.Field().String(), quantity)
+	return err == nil
+}
+
+func validSaleTime(fl validator.FieldLevel) bool {
+	_, err := pricing.ParseTime(fl.Field().String(), time.UTC, false)
+	return err == nil
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/auth"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/database"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// subjectFor builds the token subject of a user, admin or cse document, resolving the
+// permissions of its role. Principals without a role get the default role of their type.
+func (ga *GoApp) subjectFor(principal string, doc primitive.M) (auth.Subject, error) {
+	subject := auth.Subject{Principal: principal}
+
+	subject.ID, _ = doc["_id"].(primitive.ObjectID)
+	subject.Email, _ = doc["email"].(string)
+	subject.Name, _ = doc["name"].(string)
+
+	subject.Role, _ = doc["role"].(string)
+	if subject.Role == "" {
+		subject.Role = auth.DefaultRole(principal)
+	}
+
+	permissions, err := ga.rolePermissions(subject.Role)
+	if err != nil {
+		return subject, err
+	}
+	subject.Permissions = permissions
+
+	return subject, nil
+}
+
+func (ga *GoApp) rolePermissions(role string) ([]string, error) {
+	return RolePermissions(ga.DB, role)
+}
+
+// RolePermissions returns the permissions role grants as it is defined now, looking custom
+// roles up in db.
+func RolePermissions(db database.DBRepo, role string) ([]string, error) {
+	if permissions, ok := auth.BuiltinRoles[role]; ok {
+		return permissions, nil
+	}
+
+	custom, err := db.GetRole(role)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve role %q: %w", role, err)
+	}
+
+	return custom.Permissions, nil
+}
+
+func validatePermissions(permissions []string) error {
+	for _, p := range permissions {
+		if !auth.IsPermission(p) {
+			return fmt.Errorf("unknown permission %q", p)
+		}
+	}
+	return nil
+}
+
+// CreateRole defines a custom role, e.g. an inventory manager who may change stock but not delete orders.
+func (ga *GoApp) CreateRole() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var input struct {
+			Name        string   `json:"name" binding:"required"`
+			Permissions []string `json:"permissions" binding:"required"`
+		}
+
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			badRequest(ctx, err)
+			return
+		}
+
+		input.Name = strings.TrimSpace(input.Name)
+
+		if _, builtin := auth.BuiltinRoles[input.Name]; builtin || input.Name == "" {
+			respondError(ctx, http.StatusBadRequest, "Role name is reserved")
+			return
+		}
+
+		if err := validatePermissions(input.Permissions); err != nil {
+			respondError(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		role := &model.Role{
+			Name:        input.Name,
+			Permissions: input.Permissions,
+			CreatedAt:   time.Now(),
+			UpdatedAt:   time.Now(),
+		}
+
+		ok, status, err := ga.DB.CreateRole(role)
+		if err != nil || !ok {
+			respondError(ctx, http.StatusInternalServerError, "Failed to create role")
+			return
+		}
+
+		switch status {
+		case 1:
+			ctx.JSON(http.StatusCreated, gin.H{"message": "Role created successfully", "data": role})
+		case 2:
+			respondError(ctx, http.StatusConflict, "Role already exists")
+		}
+	}
+}
+
+// GetAllRoles lists the built in roles followed by the custom ones.
+func (ga *GoApp) GetAllRoles() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		custom, err := ga.DB.GetAllRoles()
+		if err != nil {
+			respondError(ctx, http.StatusInternalServerError, "Failed to get roles")
+			return
+		}
+
+		builtin := []gin.H{}
+		for _, name := range []string{auth.AdminRole, auth.CSERole, auth.CustomerRole} {
+			builtin = append(builtin, gin.H{"name": name, "permissions": auth.BuiltinRoles[name]})
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"message":     "Roles fetched successfully",
+			"builtin":     builtin,
+			"data":        custom,
+			"permissions": auth.AllPermissions,
+		})
+	}
+}
+
+// UpdateRole replaces the permissions of a custom role. Holders get them with their next request,
+// as Authorisation resolves the permissions of the role every time.
+func (ga *GoApp) UpdateRole() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var input struct {
+			Name        string   `json:"name" binding:"required"`
+			Permissions []string `json:"permissions" binding:"required"`
+		}
+
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			badRequest(ctx, err)
+			return
+		}
+
+		if _, builtin := auth.BuiltinRoles[input.Name]; builtin {
+			respondError(ctx, http.StatusBadRequest, "Built in roles cannot be changed")
+			return
+		}
+
+		if err := validatePermissions(input.Permissions); err != nil {
+			respondError(ctx, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		updated, err := ga.DB.UpdateRolePermissions(input.Name, input.Permissions)
+		if err != nil {
+			respondError(ctx, http.StatusInternalServerError, "Failed to update role")
+			return
+		}
+
+		if !updated {
+			respondError(ctx, http.StatusNotFound, "Role not found")
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "Role updated successfully"})
+	}
+}
+
+// DeleteRole removes a custom role that no admin holds anymore.
+func (ga *GoApp) DeleteRole() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		name := ctx.Param("name")
+
+		if _, builtin := auth.BuiltinRoles[name]; builtin {
+			respondError(ctx, http.StatusBadRequest, "Built in roles cannot be deleted")
+			return
+		}
+
+		status, err := ga.DB.DeleteRole(name)
+		if err != nil {
+			respondError(ctx, http.StatusInternalServerError, "Failed to delete role")
+			return
+		}
+
+		switch status {
+		case 1:
+			ctx.JSON(http.StatusOK, gin.H{"message": "Role deleted successfully"})
+		case 2:
+			respondError(ctx, http.StatusConflict, "Role is still assigned to admins")
+		case 3:
+			respondError(ctx, http.StatusNotFound, "Role not found")
+		}
+	}
+}
+
+// AssignRole gives an admin a built in or custom role.
+func (ga *GoApp) AssignRole() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var input struct {
+			AdminID string `json:"admin_id" binding:"required"`
+			Role    string `json:"role" binding:"required"`
+		}
+
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			badRequest(ctx, err)
+			return
+		}
+
+		adminID, err := primitive.ObjectIDFromHex(input.AdminID)
+		if err != nil {
+			respondError(ctx, http.StatusBadRequest, "Invalid admin ID format")
+			return
+		}
+
+		if input.Role == auth.CustomerRole || input.Role == auth.CSERole {
+			respondError(ctx, http.StatusBadRequest, "Role cannot be assigned to an admin")
+			return
+		}
+
+		if _, err := ga.rolePermissions(input.Role); err != nil {
+			respondError(ctx, http.StatusNotFound, "Role not found")
+			return
+		}
+
+		updated, err := ga.DB.AssignRoleToAdmin(adminID, input.Role)
+		if err != nil {
+			respondError(ctx, http.StatusInternalServerError, "Failed to assign role")
+			return
+		}
+
+		if !updated {
+			respondError(ctx, http.StatusNotFound, "Admin not found")
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "Role assigned successfully"})
+	}
+}
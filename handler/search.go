@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/dto"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/search"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/units"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Results and suggestions returned when the request does not say how many.
+const (
+	defaultSearchResults     = 20
+	defaultSearchSuggestions = 8
+)
+
+// productFields is what of a product is searchable; the name weighs most, the description least.
+func productFields(p *model.Product) []search.Field {
+	d := p.Description
+	description := strings.Join([]string{
+		d.FuelType, d.Mileage, d.Engine, d.PowerOutput, d.SeatingCapacity, d.Tyre, d.TopSpeed,
+	}, " ")
+
+	// Every option the variants come in counts once, however many variants share it.
+	seen := map[string]bool{}
+	variants := []string{}
+	for _, v := range p.Variants {
+		for _, option := range []string{v.Color, v.Trim, v.Transmission, v.FuelType} {
+			if option != "" && !seen[option] {
+				seen[option] = true
+				variants = append(variants, option)
+			}
+		}
+	}
+
+	return []search.Field{
+		{Text: p.Name, Weight: 3},
+		{Text: p.Model_Name, Weight: 2.5},
+		{Text: p.Company_Name, Weight: 2},
+		{Text: p.Category, Weight: 1.5},
+		{Text: description, Weight: 1},
+		{Text: strings.Join(variants, " "), Weight: 1},
+	}
+}
+
+// indexProduct adds a product to the search index, or refreshes it.
+func (ga *GoApp) indexProduct(p *model.Product) {
+	if ga.App.Search == nil || p.ID.IsZero() {
+		return
+	}
+	ga.App.Search.Put(p.ID.Hex(), productFields(p)...)
+}
+
+func (ga *GoApp) unindexProduct(id primitive.ObjectID) {
+	if ga.App.Search == nil {
+		return
+	}
+	ga.App.Search.Delete(id.Hex())
+}
+
+// BuildSearchIndex indexes every product in the database. Products created, updated or deleted
+// through the API afterwards keep the index in sync.
+func (ga *GoApp) BuildSearchIndex() error {
+	products, err := ga.DB.GetAllProducts()
+	if err != nil {
+		return err
+	}
+
+	for i := range products {
+		ga.indexProduct(&products[i])
+	}
+
+	ga.App.InfoLogger.Printf("Search index built with %d products", len(products))
+	return nil
+}
+
+// SearchProducts returns the products matching a free text query, the most relevant first, each
+// with its relevance score.
+func (ga *GoApp) SearchProducts() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var input dto.ProductSearchRequest
+		if err := ctx.ShouldBindQuery(&input); err != nil {
+			badRequest(ctx, err)
+			return
+		}
+
+		if input.Limit == 0 {
+			input.Limit = defaultSearchResults
+		}
+
+		hits, total := ga.App.Search.Search(input.Q, input.Offset, input.Limit)
+
+		ids := make([]primitive.ObjectID, 0, len(hits))
+		scores := make(map[primitive.ObjectID]float64, len(hits))
+		for _, hit := range hits {
+			id, err := primitive.ObjectIDFromHex(hit.ID)
+			if err != nil {
+				continue
+			}
+			ids = append(ids, id)
+			scores[id] = hit.Score
+		}
+
+		products := []primitive.M{}
+		if len(ids) > 0 {
+			var err error
+			products, err = ga.DB.GetProductsByIDs(ids)
+			if err != nil {
+				abortWithError(ctx, err)
+				return
+			}
+		}
+
+		for _, product := range products {
+			product["score"] = scores[product["_id"].(primitive.ObjectID)]
+		}
+		dto.ShowSpecs(products, units.System(input.Units))
+		dto.ShowPrices(products, ga.App.Pricing, time.Now())
+
+		ctx.JSON(http.StatusOK, gin.H{"data": products, "total": total})
+	}
+}
+
+// SuggestProducts completes what a customer is typing in the search box.
+func (ga *GoApp) SuggestProducts() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var input dto.ProductSuggestRequest
+		if err := ctx.ShouldBindQuery(&input); err != nil {
+			badRequest(ctx, err)
+			return
+		}
+
+		if input.Limit == 0 {
+			input.Limit = defaultSearchSuggestions
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"data": ga.App.Search.Suggest(input.Q, input.Limit)})
+	}
+}
@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/auth"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxDeviceName caps the device label clients may send in the X-Device-Name header.
+const maxDeviceName = 64
+
+// hashToken returns the hex SHA-256 of a refresh token, the form sessions store it in.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// startSession issues the first token pair of a new login session of subject and records the
+// session with the device it was started from. The session id is the family of the tokens.
+func (ga *GoApp) startSession(ctx *gin.Context, subject auth.Subject) (string, string, string, error) {
+	now := time.Now()
+	id := primitive.NewObjectID()
+	family := id.Hex()
+
+	t1, t2, err := auth.Generate(subject, family)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	err = ga.DB.CreateLoginSession(&model.LoginSession{
+		ID:          id,
+		Principal:   subject.Principal,
+		SubjectID:   subject.ID,
+		RefreshHash: hashToken(t2),
+		Device:      deviceName(ctx),
+		IP:          ctx.ClientIP(),
+		UserAgent:   ctx.Request.UserAgent(),
+		CreatedAt:   now,
+		LastSeenAt:  now,
+		ExpiresAt:   now.Add(auth.RefreshTokenLifetime),
+	})
+	if err != nil {
+		return "", "", "", err
+	}
+
+	return family, t1, t2, nil
+}
+
+// deviceName labels a session with the X-Device-Name header, or with the platform and browser
+// guessed from the user agent when the client did not name itself.
+func deviceName(ctx *gin.Context) string {
+	if name := strings.TrimSpace(ctx.GetHeader("X-Device-Name")); name != "" {
+		if len(name) > maxDeviceName {
+			name = name[:maxDeviceName]
+		}
+		return name
+	}
+
+	ua := ctx.Request.UserAgent()
+	if ua == "" {
+		return "Unknown device"
+	}
+
+	platform := "Unknown OS"
+	for _, p := range []struct{ token, name string }{
+		{"Android", "Android"},
+		{"iPhone", "iPhone"},
+		{"iPad", "iPad"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"Linux", "Linux"},
+	} {
+		if strings.Contains(ua, p.token) {
+			platform = p.name
+			break
+		}
+	}
+
+	browser := "Unknown client"
+	for _, b := range []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+		{"PostmanRuntime/", "Postman"},
+	} {
+		if strings.Contains(ua, b.token) {
+			browser = b.name
+			break
+		}
+	}
+
+	return browser + " on " + platform
+}
+
+// endSession revokes the login session the token of claims belongs to.
+func (ga *GoApp) endSession(claims *auth.GoAppClaims) {
+	id, err := primitive.ObjectIDFromHex(claims.Family)
+	if err != nil {
+		return
+	}
+
+	if err := ga.DB.RevokeLoginSession(claims.Principal, claims.ID, id); err != nil {
+		ga.App.ErrorLogger.Printf("Error revoking session %s: %v", claims.Family, err)
+	}
+}
+
+// revokeSessions ends every login session of a principal, e.g. after its password changed.
+func (ga *GoApp) revokeSessions(principal string, id primitive.ObjectID) {
+	if _, err := ga.DB.RevokeLoginSessions(principal, id); err != nil {
+		ga.App.ErrorLogger.Printf("Error revoking sessions of %s %s: %v", principal, id.Hex(), err)
+	}
+}
+
+// sessionView is a session as listed to its owner, flagging the one the request came from.
+type sessionView struct {
+	model.LoginSession
+	Current bool `json:"current"`
+}
+
+func sessionViews(sessions []model.LoginSession, current string) []sessionView {
+	views := make([]sessionView, 0, len(sessions))
+	for _, s := range sessions {
+		views = append(views, sessionView{LoginSession: s, Current: s.ID.Hex() == current})
+	}
+	return views
+}
+
+// GetMySessions lists the active login sessions of the signed in user or admin.
+func (ga *GoApp) GetMySessions() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+
+		claims := ctx.MustGet("Claims").(*auth.GoAppClaims)
+
+		sessions, err := ga.DB.ListLoginSessions(claims.Principal, claims.ID)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"sessions": sessionViews(sessions, claims.Family)})
+	}
+}
+
+// RevokeMySession signs the caller out of one of its sessions, e.g. on a lost device.
+func (ga *GoApp) RevokeMySession() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+
+		claims := ctx.MustGet("Claims").(*auth.GoAppClaims)
+
+		sessionID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+		if err != nil {
+			respondError(ctx, http.StatusBadRequest, "Invalid session ID format")
+			return
+		}
+
+		if err := ga.DB.RevokeLoginSession(claims.Principal, claims.ID, sessionID); err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "Session revoked successfully"})
+	}
+}
+
+// GetUserSessions lists the active login sessions of any user.
+func (ga *GoApp) GetUserSessions() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+
+		userID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+		if err != nil {
+			respondError(ctx, http.StatusBadRequest, "Invalid user ID format")
+			return
+		}
+
+		sessions, err := ga.DB.ListLoginSessions(auth.UserPrincipal, userID)
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"sessions": sessions})
+	}
+}
+
+// RevokeUserSession signs a user out of one of its sessions.
+func (ga *GoApp) RevokeUserSession() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+
+		userID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+		if err != nil {
+			respondError(ctx, http.StatusBadRequest, "Invalid user ID format")
+			return
+		}
+
+		sessionID, err := primitive.ObjectIDFromHex(ctx.Param("sessionId"))
+		if err != nil {
+			respondError(ctx, http.StatusBadRequest, "Invalid session ID format")
+			return
+		}
+
+		if err := ga.DB.RevokeLoginSession(auth.UserPrincipal, userID, sessionID); err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+
+		ga.App.InfoLogger.Printf("Session %s of user %s revoked", sessionID.Hex(), userID.Hex())
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "Session revoked successfully"})
+	}
+}
+
+// CSRFToken returns the CSRF token of the caller, which browser clients echo in the
+// X-CSRF-Token header of state changing requests.
+func (ga *GoApp) CSRFToken() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, gin.H{"csrf_token": ctx.GetString("CSRFToken")})
+	}
+}
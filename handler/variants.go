@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/domain"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/dto"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// variantProblem says what is wrong with an item of a cart or order naming variantID of product,
+// if anything: it must name one of its variants when it is sold in variants, none otherwise. It
+// returns the rule the item breaks and why, and false when it breaks one.
+func variantProblem(product model.Product, variantID primitive.ObjectID) (string, string, bool) {
+	if len(product.Variants) == 0 {
+		if !variantID.IsZero() {
+			return "excluded", "must be left out as the product has no variants", false
+		}
+		return "", "", true
+	}
+
+	if variantID.IsZero() {
+		return "required", "is required as the product comes in variants", false
+	}
+	if product.Variant(variantID) == nil {
+		return "exists", "must be a variant of the product", false
+	}
+	return "", "", true
+}
+
+// checkVariant makes sure an item of a cart or order names what can be bought of a product,
+// answering the request when not. field names the request field the variant id came from.
+func (ga *GoApp) checkVariant(ctx *gin.Context, field string, productID primitive.ObjectID, variantID primitive.ObjectID) bool {
+	invalid := func(rule string, message string) bool {
+		RespondError(ctx, http.StatusUnprocessableEntity, "validation_failed", "Some fields are invalid", []FieldError{
+			{Field: field, Rule: rule, Message: message},
+		})
+		return false
+	}
+
+	product, err := ga.DB.GetProduct(productID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return invalid("exists", "must be a variant of an existing product")
+	}
+	if err != nil {
+		abortWithError(ctx, err)
+		return false
+	}
+
+	if rule, message, ok := variantProblem(product, variantID); !ok {
+		return invalid(rule, message)
+	}
+	return true
+}
+
+// reindexProduct refreshes the search entry and the stored price of a product after it changed.
+func (ga *GoApp) reindexProduct(id primitive.ObjectID) {
+	product, err := ga.DB.GetProduct(id)
+	if err != nil {
+		ga.App.ErrorLogger.Printf("cannot reindex product %s : %v", id.Hex(), err)
+		return
+	}
+	ga.indexProduct(&product)
+	ga.priceProduct(&product)
+}
+
+// parseProductID reads the id of the product a variant route is for, answering the request when
+// it is malformed.
+func parseProductID(ctx *gin.Context) (primitive.ObjectID, bool) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		respondError(ctx, http.StatusBadRequest, "Invalid product ID format")
+		return id, false
+	}
+	return id, true
+}
+
+func productAndVariantIDs(ctx *gin.Context) (primitive.ObjectID, primitive.ObjectID, bool) {
+	productID, ok := parseProductID(ctx)
+	if !ok {
+		return productID, primitive.NilObjectID, false
+	}
+
+	variantID, err := primitive.ObjectIDFromHex(ctx.Param("variant_id"))
+	if err != nil {
+		respondError(ctx, http.StatusBadRequest, "Invalid variant ID format")
+		return productID, variantID, false
+	}
+
+	return productID, variantID, true
+}
+
+// AddVariant adds a variant to a product. The product's price, stock and options follow.
+func (ga *GoApp) AddVariant() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		productID, ok := parseProductID(ctx)
+		if !ok {
+			return
+		}
+
+		var input dto.VariantRequest
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			badRequest(ctx, err)
+			return
+		}
+
+		variant := input.ToVariant()
+
+		if err := ga.DB.AddVariant(productID, variant); err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+
+		ga.reindexProduct(productID)
+
+		ctx.JSON(http.StatusCreated, gin.H{"message": "Variant added successfully", "data": variant})
+	}
+}
+
+// UpdateVariant edits a variant of a product.
+func (ga *GoApp) UpdateVariant() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		productID, variantID, ok := productAndVariantIDs(ctx)
+		if !ok {
+			return
+		}
+
+		var input dto.UpdateVariantRequest
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			badRequest(ctx, err)
+			return
+		}
+
+		variant, err := ga.DB.UpdateVariant(productID, variantID, input.ToChanges())
+		if err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+
+		ga.reindexProduct(productID)
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "Variant updated successfully", "data": variant})
+	}
+}
+
+// DeleteVariant removes a variant from a product.
+func (ga *GoApp) DeleteVariant() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		productID, variantID, ok := productAndVariantIDs(ctx)
+		if !ok {
+			return
+		}
+
+		if err := ga.DB.DeleteVariant(productID, variantID); err != nil {
+			abortWithError(ctx, err)
+			return
+		}
+
+		ga.reindexProduct(productID)
+
+		ga.App.InfoLogger.Printf("Variant %s of product %s deleted by %s", variantID.Hex(), productID.Hex(), ctx.MustGet("UID").(primitive.ObjectID).Hex())
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "Variant deleted successfully"})
+	}
+}
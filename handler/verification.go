@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/auth"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/domain"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const emailVerificationLifetime = 24 * time.Hour
+
+// sendVerificationEmail mails a signed link proving that the user owns email.
+func (ga *GoApp) sendVerificationEmail(userID primitive.ObjectID, email string) error {
+	token, err := auth.GenerateActionToken(auth.PurposeVerifyEmail, userID.Hex(), email, emailVerificationLifetime)
+	if err != nil {
+		return err
+	}
+
+	link := strings.TrimRight(ga.App.BaseURL, "/") + "/verify-email?token=" + url.QueryEscape(token)
+
+	body := fmt.Sprintf("Please confirm that %s is your email address by opening the link below.\n\n%s\n\n"+
+		"The link expires in %d hours. If you did not create a CarsGo account, you can ignore this email.",
+		email, link, int(emailVerificationLifetime.Hours()))
+
+	return ga.App.Mailer.Send(email, "Verify your CarsGo email", body)
+}
+
+// requireVerifiedEmail aborts the request with the email_not_verified code when the customer it
+// acts for, as actingCustomer resolved it, has not verified their email yet. That holds for admins
+// acting on behalf of customers too; an admin naming no existing customer in field gets a 422.
+func (ga *GoApp) requireVerifiedEmail(ctx *gin.Context, field string, customerID primitive.ObjectID) bool {
+	user, err := ga.DB.GetEmailVerification(customerID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) && ctx.GetString("Principal") != auth.UserPrincipal {
+			RespondError(ctx, http.StatusUnprocessableEntity, "validation_failed", "Some fields are invalid", []FieldError{
+				{Field: field, Rule: "exists", Message: "must be an existing customer"},
+			})
+			return false
+		}
+		internalError(ctx, err, "Failed to check email verification")
+		return false
+	}
+
+	if verified, _ := user["verified"].(bool); !verified {
+		message := "Please verify your email before placing orders"
+		if ctx.GetString("Principal") != auth.UserPrincipal {
+			message = "The customer has not verified their email yet"
+		}
+		RespondError(ctx, http.StatusForbidden, "email_not_verified", message, nil)
+		return false
+	}
+
+	return true
+}
+
+// VerifyEmail confirms the email address a verification link was sent to. For a changed email
+// this is the moment the change takes effect.
+func (ga *GoApp) VerifyEmail() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		claims, err := auth.ParseActionToken(auth.PurposeVerifyEmail, ctx.Query("token"))
+		if err != nil {
+			RespondError(ctx, http.StatusBadRequest, "invalid_verification_link", "Verification link is invalid or has expired", nil)
+			return
+		}
+
+		userID, err := primitive.ObjectIDFromHex(claims.Subject)
+		if err != nil {
+			RespondError(ctx, http.StatusBadRequest, "invalid_verification_link", "Verification link is invalid or has expired", nil)
+			return
+		}
+
+		verified, err := ga.DB.MarkEmailVerified(userID, claims.Email)
+		if err != nil {
+			respondError(ctx, http.StatusInternalServerError, "Failed to verify email")
+			return
+		}
+
+		if verified {
+			ctx.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+			return
+		}
+
+		if _, err := ga.DB.FindPrincipalByEmail(auth.UserPrincipal, claims.Email); err == nil {
+			RespondError(ctx, http.StatusConflict, "email_taken", "Email is already in use", nil)
+			return
+		}
+
+		changed, err := ga.DB.ConfirmPendingEmail(userID, claims.Email)
+		if err != nil {
+			respondError(ctx, http.StatusInternalServerError, "Failed to verify email")
+			return
+		}
+
+		if !changed {
+			RespondError(ctx, http.StatusBadRequest, "invalid_verification_link", "Verification link is no longer valid", nil)
+			return
+		}
+
+		if err := auth.RevokeAll(userID); err != nil {
+			ga.App.ErrorLogger.Printf("Error revoking sessions after email change for %s: %v", userID.Hex(), err)
+		}
+
+		ga.revokeSessions(auth.UserPrincipal, userID)
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "Email changed successfully, please sign in with your new email"})
+	}
+}
+
+// ResendVerification sends a new verification link for the pending email, or for the current one
+// while it is unverified.
+func (ga *GoApp) ResendVerification() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userID := ctx.MustGet("UID").(primitive.ObjectID)
+
+		user, err := ga.DB.GetEmailVerification(userID)
+		if err != nil {
+			respondError(ctx, http.StatusInternalServerError, "Failed to fetch user")
+			return
+		}
+
+		email, _ := user["pending_email"].(string)
+		if email == "" {
+			if verified, _ := user["verified"].(bool); verified {
+				RespondError(ctx, http.StatusBadRequest, "email_already_verified", "Email is already verified", nil)
+				return
+			}
+			email, _ = user["email"].(string)
+		}
+
+		if err := ga.sendVerificationEmail(userID, email); err != nil {
+			ga.App.ErrorLogger.Printf("Error sending verification email to %s: %v", email, err)
+			respondError(ctx, http.StatusInternalServerError, "Failed to send verification email")
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "Verification email sent to " + email})
+	}
+}
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Purposes of action tokens. An action token is only accepted for the purpose it was issued for.
+const (
+	PurposeAdminInvite = "admin_invite"
+	PurposeVerifyEmail = "verify_email"
+	PurposeMFALogin    = "mfa_login"
+	PurposeMFAEnroll   = "mfa_enroll"
+)
+
+var ErrWrongPurpose = errors.New("token was issued for a different purpose")
+
+// ActionClaims are carried by short lived, single purpose tokens such as admin invites and email verification links.
+type ActionClaims struct {
+	jwt.RegisteredClaims
+	Purpose string
+	Email   string
+}
+
+// GenerateActionToken signs a token that lets its bearer perform a single kind of action on subject.
+func GenerateActionToken(purpose string, subject string, email string, ttl time.Duration) (string, error) {
+	token, _, err := IssueActionToken(purpose, subject, email, ttl)
+	return token, err
+}
+
+// IssueActionToken is GenerateActionToken for callers that also need the token ID, e.g. to make
+// the token single use by remembering which ID is still valid.
+func IssueActionToken(purpose string, subject string, email string, ttl time.Duration) (string, string, error) {
+	claims := ActionClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        primitive.NewObjectID().Hex(),
+			Subject:   subject,
+			Issuer:    Issuer,
+			Audience:  jwt.ClaimStrings{ActionAudience},
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+		},
+		Purpose: purpose,
+		Email:   email,
+	}
+
+	token, err := sign(claims)
+	if err != nil {
+		return "", "", err
+	}
+
+	return token, claims.ID, nil
+}
+
+// ParseActionToken validates an action token issued for purpose and returns its claims.
+func ParseActionToken(purpose string, tokenString string) (*ActionClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &ActionClaims{}, verificationKey, parserOptions(ActionAudience)...)
+	if err != nil {
+		return nil, classify(err)
+	}
+
+	claims, ok := token.Claims.(*ActionClaims)
+	if !ok || claims.Purpose != purpose {
+		return nil, ErrWrongPurpose
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// APIKeyRole is the role API key requests are authorised as. Their permissions are the key's scopes.
+const APIKeyRole = "api_key"
+
+// apiKeyTag starts every API key so leaked keys are easy to recognise, e.g. by secret scanners.
+const apiKeyTag = "cgk"
+
+// GenerateAPIKey returns a new API key and its prefix. Keys look like cgk_<prefix>_<secret>; the
+// prefix identifies the key and may be shown, the whole key is only known to its holder.
+func GenerateAPIKey() (string, string, error) {
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+
+	prefix := hex.EncodeToString(id)
+
+	return apiKeyTag + "_" + prefix + "_" + base64.RawURLEncoding.EncodeToString(secret), prefix, nil
+}
+
+// APIKeyPrefix returns the prefix of key, or false if key is not shaped like an API key.
+func APIKeyPrefix(key string) (string, bool) {
+	parts := strings.SplitN(key, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyTag || len(parts[1]) != 8 || parts[2] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// HashAPIKey returns the form API keys are stored in. Keys are long random strings, so a
+// plain SHA-256 is enough to make a leaked database useless.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,69 +1,131 @@
 package auth
 
 import (
-	"net/http"
-	"os"
+	"errors"
 	"time"
 
-	"github.com/PraveenRajPurak/CarsGo-Backend/modules/config"
 	"github.com/golang-jwt/jwt/v5"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-var app config.GoAppTools
+// Principal types a token can be issued to.
+const (
+	UserPrincipal  = "user"
+	AdminPrincipal = "admin"
+	CSEPrincipal   = "cse"
+)
+
+// Token types carried in GoAppClaims.Type.
+const (
+	AccessToken  = "access"
+	RefreshToken = "refresh"
+)
+
+// Lifetimes of the tokens issued by Generate. No token outlives RefreshTokenLifetime, so that is
+// also how long revocations are remembered.
+const (
+	AccessTokenLifetime  = 24 * time.Hour
+	RefreshTokenLifetime = 48 * time.Hour
+)
+
+var ErrNotRefreshToken = errors.New("token is not a refresh token")
 
 type GoAppClaims struct {
 	jwt.RegisteredClaims
-	Email string
-	ID    primitive.ObjectID
-	Name  string
+	Email       string
+	ID          primitive.ObjectID
+	Name        string
+	Principal   string
+	Role        string
+	Permissions []string
+	Type        string
+	Family      string
 }
 
-var secretKey = os.Getenv("ACCESS_TOKEN_SECRET")
+// Subject is the identity tokens are issued to.
+type Subject struct {
+	Email       string
+	ID          primitive.ObjectID
+	Name        string
+	Principal   string
+	Role        string
+	Permissions []string
+}
 
-func Generate(email string, id primitive.ObjectID, name string) (string, string, error) {
+// Generate issues an access/refresh token pair. Every rotation of a refresh token keeps
+// the family it was first issued in so reuse of a rotated-out token can be traced back.
+func Generate(subject Subject, family string) (string, string, error) {
 
 	goAppClaims := GoAppClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
-			Issuer:    "ecommerceApp",
+			ID:        primitive.NewObjectID().Hex(),
+			Issuer:    Issuer,
+			Audience:  jwt.ClaimStrings{Audience},
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 24)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenLifetime)),
 		},
-		Email: email,
-		ID:    id,
-		Name:  name,
+		Email:       subject.Email,
+		ID:          subject.ID,
+		Name:        subject.Name,
+		Principal:   subject.Principal,
+		Role:        subject.Role,
+		Permissions: subject.Permissions,
+		Type:        AccessToken,
+		Family:      family,
 	}
 
-	newGoAppClaims := &jwt.RegisteredClaims{
-		Issuer:    "ecommerceApp",
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 48)),
+	newGoAppClaims := GoAppClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        primitive.NewObjectID().Hex(),
+			Issuer:    Issuer,
+			Audience:  jwt.ClaimStrings{Audience},
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(RefreshTokenLifetime)),
+		},
+		Email:     subject.Email,
+		ID:        subject.ID,
+		Name:      subject.Name,
+		Principal: subject.Principal,
+		Type:      RefreshToken,
+		Family:    family,
 	}
 
-	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, goAppClaims).SignedString([]byte(secretKey))
+	token, err := sign(goAppClaims)
 	if err != nil {
 		return "", "", err
 	}
-	newToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, newGoAppClaims).SignedString([]byte(secretKey))
+	newToken, err := sign(newGoAppClaims)
 	if err != nil {
 		return "", "", err
 	}
 	return token, newToken, nil
 }
 
-func Parse(tokenString string) (*GoAppClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &GoAppClaims{}, func(t *jwt.Token) (interface{}, error) {
-		return []byte(secretKey), nil
-	})
+// ParseRefresh validates a refresh token and returns its claims.
+func ParseRefresh(tokenString string) (*GoAppClaims, error) {
+	claims, err := parse(tokenString)
 	if err != nil {
-		app.ErrorLogger.Fatalf("error while parsing token with it claims %v", err)
+		return nil, err
 	}
-	claims, ok := token.Claims.(*GoAppClaims)
-	if !ok {
-		app.ErrorLogger.Fatalf("error %v controller not authorized access", http.StatusUnauthorized)
+	if claims.Type != RefreshToken {
+		return nil, ErrNotRefreshToken
 	}
-	if claims.ExpiresAt != nil && claims.ExpiresAt.Time.Before(time.Now()) {
-		app.ErrorLogger.Fatalf("error %v token has expired", http.StatusUnauthorized)
+	return claims, nil
+}
+
+// Parse validates a token issued by Generate and returns its claims. Errors wrap one of
+// ErrTokenExpired, ErrTokenMalformed, ErrSignatureInvalid or ErrTokenInvalid.
+func Parse(tokenString string) (*GoAppClaims, error) {
+	return parse(tokenString)
+}
+
+func parse(tokenString string) (*GoAppClaims, error) {
+	claims := &GoAppClaims{}
+
+	_, err := jwt.ParseWithClaims(tokenString, claims, verificationKey, parserOptions(Audience)...)
+	if err != nil {
+		return nil, classify(err)
 	}
+
 	return claims, nil
 }
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Issuer and audiences of our tokens. Access and refresh tokens are meant for the API, action
+// tokens only for the endpoint handling their purpose, so neither is accepted in place of the other.
+const (
+	Issuer         = "ecommerceApp"
+	Audience       = "carsgo-api"
+	ActionAudience = "carsgo-actions"
+)
+
+// Leeway is the clock skew tolerated when checking exp, nbf and iat.
+const Leeway = 30 * time.Second
+
+var (
+	ErrTokenExpired     = errors.New("token has expired")
+	ErrTokenMalformed   = errors.New("token is malformed")
+	ErrSignatureInvalid = errors.New("token signature is invalid")
+	ErrTokenInvalid     = errors.New("token is not valid for this service")
+)
+
+func parserOptions(audience string) []jwt.ParserOption {
+	return []jwt.ParserOption{
+		jwt.WithValidMethods(signingMethods),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithIssuer(Issuer),
+		jwt.WithAudience(audience),
+		jwt.WithLeeway(Leeway),
+	}
+}
+
+// classify maps the errors of the jwt package onto ours.
+func classify(err error) error {
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return fmt.Errorf("%w: %v", ErrTokenExpired, err)
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return fmt.Errorf("%w: %v", ErrTokenMalformed, err)
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid), errors.Is(err, jwt.ErrTokenUnverifiable), errors.Is(err, ErrUnknownKey):
+		return fmt.Errorf("%w: %v", ErrSignatureInvalid, err)
+	default:
+		return fmt.Errorf("%w: %v", ErrTokenInvalid, err)
+	}
+}
+
+// ErrorCode returns the machine readable code to report a token error to the client with.
+func ErrorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrTokenExpired):
+		return "token_expired"
+	case errors.Is(err, ErrTokenMalformed):
+		return "token_malformed"
+	case errors.Is(err, ErrSignatureInvalid):
+		return "signature_invalid"
+	case errors.Is(err, ErrNotRefreshToken):
+		return "wrong_token_type"
+	default:
+		return "token_invalid"
+	}
+}
@@ -0,0 +1,272 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrUnknownKey  = errors.New("token is signed with an unknown key")
+	ErrNoActiveKey = errors.New("no signing key is loaded")
+)
+
+// signingMethods are the algorithms tokens may be signed with.
+var signingMethods = []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}
+
+type signingKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// Keyring holds every key tokens may be verified with, looked up by the kid header, and the
+// one new tokens are signed with. Keys whose file only holds a public key verify tokens issued
+// before a rotation until those expire, without being able to sign new ones.
+type Keyring struct {
+	mu     sync.RWMutex
+	keys   map[string]*signingKey
+	active *signingKey
+}
+
+var keyring = &Keyring{keys: map[string]*signingKey{}}
+
+// LoadKeyring loads every *.pem file in dir as a key named after the file, e.g. 2026-10.pem has
+// the kid "2026-10". Files may hold a PKCS#8 RSA or Ed25519 private key, a PKCS#1 RSA private key
+// or a PKIX public key. activeKID picks the key new tokens are signed with and may only be left
+// empty when dir holds a single private key.
+//
+// Without a dir an ephemeral Ed25519 key is generated, so tokens do not survive a restart. That is
+// only meant for local development.
+func LoadKeyring(dir string, activeKID string) error {
+	keys := map[string]*signingKey{}
+
+	if dir == "" {
+		key, err := ephemeralKey()
+		if err != nil {
+			return err
+		}
+		keys[key.kid] = key
+		activeKID = key.kid
+	} else {
+		files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+		if err != nil {
+			return err
+		}
+		sort.Strings(files)
+
+		for _, file := range files {
+			key, err := readKey(file)
+			if err != nil {
+				return fmt.Errorf("cannot load signing key %s: %w", file, err)
+			}
+			keys[key.kid] = key
+		}
+	}
+
+	if activeKID == "" {
+		for kid, key := range keys {
+			if key.private == nil {
+				continue
+			}
+			if activeKID != "" {
+				return errors.New("several private keys are loaded, JWT_SIGNING_KID must name the active one")
+			}
+			activeKID = kid
+		}
+	}
+
+	active, ok := keys[activeKID]
+	if !ok || active.private == nil {
+		return fmt.Errorf("%w: no private key with kid %q", ErrNoActiveKey, activeKID)
+	}
+
+	keyring.mu.Lock()
+	defer keyring.mu.Unlock()
+
+	keyring.keys = keys
+	keyring.active = active
+
+	return nil
+}
+
+func ephemeralKey() (*signingKey, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return &signingKey{
+		kid:     "ephemeral-" + base64.RawURLEncoding.EncodeToString(public[:6]),
+		method:  jwt.SigningMethodEdDSA,
+		private: private,
+		public:  public,
+	}, nil
+}
+
+func readKey(file string) (*signingKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	key := &signingKey{kid: strings.TrimSuffix(filepath.Base(file), ".pem")}
+
+	var parsed interface{}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.method, key.private, key.public = jwt.SigningMethodRS256, k, &k.PublicKey
+	case ed25519.PrivateKey:
+		key.method, key.private, key.public = jwt.SigningMethodEdDSA, k, k.Public()
+	case *rsa.PublicKey:
+		key.method, key.public = jwt.SigningMethodRS256, k
+	case ed25519.PublicKey:
+		key.method, key.public = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, use RSA or Ed25519", parsed)
+	}
+
+	if rsaKey, ok := key.public.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < 2048 {
+		return nil, errors.New("RSA keys must be at least 2048 bits")
+	}
+
+	return key, nil
+}
+
+// GenerateKeyFile writes a new PKCS#8 Ed25519 private key for kid into dir.
+func GenerateKeyFile(dir string, kid string) (string, error) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return "", err
+	}
+
+	file := filepath.Join(dir, kid+".pem")
+
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	return file, pem.Encode(f, &pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+// sign signs claims with the active key and names it in the kid header.
+func sign(claims jwt.Claims) (string, error) {
+	keyring.mu.RLock()
+	active := keyring.active
+	keyring.mu.RUnlock()
+
+	if active == nil {
+		return "", ErrNoActiveKey
+	}
+
+	token := jwt.NewWithClaims(active.method, claims)
+	token.Header["kid"] = active.kid
+
+	return token.SignedString(active.private)
+}
+
+// verificationKey is the jwt.Keyfunc selecting the public key named by the token's kid header.
+func verificationKey(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+
+	keyring.mu.RLock()
+	key, ok := keyring.keys[kid]
+	keyring.mu.RUnlock()
+
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	if t.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("key %q does not sign with %s", kid, t.Method.Alg())
+	}
+
+	return key.public, nil
+}
+
+// JWK is a public key in JSON Web Key format.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public halves of all loaded keys so other services can verify our tokens.
+func JWKS() JWKSet {
+	keyring.mu.RLock()
+	defer keyring.mu.RUnlock()
+
+	set := JWKSet{Keys: []JWK{}}
+
+	for _, key := range keyring.keys {
+		jwk := JWK{Kid: key.kid, Use: "sig", Alg: key.method.Alg()}
+
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+
+	return set
+}
@@ -0,0 +1,117 @@
+package auth
+
+// Permissions a route can require. Tokens carry the permission set of their role.
+const (
+	PermAccountSelf    = "account:self"
+	PermCartWrite      = "cart:write"
+	PermOrdersPlace    = "orders:place"
+	PermChatUse        = "chat:use"
+	PermReviewsWrite   = "reviews:write"
+	PermCatalogWrite   = "catalog:write"
+	PermCatalogDelete  = "catalog:delete"
+	PermOrdersRead     = "orders:read"
+	PermOrdersWrite    = "orders:write"
+	PermOrdersDelete   = "orders:delete"
+	PermPaymentsWrite  = "payments:write"
+	PermChatHandle     = "chat:handle"
+	PermCSEManage      = "cse:manage"
+	PermUsersRead      = "users:read"
+	PermUsersManage    = "users:manage"
+	PermPaymentsRead   = "payments:read"
+	PermRolesManage    = "roles:manage"
+	PermAdminsInvite   = "admins:invite"
+	PermSecurityManage = "security:manage"
+	PermAPIKeysManage  = "api_keys:manage"
+)
+
+// Built in roles, one per principal type. Custom roles are stored in the roles collection.
+const (
+	CustomerRole = "customer"
+	AdminRole    = "admin"
+	CSERole      = "cse"
+)
+
+var AllPermissions = []string{
+	PermAccountSelf,
+	PermCartWrite,
+	PermOrdersPlace,
+	PermChatUse,
+	PermReviewsWrite,
+	PermCatalogWrite,
+	PermCatalogDelete,
+	PermOrdersRead,
+	PermOrdersWrite,
+	PermOrdersDelete,
+	PermPaymentsWrite,
+	PermChatHandle,
+	PermCSEManage,
+	PermUsersRead,
+	PermUsersManage,
+	PermPaymentsRead,
+	PermRolesManage,
+	PermAdminsInvite,
+	PermSecurityManage,
+	PermAPIKeysManage,
+}
+
+// APIKeyScopes are the permissions an API key may be granted. Keys act for integrations, never
+// for a person, so account and access management stay with signed in admins.
+var APIKeyScopes = []string{
+	PermCatalogWrite,
+	PermCatalogDelete,
+	PermOrdersRead,
+	PermOrdersWrite,
+	PermOrdersDelete,
+	PermPaymentsRead,
+	PermPaymentsWrite,
+	PermUsersRead,
+}
+
+var BuiltinRoles = map[string][]string{
+	CustomerRole: {PermAccountSelf, PermCartWrite, PermOrdersPlace, PermChatUse, PermReviewsWrite},
+	AdminRole:    AllPermissions,
+	CSERole:      {PermAccountSelf, PermChatHandle},
+}
+
+// DefaultRole returns the role a principal gets when none has been assigned to it.
+func DefaultRole(principal string) string {
+	switch principal {
+	case AdminPrincipal:
+		return AdminRole
+	case CSEPrincipal:
+		return CSERole
+	default:
+		return CustomerRole
+	}
+}
+
+// IsPermission reports whether p is a permission routes can require.
+func IsPermission(p string) bool {
+	for _, known := range AllPermissions {
+		if known == p {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAPIKeyScope reports whether an API key may be granted permission p.
+func IsAPIKeyScope(p string) bool {
+	for _, scope := range APIKeyScopes {
+		if scope == p {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPermission reports whether the claims grant permission p. Authorisation replaces the
+// permissions a token was issued with by those its role grants at the time of the request.
+func (c *GoAppClaims) HasPermission(p string) bool {
+	for _, granted := range c.Permissions {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/database/query"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// negativeCacheTTL bounds how long a "not revoked" answer is trusted before the
+// denylist collection is consulted again, so revocations made by other instances
+// are picked up quickly.
+const negativeCacheTTL = 30 * time.Second
+
+// Denylist records revoked tokens in the revoked_tokens collection, whose TTL index drops
+// entries once the tokens they refer to have expired anyway, and caches lookups in memory.
+type Denylist struct {
+	collection *mongo.Collection
+
+	mu       sync.RWMutex
+	tokens   map[string]time.Time
+	subjects map[primitive.ObjectID]time.Time
+	checked  map[string]time.Time
+}
+
+type revokedToken struct {
+	ID            string             `bson:"_id"`
+	Kind          string             `bson:"kind"`
+	JTI           string             `bson:"jti,omitempty"`
+	Subject       primitive.ObjectID `bson:"subject"`
+	RevokedBefore time.Time          `bson:"revoked_before,omitempty"`
+	ExpiresAt     time.Time          `bson:"expires_at"`
+}
+
+var denylist *Denylist
+
+// InitDenylist connects the package to the revoked_tokens collection and makes sure its TTL index exists.
+func InitDenylist(client *mongo.Client) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	collection := query.User(client, "revoked_tokens")
+
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return err
+	}
+
+	denylist = &Denylist{
+		collection: collection,
+		tokens:     map[string]time.Time{},
+		subjects:   map[primitive.ObjectID]time.Time{},
+		checked:    map[string]time.Time{},
+	}
+
+	return nil
+}
+
+// Revoke denylists the token the claims were parsed from until it expires.
+func Revoke(claims *GoAppClaims) error {
+	if denylist == nil || claims.RegisteredClaims.ID == "" {
+		return nil
+	}
+
+	expiresAt := time.Now().Add(RefreshTokenLifetime)
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	entry := revokedToken{
+		ID:        "jti:" + claims.RegisteredClaims.ID,
+		Kind:      "token",
+		JTI:       claims.RegisteredClaims.ID,
+		Subject:   claims.ID,
+		ExpiresAt: expiresAt,
+	}
+
+	_, err := denylist.collection.ReplaceOne(ctx, bson.D{{Key: "_id", Value: entry.ID}}, entry, options.Replace().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+
+	denylist.mu.Lock()
+	denylist.tokens[entry.JTI] = expiresAt
+	denylist.mu.Unlock()
+
+	return nil
+}
+
+// RevokeAll invalidates every token issued to subject up to now.
+func RevokeAll(subject primitive.ObjectID) error {
+	if denylist == nil {
+		return nil
+	}
+
+	// Token timestamps have second precision, so round up to cover tokens issued earlier in this second.
+	revokedBefore := time.Now().Truncate(time.Second).Add(time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	entry := revokedToken{
+		ID:            "subject:" + subject.Hex(),
+		Kind:          "subject",
+		Subject:       subject,
+		RevokedBefore: revokedBefore,
+		ExpiresAt:     revokedBefore.Add(RefreshTokenLifetime),
+	}
+
+	_, err := denylist.collection.ReplaceOne(ctx, bson.D{{Key: "_id", Value: entry.ID}}, entry, options.Replace().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+
+	denylist.mu.Lock()
+	denylist.subjects[subject] = revokedBefore
+	denylist.mu.Unlock()
+
+	return nil
+}
+
+// IsRevoked reports whether the token the claims were parsed from has been revoked,
+// either on its own or through a revocation of every token of its subject.
+func IsRevoked(claims *GoAppClaims) (bool, error) {
+	if denylist == nil {
+		return false, nil
+	}
+
+	jti := claims.RegisteredClaims.ID
+	now := time.Now()
+
+	denylist.mu.RLock()
+	_, tokenRevoked := denylist.tokens[jti]
+	revokedBefore, subjectRevoked := denylist.subjects[claims.ID]
+	checkedAt, checked := denylist.checked[jti]
+	denylist.mu.RUnlock()
+
+	if tokenRevoked || (subjectRevoked && issuedBefore(claims, revokedBefore)) {
+		return true, nil
+	}
+
+	if checked && now.Sub(checkedAt) < negativeCacheTTL {
+		return false, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: bson.A{"jti:" + jti, "subject:" + claims.ID.Hex()}}}}}
+
+	cursor, err := denylist.collection.Find(ctx, filter)
+	if err != nil {
+		return false, err
+	}
+
+	var entries []revokedToken
+	if err = cursor.All(ctx, &entries); err != nil {
+		return false, err
+	}
+
+	revoked := false
+
+	denylist.mu.Lock()
+	defer denylist.mu.Unlock()
+
+	for _, entry := range entries {
+		switch entry.Kind {
+		case "token":
+			denylist.tokens[entry.JTI] = entry.ExpiresAt
+			revoked = true
+		case "subject":
+			denylist.subjects[entry.Subject] = entry.RevokedBefore
+			if issuedBefore(claims, entry.RevokedBefore) {
+				revoked = true
+			}
+		}
+	}
+
+	denylist.checked[jti] = now
+	denylist.prune(now)
+
+	return revoked, nil
+}
+
+func issuedBefore(claims *GoAppClaims, cutoff time.Time) bool {
+	return claims.IssuedAt == nil || claims.IssuedAt.Time.Before(cutoff)
+}
+
+// prune drops cache entries that can no longer matter. Callers must hold mu.
+func (d *Denylist) prune(now time.Time) {
+	for jti, expiresAt := range d.tokens {
+		if now.After(expiresAt) {
+			delete(d.tokens, jti)
+		}
+	}
+	for subject, revokedBefore := range d.subjects {
+		if now.After(revokedBefore.Add(RefreshTokenLifetime)) {
+			delete(d.subjects, subject)
+		}
+	}
+	for jti, checkedAt := range d.checked {
+		if now.Sub(checkedAt) >= negativeCacheTTL {
+			delete(d.checked, jti)
+		}
+	}
+}
@@ -3,6 +3,10 @@ package config
 import (
 	"log"
 
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/mailer"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/oidc"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/pricing"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/search"
 	"github.com/go-playground/validator/v10"
 )
 
@@ -10,4 +14,10 @@ type GoAppTools struct {
 	ErrorLogger *log.Logger
 	InfoLogger  *log.Logger
 	Validate    *validator.Validate
+	Mailer      mailer.Mailer
+	BaseURL     string
+	Cookies     CookieConfig
+	OIDC        map[string]*oidc.Provider
+	Search      *search.Index
+	Pricing     *pricing.Engine
 }
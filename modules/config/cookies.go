@@ -0,0 +1,132 @@
+package config
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Environments APP_ENV may name. Anything but development is treated as production.
+const (
+	Development = "development"
+	Production  = "production"
+)
+
+// minAuthKeyLength is the shortest session authentication key accepted in production.
+const minAuthKeyLength = 32
+
+// CookieConfig holds the secrets and attributes of every cookie the app sets.
+type CookieConfig struct {
+	// KeyPairs are the session keys as authentication/encryption pairs, the current pair first.
+	// Older pairs only verify cookies issued before the keys were rotated.
+	KeyPairs [][]byte
+	Domain   string
+	Secure   bool
+	SameSite http.SameSite
+	MaxAge   int
+}
+
+// CookiesFromEnv reads the cookie configuration.
+//
+// SESSION_SECRETS lists comma separated key pairs, newest first, each written as
+// "authentication-key" or "authentication-key:encryption-key". Encryption keys must be 16, 24
+// or 32 bytes long. APP_ENV selects the defaults: production cookies are Secure and SameSite=Lax,
+// development cookies are not Secure so they work over plain http on localhost. COOKIE_SECURE,
+// COOKIE_SAMESITE (lax, strict or none), COOKIE_DOMAIN and COOKIE_MAX_AGE override them.
+func CookiesFromEnv() (CookieConfig, error) {
+	env := Environment()
+
+	c := CookieConfig{
+		Domain:   os.Getenv("COOKIE_DOMAIN"),
+		Secure:   env != Development,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   7 * 24 * 60 * 60,
+	}
+
+	if v := os.Getenv("COOKIE_SECURE"); v != "" {
+		secure, err := strconv.ParseBool(v)
+		if err != nil {
+			return c, fmt.Errorf("invalid COOKIE_SECURE %q", v)
+		}
+		c.Secure = secure
+	}
+
+	switch strings.ToLower(os.Getenv("COOKIE_SAMESITE")) {
+	case "", "lax":
+	case "strict":
+		c.SameSite = http.SameSiteStrictMode
+	case "none":
+		c.SameSite = http.SameSiteNoneMode
+	default:
+		return c, fmt.Errorf("invalid COOKIE_SAMESITE %q", os.Getenv("COOKIE_SAMESITE"))
+	}
+
+	if c.SameSite == http.SameSiteNoneMode && !c.Secure {
+		return c, errors.New("COOKIE_SAMESITE none requires secure cookies")
+	}
+
+	if v := os.Getenv("COOKIE_MAX_AGE"); v != "" {
+		maxAge, err := strconv.Atoi(v)
+		if err != nil || maxAge < 0 {
+			return c, fmt.Errorf("invalid COOKIE_MAX_AGE %q", v)
+		}
+		c.MaxAge = maxAge
+	}
+
+	keyPairs, err := parseKeyPairs(os.Getenv("SESSION_SECRETS"), env)
+	if err != nil {
+		return c, err
+	}
+	c.KeyPairs = keyPairs
+
+	return c, nil
+}
+
+// Environment returns APP_ENV, defaulting to production so a missing setting fails safe.
+func Environment() string {
+	if env := strings.ToLower(os.Getenv("APP_ENV")); env == Development {
+		return Development
+	}
+	return Production
+}
+
+func parseKeyPairs(secrets string, env string) ([][]byte, error) {
+	if strings.TrimSpace(secrets) == "" {
+		if env != Development {
+			return nil, errors.New("SESSION_SECRETS must be set outside development")
+		}
+		key := make([]byte, 64)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		return [][]byte{key, nil}, nil
+	}
+
+	var keyPairs [][]byte
+
+	for i, entry := range strings.Split(secrets, ",") {
+		authKey, encKey, _ := strings.Cut(strings.TrimSpace(entry), ":")
+
+		if authKey == "" {
+			return nil, fmt.Errorf("SESSION_SECRETS entry %d has no authentication key", i+1)
+		}
+		if env != Development && len(authKey) < minAuthKeyLength {
+			return nil, fmt.Errorf("SESSION_SECRETS entry %d: authentication key must be at least %d bytes", i+1, minAuthKeyLength)
+		}
+
+		switch len(encKey) {
+		case 0:
+			keyPairs = append(keyPairs, []byte(authKey), nil)
+		case 16, 24, 32:
+			keyPairs = append(keyPairs, []byte(authKey), []byte(encKey))
+		default:
+			return nil, fmt.Errorf("SESSION_SECRETS entry %d: encryption key must be 16, 24 or 32 bytes", i+1)
+		}
+	}
+
+	return keyPairs, nil
+}
@@ -1,26 +1,52 @@
 package database
 
 import (
+	"time"
+
 	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// DBRepo is the storage of the app. Its methods report failures as domain errors, e.g. a
+// missing document as domain.ErrNotFound, so handlers can answer with the right status.
 type DBRepo interface {
 	InsertUser(user *model.User) (bool, int, error)
 	VerifyUser(email string) (primitive.M, error)
 	UpdateUser(userID primitive.ObjectID, tk map[string]string) (bool, error)
-	CreateNewPassword(email string, password string) (bool, error)
 	InsertProduct(product *model.Product) (bool, int, error)
 	Update_Stock(id primitive.ObjectID, new_stock int) (bool, error)
-	ViewProducts() ([]primitive.M, error)
+	ViewProducts(q model.ProductQuery) (*model.ProductPage, error)
+	GetAllProducts() ([]model.Product, error)
+	GetProductsByIDs(ids []primitive.ObjectID) ([]primitive.M, error)
+	GetProduct(id primitive.ObjectID) (model.Product, error)
+	GetProducts(ids []primitive.ObjectID) ([]model.Product, error)
+	SaveComparison(comparison *model.Comparison) error
+	GetComparison(id primitive.ObjectID) (model.Comparison, error)
+	MigrateSpecs() error
+	MigrateEmails() error
+	GetProductsToReprice() ([]model.Product, error)
+	SetProductPrice(product *model.Product, change *model.PriceChange) (bool, error)
+	GetPriceHistory(productID primitive.ObjectID, limit int64) ([]model.PriceChange, error)
+	GetCart(userID primitive.ObjectID) ([]model.CartItems, error)
+	AddVariant(productID primitive.ObjectID, variant *model.Variant) error
+	UpdateVariant(productID primitive.ObjectID, variantID primitive.ObjectID, changes model.VariantChanges) (*model.Variant, error)
+	DeleteVariant(productID primitive.ObjectID, variantID primitive.ObjectID) error
 	CreateCategory(category *model.Category) (bool, int, error)
+	GetCategory(id primitive.ObjectID) (model.Category, error)
+	UpdateCategory(id primitive.ObjectID, changes model.CategoryChanges) (model.Category, error)
+	MoveCategory(id primitive.ObjectID, parentID *primitive.ObjectID) (model.Category, error)
+	DeleteCategory(id primitive.ObjectID, reassignTo *primitive.ObjectID) error
+	CountProductsByCategory() (map[primitive.ObjectID]int64, error)
+	GetProductsByCategory(id primitive.ObjectID) ([]model.Product, error)
+	MigrateCategories() error
 	SignUpAdmin(admin *model.Admin) (bool, int, error)
 	VerifyAdmin(email string) (primitive.M, error)
 	UpdateAdmin(userID primitive.ObjectID, tk map[string]string) (bool, error)
+	UpdateCSE(cseID primitive.ObjectID, tk map[string]string) (bool, error)
+	FindPrincipalByEmail(principal string, email string) (primitive.M, error)
+	GetPrincipalByID(principal string, id primitive.ObjectID) (primitive.M, error)
 	SignOutAdmin(adminID primitive.ObjectID) (bool, error)
 	SignOutUser(userID primitive.ObjectID) (bool, error)
-	CreateNewPasswordAdmin(email string, password string) (bool, error)
-	UpdateEmailUser(current_email string, new_email string) (bool, error)
 	UpdateEmailAdmin(current_email string, new_email string) (bool, error)
 	UpdateNameUser(email string, new_name string) (bool, error)
 	UpdateNameAdmin(email string, new_name string) (bool, error)
@@ -33,7 +59,7 @@ type DBRepo interface {
 	GetSingleProduct(Id primitive.ObjectID) (primitive.M, error)
 	AddToCart(userID primitive.ObjectID, cartItems *model.CartItems) (bool, error)
 	Empty_the_Cart(userID primitive.ObjectID) (bool, error)
-	RemoveFromCart(userID primitive.ObjectID, productID primitive.ObjectID) (bool, error)
+	RemoveFromCart(userID primitive.ObjectID, productID primitive.ObjectID, variantID primitive.ObjectID) (bool, error)
 	GetAllUsers() ([]primitive.M, error)
 	InitializeUser(userId primitive.ObjectID) (bool, error)
 	CreateOrder(order *model.Order) (primitive.M, error)
@@ -46,9 +72,11 @@ type DBRepo interface {
 	PaymentCreation(payment *model.Payment) (primitive.M, error)
 	GetAllShipments() ([]primitive.M, error)
 	UpdatePaymentToIncludeOrderId(paymentId primitive.ObjectID, orderId primitive.ObjectID) (bool, error)
+	GetOrder(id primitive.ObjectID) (model.Order, error)
+	GetPayment(id primitive.ObjectID) (model.Payment, error)
 	AddAddress(userId primitive.ObjectID, address *model.Address) (bool, error)
 	GetAllPayments() ([]primitive.M, error)
-	GetAllCategories() ([]primitive.M, error)
+	GetAllCategories() ([]model.Category, error)
 	GetUserByID(userId primitive.ObjectID) (primitive.M, error)
 	GetUserOrders(userId primitive.ObjectID) ([]primitive.M, error)
 	InsertMultipleProductsBulk(products []*model.Product) (int, int, error)
@@ -77,4 +105,59 @@ type DBRepo interface {
 	UpdateOrderWithRated(orderID primitive.ObjectID) error
 	//DeleteReview(reviewID primitive.ObjectID) error
 	UpdateProductSummarizedReview(productID primitive.ObjectID, summarizedReview string) error
+	CreateRole(role *model.Role) (bool, int, error)
+	GetRole(name string) (model.Role, error)
+	GetAllRoles() ([]model.Role, error)
+	UpdateRolePermissions(name string, permissions []string) (bool, error)
+	DeleteRole(name string) (int, error)
+	AssignRoleToAdmin(adminID primitive.ObjectID, role string) (bool, error)
+	CountAdmins() (int64, error)
+	CreateAdminInvite(invite *model.AdminInvite) error
+	RedeemAdminInvite(inviteID primitive.ObjectID, email string) (*model.AdminInvite, error)
+	ReleaseAdminInvite(inviteID primitive.ObjectID) error
+	EnsureIndexes() error
+	CreatePasswordReset(reset *model.PasswordReset) error
+	GetPasswordReset(principal string, email string) (*model.PasswordReset, error)
+	RecordPasswordResetAttempt(resetID primitive.ObjectID) error
+	ConsumePasswordReset(resetID primitive.ObjectID) (bool, error)
+	ResetPassword(principal string, id primitive.ObjectID, hashedPassword string) (bool, error)
+	UpdatePasswordHash(principal string, id primitive.ObjectID, oldHash string, newHash string) (bool, error)
+	MarkEmailVerified(userID primitive.ObjectID, email string) (bool, error)
+	SetPendingEmail(userID primitive.ObjectID, email string) error
+	ConfirmPendingEmail(userID primitive.ObjectID, email string) (bool, error)
+	GetEmailVerification(userID primitive.ObjectID) (primitive.M, error)
+	SetPendingTOTPSecret(principal string, id primitive.ObjectID, secret string) error
+	EnableTOTP(principal string, id primitive.ObjectID, secret string, hashedRecoveryCodes []string, step int64) (bool, error)
+	DisableTOTP(principal string, id primitive.ObjectID) error
+	RecordTOTPStep(principal string, id primitive.ObjectID, step int64) (bool, error)
+	ConsumeRecoveryCode(principal string, id primitive.ObjectID, hashedCode string) (bool, error)
+	StartMFAChallenge(principal string, id primitive.ObjectID, jti string) error
+	RecordMFAFailure(principal string, id primitive.ObjectID, jti string) error
+	CompleteMFAChallenge(principal string, id primitive.ObjectID, jti string) (bool, error)
+	GetSecuritySettings() (model.SecuritySettings, error)
+	UpdateSecuritySettings(settings *model.SecuritySettings) error
+	GetLoginAttempts(keys []string) ([]model.LoginAttempt, error)
+	RecordLoginFailure(attempt *model.LoginAttempt, window time.Duration) (model.LoginAttempt, error)
+	LockLogin(key string, lockedUntil time.Time, window time.Duration) error
+	ClearLoginAttempts(key string) (bool, error)
+	RecordSecurityEvent(event *model.SecurityEvent) error
+	GetSecurityEvents(limit int64) ([]model.SecurityEvent, error)
+	CreateLoginSession(session *model.LoginSession) error
+	GetLoginSession(id primitive.ObjectID) (model.LoginSession, error)
+	ListLoginSessions(principal string, subjectID primitive.ObjectID) ([]model.LoginSession, error)
+	RotateSessionRefresh(id primitive.ObjectID, oldHash string, newHash string, expiresAt time.Time) (bool, error)
+	TouchLoginSession(id primitive.ObjectID, ip string, now time.Time) (bool, error)
+	RevokeLoginSession(principal string, subjectID primitive.ObjectID, id primitive.ObjectID) error
+	RevokeLoginSessions(principal string, subjectID primitive.ObjectID) (int64, error)
+	CreateOIDCLogin(login *model.OIDCLogin) error
+	ConsumeOIDCLogin(id string) (model.OIDCLogin, error)
+	FindUserByIdentity(provider string, subject string) (primitive.M, error)
+	LinkIdentity(userID primitive.ObjectID, identity *model.Identity) (bool, error)
+	CreateAPIKey(key *model.APIKey) error
+	GetAPIKeyByPrefix(prefix string) (model.APIKey, error)
+	ListAPIKeys() ([]model.APIKey, error)
+	RevokeAPIKey(id primitive.ObjectID) error
+	TouchAPIKey(id primitive.ObjectID, ip string, now time.Time) error
+	RecordAPIKeyCall(call *model.APIKeyCall) error
+	GetAPIKeyCalls(keyID primitive.ObjectID, limit int64) ([]model.APIKeyCall, error)
 }
@@ -0,0 +1,83 @@
+package query
+
+import (
+	"context"
+	"time"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (g *GoAppDB) CountAdmins() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	count, err := User(g.DB, "admin").CountDocuments(ctx, bson.D{})
+	if err != nil {
+		g.App.ErrorLogger.Printf("Error counting admins: %v", err)
+		return 0, dbError(err, "admin")
+	}
+
+	return count, nil
+}
+
+func (g *GoAppDB) CreateAdminInvite(invite *model.AdminInvite) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	invite.ID = primitive.NewObjectID()
+
+	_, err := User(g.DB, "admin_invites").InsertOne(ctx, invite)
+	if err != nil {
+		g.App.ErrorLogger.Printf("Error creating admin invite: %v", err)
+		return dbError(err, "admin invite")
+	}
+
+	return nil
+}
+
+// RedeemAdminInvite marks an unused, unexpired invite for email as used and returns it.
+// It returns mongo.ErrNoDocuments when no such invite exists, so an invite can only be redeemed once.
+func (g *GoAppDB) RedeemAdminInvite(inviteID primitive.ObjectID, email string) (*model.AdminInvite, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	now := time.Now()
+
+	filter := bson.D{
+		{Key: "_id", Value: inviteID},
+		{Key: "email", Value: email},
+		{Key: "used_at", Value: nil},
+		{Key: "expires_at", Value: bson.D{{Key: "$gt", Value: now}}},
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "used_at", Value: now}}}}
+
+	var invite model.AdminInvite
+
+	err := User(g.DB, "admin_invites").FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&invite)
+	if err != nil {
+		g.App.ErrorLogger.Printf("Error redeeming admin invite %s: %v", inviteID.Hex(), err)
+		return nil, dbError(err, "admin invite")
+	}
+
+	return &invite, nil
+}
+
+// ReleaseAdminInvite makes a redeemed invite usable again, for when the sign up it was redeemed for failed.
+func (g *GoAppDB) ReleaseAdminInvite(inviteID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := bson.D{{Key: "_id", Value: inviteID}}
+	update := bson.D{{Key: "$unset", Value: bson.D{{Key: "used_at", Value: ""}}}}
+
+	_, err := User(g.DB, "admin_invites").UpdateOne(ctx, filter, update)
+	if err != nil {
+		g.App.ErrorLogger.Printf("Error releasing admin invite %s: %v", inviteID.Hex(), err)
+		return dbError(err, "admin invite")
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package query
+
+import (
+	"context"
+	"time"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/domain"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (g *GoAppDB) CreateAPIKey(key *model.APIKey) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	key.ID = primitive.NewObjectID()
+
+	_, err := User(g.DB, "api_keys").InsertOne(ctx, key)
+	if err != nil {
+		g.App.ErrorLogger.Printf("Error creating API key: %v", err)
+		return dbError(err, "API key")
+	}
+
+	return nil
+}
+
+// GetAPIKeyByPrefix returns the key with the given prefix, revoked and expired ones included.
+func (g *GoAppDB) GetAPIKeyByPrefix(prefix string) (model.APIKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	var key model.APIKey
+
+	err := User(g.DB, "api_keys").FindOne(ctx, bson.D{{Key: "prefix", Value: prefix}}).Decode(&key)
+	if err != nil {
+		return key, dbError(err, "API key")
+	}
+
+	return key, nil
+}
+
+// ListAPIKeys returns every key, newest first.
+func (g *GoAppDB) ListAPIKeys() ([]model.APIKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := User(g.DB, "api_keys").Find(ctx, bson.D{}, opts)
+	if err != nil {
+		g.App.ErrorLogger.Printf("Error fetching API keys: %v", err)
+		return nil, dbError(err, "API key")
+	}
+
+	keys := []model.APIKey{}
+	if err = cursor.All(ctx, &keys); err != nil {
+		g.App.ErrorLogger.Printf("Error decoding API keys: %v", err)
+		return nil, dbError(err, "API key")
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey stops a key from being accepted. Revoking a revoked key is reported as not found.
+func (g *GoAppDB) RevokeAPIKey(id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := bson.D{
+		{Key: "_id", Value: id},
+		{Key: "revoked_at", Value: bson.D{{Key: "$exists", Value: false}}},
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "revoked_at", Value: time.Now()}}}}
+
+	result, err := User(g.DB, "api_keys").UpdateOne(ctx, filter, update)
+	if err != nil {
+		g.App.ErrorLogger.Printf("Error revoking API key: %v", err)
+		return dbError(err, "API key")
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.NotFound("API key not found")
+	}
+
+	return nil
+}
+
+// TouchAPIKey records when and from where a key was last used.
+func (g *GoAppDB) TouchAPIKey(id primitive.ObjectID, ip string, now time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "last_used_at", Value: now},
+		{Key: "last_used_ip", Value: ip},
+	}}}
+
+	_, err := User(g.DB, "api_keys").UpdateOne(ctx, bson.D{{Key: "_id", Value: id}}, update)
+	if err != nil {
+		g.App.ErrorLogger.Printf("Error updating API key usage: %v", err)
+		return dbError(err, "API key")
+	}
+
+	return nil
+}
+
+func (g *GoAppDB) RecordAPIKeyCall(call *model.APIKeyCall) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	call.ID = primitive.NewObjectID()
+
+	_, err := User(g.DB, "api_key_calls").InsertOne(ctx, call)
+	if err != nil {
+		g.App.ErrorLogger.Printf("Error recording API key call: %v", err)
+		return dbError(err, "API key call")
+	}
+
+	return nil
+}
+
+// GetAPIKeyCalls returns the latest calls made with a key, newest first.
+func (g *GoAppDB) GetAPIKeyCalls(keyID primitive.ObjectID, limit int64) ([]model.APIKeyCall, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit)
+
+	cursor, err := User(g.DB, "api_key_calls").Find(ctx, bson.D{{Key: "key_id", Value: keyID}}, opts)
+	if err != nil {
+		g.App.ErrorLogger.Printf("Error fetching API key calls: %v", err)
+		return nil, dbError(err, "API key call")
+	}
+
+	calls := []model.APIKeyCall{}
+	if err = cursor.All(ctx, &calls); err != nil {
+		g.App.ErrorLogger.Printf("Error decoding API key calls: %v", err)
+		return nil, dbError(err, "API key call")
+	}
+
+	return calls, nil
+}
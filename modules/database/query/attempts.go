@@ -0,0 +1,147 @@
+package query
+
+import (
+	"context"
+	"time"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetLoginAttempts returns the failure counters stored under the given keys.
+func (g *GoAppDB) GetLoginAttempts(keys []string) ([]model.LoginAttempt, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: keys}}}}
+
+	cursor, err := User(g.DB, "login_attempts").Find(ctx, filter)
+	if err != nil {
+		g.App.ErrorLogger.Printf("Error fetching login attempts: %v", err)
+		return nil, dbError(err, "login attempt")
+	}
+
+	var attempts []model.LoginAttempt
+	if err = cursor.All(ctx, &attempts); err != nil {
+		g.App.ErrorLogger.Printf("Error decoding login attempts: %v", err)
+		return nil, dbError(err, "login attempt")
+	}
+
+	return attempts, nil
+}
+
+// RecordLoginFailure counts a failed sign in against attempt.ID and returns the updated counter.
+// Failures older than window, counted from the last failure or the end of the last lockout, are forgotten.
+func (g *GoAppDB) RecordLoginFailure(attempt *model.LoginAttempt, window time.Duration) (model.LoginAttempt, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	cutoff := now.Add(-window)
+
+	recent := bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "$gt", Value: bson.A{"$last_failure", cutoff}}},
+		bson.D{{Key: "$gt", Value: bson.A{"$locked_until", cutoff}}},
+	}}}
+
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.D{
+			{Key: "kind", Value: attempt.Kind},
+			{Key: "principal", Value: attempt.Principal},
+			{Key: "identifier", Value: attempt.Identifier},
+			{Key: "failures", Value: bson.D{{Key: "$cond", Value: bson.A{
+				recent,
+				bson.D{{Key: "$add", Value: bson.A{bson.D{{Key: "$ifNull", Value: bson.A{"$failures", 0}}}, 1}}},
+				1,
+			}}}},
+			{Key: "last_failure", Value: now},
+			{Key: "expires_at", Value: now.Add(window)},
+		}}},
+	}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var updated model.LoginAttempt
+
+	err := User(g.DB, "login_attempts").FindOneAndUpdate(ctx, bson.D{{Key: "_id", Value: attempt.ID}}, update, opts).Decode(&updated)
+	if err != nil {
+		g.App.ErrorLogger.Printf("Error recording login failure: %v", err)
+		return updated, dbError(err, "login attempt")
+	}
+
+	return updated, nil
+}
+
+// LockLogin blocks sign ins for the counter's key until lockedUntil. The counter is kept for window
+// after that, so the next failure locks for longer.
+func (g *GoAppDB) LockLogin(key string, lockedUntil time.Time, window time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "locked_until", Value: lockedUntil},
+		{Key: "expires_at", Value: lockedUntil.Add(window)},
+	}}}
+
+	_, err := User(g.DB, "login_attempts").UpdateOne(ctx, bson.D{{Key: "_id", Value: key}}, update)
+	if err != nil {
+		g.App.ErrorLogger.Printf("Error locking login: %v", err)
+		return dbError(err, "login attempt")
+	}
+
+	return nil
+}
+
+// ClearLoginAttempts forgets the failures counted under key. It reports false when there were none.
+func (g *GoAppDB) ClearLoginAttempts(key string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	result, err := User(g.DB, "login_attempts").DeleteOne(ctx, bson.D{{Key: "_id", Value: key}})
+	if err != nil {
+		g.App.ErrorLogger.Printf("Error clearing login attempts: %v", err)
+		return false, dbError(err, "login attempt")
+	}
+
+	return result.DeletedCount == 1, nil
+}
+
+func (g *GoAppDB) RecordSecurityEvent(event *model.SecurityEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	event.ID = primitive.NewObjectID()
+
+	_, err := User(g.DB, "security_events").InsertOne(ctx, event)
+	if err != nil {
+		g.App.ErrorLogger.Printf("Error recording security event: %v", err)
+		return dbError(err, "security event")
+	}
+
+	return nil
+}
+
+// GetSecurityEvents returns the most recent security events, newest first.
+func (g *GoAppDB) GetSecurityEvents(limit int64) ([]model.SecurityEvent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit)
+
+	cursor, err := User(g.DB, "security_events").Find(ctx, bson.D{}, opts)
+	if err != nil {
+		g.App.ErrorLogger.Printf("Error fetching security events: %v", err)
+		return nil, dbError(err, "security event")
+	}
+
+	events := []model.SecurityEvent{}
+	if err = cursor.All(ctx, &events); err != nil {
+		g.App.ErrorLogger.Printf("Error decoding security events: %v", err)
+		return nil, dbError(err, "security event")
+	}
+
+	return events, nil
+}
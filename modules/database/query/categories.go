@@ -0,0 +1,513 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/domain"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// slugify turns a name into the lower case, dash separated form used in category paths.
+func slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	return b.String()
+}
+
+// placeCategory sets the slug, ancestors and path of category for it to sit under parent, or at
+// the root when parent is nil.
+func placeCategory(category *model.Category, parent *model.Category) {
+	if parent == nil {
+		category.ParentID = nil
+		category.Ancestors = []primitive.ObjectID{}
+		category.Path = category.Slug
+		return
+	}
+
+	parentID := parent.ID
+	category.ParentID = &parentID
+	category.Ancestors = append(append([]primitive.ObjectID{}, parent.Ancestors...), parent.ID)
+	category.Path = parent.Path + "/" + category.Slug
+}
+
+// CreateCategory adds a category under its ParentID, or at the root. Status 2 means a category
+// with the same path already exists.
+func (g *GoAppDB) CreateCategory(category *model.Category) (bool, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	if category.Slug == "" {
+		category.Slug = category.Name
+	}
+	category.Slug = slugify(category.Slug)
+	if category.Slug == "" {
+		return false, 0, domain.Validation("category name must contain letters or digits")
+	}
+
+	var parent *model.Category
+	if category.ParentID != nil {
+		p, err := g.GetCategory(*category.ParentID)
+		if err != nil {
+			return false, 0, dbError(err, "parent category")
+		}
+		parent = &p
+	}
+	placeCategory(category, parent)
+
+	category.ID = primitive.NewObjectID()
+
+	_, err := User(g.DB, "category").InsertOne(ctx, category)
+	if mongo.IsDuplicateKeyError(err) {
+		return true, 2, nil
+	}
+	if err != nil {
+		g.App.ErrorLogger.Printf("cannot add category to the database : %v ", err)
+		return false, 0, dbError(err, "category")
+	}
+
+	return true, 1, nil
+}
+
+func (g *GoAppDB) GetCategory(id primitive.ObjectID) (model.Category, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	var category model.Category
+
+	err := User(g.DB, "category").FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Decode(&category)
+	if err != nil {
+		return category, dbError(err, "category")
+	}
+
+	return category, nil
+}
+
+// GetAllCategories returns every category ordered by path, so parents come before their children.
+func (g *GoAppDB) GetAllCategories() ([]model.Category, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "path", Value: 1}})
+
+	cursor, err := User(g.DB, "category").Find(ctx, bson.D{}, opts)
+	if err != nil {
+		g.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return nil, dbError(err, "category")
+	}
+
+	categories := []model.Category{}
+	if err = cursor.All(ctx, &categories); err != nil {
+		g.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return nil, dbError(err, "category")
+	}
+
+	return categories, nil
+}
+
+// CountProductsByCategory returns how many products each category holds directly, leaving out
+// those of its subcategories.
+func (g *GoAppDB) CountProductsByCategory() (map[primitive.ObjectID]int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$category_id"},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+	}
+
+	cursor, err := Product(g.DB, "product").Aggregate(ctx, pipeline)
+	if err != nil {
+		g.App.ErrorLogger.Printf("cannot count products per category : %v ", err)
+		return nil, dbError(err, "product")
+	}
+
+	var rows []struct {
+		ID    primitive.ObjectID `bson:"_id"`
+		Count int64              `bson:"count"`
+	}
+	if err = cursor.All(ctx, &rows); err != nil {
+		g.App.ErrorLogger.Printf("cannot count products per category : %v ", err)
+		return nil, dbError(err, "product")
+	}
+
+	counts := make(map[primitive.ObjectID]int64, len(rows))
+	for _, row := range rows {
+		counts[row.ID] = row.Count
+	}
+
+	return counts, nil
+}
+
+// categorySubtree returns the id of the category and of all its descendants.
+func (g *GoAppDB) categorySubtree(ctx context.Context, id primitive.ObjectID) ([]primitive.ObjectID, error) {
+	filter := bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "_id", Value: id}},
+		bson.D{{Key: "ancestors", Value: id}},
+	}}}
+
+	cursor, err := User(g.DB, "category").Find(ctx, filter, options.Find().SetProjection(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, dbError(err, "category")
+	}
+
+	var rows []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err = cursor.All(ctx, &rows); err != nil {
+		return nil, dbError(err, "category")
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.ID)
+	}
+	return ids, nil
+}
+
+// UpdateCategory applies changes to a category. A new slug changes the path of the category and
+// of its descendants, and a new name is copied to the products of the category. The changes are
+// checked before anything is written, and products only follow once the category itself changed.
+func (g *GoAppDB) UpdateCategory(id primitive.ObjectID, changes model.CategoryChanges) (model.Category, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	category, err := g.GetCategory(id)
+	if err != nil {
+		return category, err
+	}
+
+	moved := category
+	if changes.Slug != nil {
+		moved.Slug = slugify(*changes.Slug)
+		if moved.Slug == "" {
+			return category, domain.Validation("slug must contain letters or digits")
+		}
+	}
+
+	if moved.Slug != category.Slug {
+		var parent *model.Category
+		if category.ParentID != nil {
+			p, err := g.GetCategory(*category.ParentID)
+			if err != nil {
+				return category, dbError(err, "parent category")
+			}
+			parent = &p
+		}
+		placeCategory(&moved, parent)
+
+		taken, err := User(g.DB, "category").CountDocuments(ctx, bson.D{
+			{Key: "path", Value: moved.Path},
+			{Key: "_id", Value: bson.D{{Key: "$ne", Value: id}}},
+		})
+		if err != nil {
+			return category, dbError(err, "category")
+		}
+		if taken > 0 {
+			return category, domain.Conflict(fmt.Sprintf("a category already exists at %s", moved.Path))
+		}
+
+		if err := g.relocateCategory(ctx, category, &moved, parent); err != nil {
+			return category, err
+		}
+	}
+	category = moved
+
+	set := bson.D{{Key: "updatedat", Value: time.Now()}}
+
+	renamed := changes.Name != nil && *changes.Name != category.Name
+	if renamed {
+		category.Name = *changes.Name
+		set = append(set, bson.E{Key: "name", Value: category.Name})
+	}
+	if changes.General_Description != nil {
+		category.General_Description = *changes.General_Description
+		set = append(set, bson.E{Key: "general_description", Value: category.General_Description})
+	}
+	if changes.CategoryImage != nil {
+		category.CategoryImage = *changes.CategoryImage
+		set = append(set, bson.E{Key: "categoryimage", Value: category.CategoryImage})
+	}
+
+	_, err = User(g.DB, "category").UpdateOne(ctx, bson.D{{Key: "_id", Value: id}}, bson.D{{Key: "$set", Value: set}})
+	if err != nil {
+		g.App.ErrorLogger.Printf("cannot update category : %v ", err)
+		return category, dbError(err, "category")
+	}
+
+	if renamed {
+		_, err := Product(g.DB, "product").UpdateMany(ctx,
+			bson.D{{Key: "category_id", Value: id}},
+			bson.D{{Key: "$set", Value: bson.D{{Key: "category", Value: category.Name}}}})
+		if err != nil {
+			g.App.ErrorLogger.Printf("cannot rename the category of products : %v ", err)
+			return category, dbError(err, "product")
+		}
+	}
+
+	return category, nil
+}
+
+// MoveCategory puts a category and its subtree under parentID, or at the root when it is nil.
+func (g *GoAppDB) MoveCategory(id primitive.ObjectID, parentID *primitive.ObjectID) (model.Category, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	category, err := g.GetCategory(id)
+	if err != nil {
+		return category, err
+	}
+
+	var parent *model.Category
+	if parentID != nil {
+		p, err := g.GetCategory(*parentID)
+		if err != nil {
+			return category, dbError(err, "parent category")
+		}
+		if within(p, id) {
+			return category, domain.Validation("a category cannot be moved under itself or its subcategories")
+		}
+		parent = &p
+	}
+
+	moved := category
+	if err := g.relocateCategory(ctx, category, &moved, parent); err != nil {
+		return category, err
+	}
+
+	return moved, nil
+}
+
+// within reports whether category is the category id or one of its descendants.
+func within(category model.Category, id primitive.ObjectID) bool {
+	if category.ID == id {
+		return true
+	}
+	for _, ancestor := range category.Ancestors {
+		if ancestor == id {
+			return true
+		}
+	}
+	return false
+}
+
+// relocateCategory places moved under parent, moved being category with a possibly new slug, and
+// rewrites the ancestors and paths of all descendants to follow it.
+func (g *GoAppDB) relocateCategory(ctx context.Context, category model.Category, moved *model.Category, parent *model.Category) error {
+	placeCategory(moved, parent)
+
+	if moved.Path == category.Path && len(moved.Ancestors) == len(category.Ancestors) {
+		return nil
+	}
+
+	cursor, err := User(g.DB, "category").Find(ctx, bson.D{{Key: "ancestors", Value: category.ID}})
+	if err != nil {
+		return dbError(err, "category")
+	}
+	var descendants []model.Category
+	if err = cursor.All(ctx, &descendants); err != nil {
+		return dbError(err, "category")
+	}
+
+	now := time.Now()
+
+	writes := []mongo.WriteModel{
+		mongo.NewUpdateOneModel().
+			SetFilter(bson.D{{Key: "_id", Value: category.ID}}).
+			SetUpdate(bson.D{{Key: "$set", Value: bson.D{
+				{Key: "slug", Value: moved.Slug},
+				{Key: "parent_id", Value: moved.ParentID},
+				{Key: "ancestors", Value: moved.Ancestors},
+				{Key: "path", Value: moved.Path},
+				{Key: "updatedat", Value: now},
+			}}}),
+	}
+
+	depth := len(category.Ancestors) + 1
+	for _, d := range descendants {
+		ancestors := append(append(append([]primitive.ObjectID{}, moved.Ancestors...), category.ID), d.Ancestors[depth:]...)
+		path := moved.Path + strings.TrimPrefix(d.Path, category.Path)
+
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.D{{Key: "_id", Value: d.ID}}).
+			SetUpdate(bson.D{{Key: "$set", Value: bson.D{
+				{Key: "ancestors", Value: ancestors},
+				{Key: "path", Value: path},
+				{Key: "updatedat", Value: now},
+			}}}))
+	}
+
+	_, err = User(g.DB, "category").BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return domain.Conflict(fmt.Sprintf("a category already exists at %s", moved.Path))
+	}
+	if err != nil {
+		g.App.ErrorLogger.Printf("cannot move category : %v ", err)
+		return dbError(err, "category")
+	}
+
+	return nil
+}
+
+// DeleteCategory removes a category. One holding products or subcategories is only removed when
+// reassignTo names another category to take them over; otherwise it is kept and a conflict is
+// reported.
+func (g *GoAppDB) DeleteCategory(id primitive.ObjectID, reassignTo *primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	category, err := g.GetCategory(id)
+	if err != nil {
+		return err
+	}
+
+	products, err := Product(g.DB, "product").CountDocuments(ctx, bson.D{{Key: "category_id", Value: id}})
+	if err != nil {
+		return dbError(err, "product")
+	}
+
+	cursor, err := User(g.DB, "category").Find(ctx, bson.D{{Key: "parent_id", Value: id}})
+	if err != nil {
+		return dbError(err, "category")
+	}
+	var children []model.Category
+	if err = cursor.All(ctx, &children); err != nil {
+		return dbError(err, "category")
+	}
+
+	if products > 0 || len(children) > 0 {
+		if reassignTo == nil {
+			return domain.Conflict(fmt.Sprintf(
+				"category has %d products and %d subcategories, reassign them to another category first", products, len(children)))
+		}
+
+		target, err := g.GetCategory(*reassignTo)
+		if err != nil {
+			return dbError(err, "target category")
+		}
+		if within(target, id) {
+			return domain.Validation("products cannot be reassigned to the deleted category or its subcategories")
+		}
+
+		for _, child := range children {
+			moved := child
+			if err := g.relocateCategory(ctx, child, &moved, &target); err != nil {
+				return err
+			}
+		}
+
+		_, err = Product(g.DB, "product").UpdateMany(ctx,
+			bson.D{{Key: "category_id", Value: id}},
+			bson.D{{Key: "$set", Value: bson.D{
+				{Key: "category_id", Value: target.ID},
+				{Key: "category", Value: target.Name},
+				{Key: "updatedat", Value: time.Now()},
+			}}})
+		if err != nil {
+			g.App.ErrorLogger.Printf("cannot reassign products of category %s : %v ", category.Path, err)
+			return dbError(err, "product")
+		}
+	}
+
+	_, err = User(g.DB, "category").DeleteOne(ctx, bson.D{{Key: "_id", Value: id}})
+	if err != nil {
+		g.App.ErrorLogger.Printf("cannot delete category : %v ", err)
+		return dbError(err, "category")
+	}
+
+	return nil
+}
+
+// GetProductsByCategory returns the products filed directly under a category.
+func (g *GoAppDB) GetProductsByCategory(id primitive.ObjectID) ([]model.Product, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	cursor, err := Product(g.DB, "product").Find(ctx, bson.D{{Key: "category_id", Value: id}})
+	if err != nil {
+		return nil, dbError(err, "product")
+	}
+
+	products := []model.Product{}
+	if err = cursor.All(ctx, &products); err != nil {
+		return nil, dbError(err, "product")
+	}
+
+	return products, nil
+}
+
+// MigrateCategories brings data written before categories formed a tree up to date: categories
+// without a path become roots, and products get the id of the category they name.
+func (g *GoAppDB) MigrateCategories() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	categories, err := g.GetAllCategories()
+	if err != nil {
+		return err
+	}
+
+	taken := map[string]bool{}
+	for _, c := range categories {
+		if c.Path != "" {
+			taken[c.Path] = true
+		}
+	}
+
+	for _, c := range categories {
+		if c.Path == "" {
+			c.Slug = slugify(c.Name)
+			if c.Slug == "" {
+				c.Slug = c.ID.Hex()
+			}
+			for n := 2; taken[c.Slug]; n++ {
+				c.Slug = fmt.Sprintf("%s-%d", slugify(c.Name), n)
+			}
+			taken[c.Slug] = true
+			placeCategory(&c, nil)
+
+			_, err := User(g.DB, "category").UpdateOne(ctx, bson.D{{Key: "_id", Value: c.ID}},
+				bson.D{{Key: "$set", Value: bson.D{
+					{Key: "slug", Value: c.Slug},
+					{Key: "parent_id", Value: nil},
+					{Key: "ancestors", Value: c.Ancestors},
+					{Key: "path", Value: c.Path},
+				}}})
+			if err != nil {
+				return dbError(err, "category")
+			}
+		}
+
+		_, err := Product(g.DB, "product").UpdateMany(ctx,
+			bson.D{
+				{Key: "category", Value: c.Name},
+				{Key: "category_id", Value: bson.D{{Key: "$exists", Value: false}}},
+			},
+			bson.D{{Key: "$set", Value: bson.D{{Key: "category_id", Value: c.ID}}}})
+		if err != nil {
+			return dbError(err, "product")
+		}
+	}
+
+	return nil
+}
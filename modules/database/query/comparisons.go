@@ -0,0 +1,42 @@
+package query
+
+import (
+	"context"
+	"time"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SaveComparison stores a comparison so it can be opened again from its link until it expires.
+func (g *GoAppDB) SaveComparison(comparison *model.Comparison) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	comparison.ID = primitive.NewObjectID()
+	comparison.CreatedAt = time.Now()
+
+	if _, err := User(g.DB, "comparisons").InsertOne(ctx, comparison); err != nil {
+		g.App.ErrorLogger.Printf("cannot save comparison : %v ", err)
+		return dbError(err, "comparison")
+	}
+	return nil
+}
+
+// GetComparison returns a saved comparison that has not expired.
+func (g *GoAppDB) GetComparison(id primitive.ObjectID) (model.Comparison, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := bson.D{
+		{Key: "_id", Value: id},
+		{Key: "expires_at", Value: bson.D{{Key: "$gt", Value: time.Now()}}},
+	}
+
+	var comparison model.Comparison
+	if err := User(g.DB, "comparisons").FindOne(ctx, filter).Decode(&comparison); err != nil {
+		return comparison, dbError(err, "comparison")
+	}
+	return comparison, nil
+}
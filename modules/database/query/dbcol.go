@@ -13,3 +13,15 @@ func Product(client *mongo.Client, collection string) *mongo.Collection {
 	collection_db := client.Database("CarsGo").Collection(collection)
 	return collection_db
 }
+
+var principalCollections = map[string]string{
+	"user":  "user",
+	"admin": "admin",
+	"cse":   "cses",
+}
+
+func Principal(client *mongo.Client, principal string) *mongo.Collection {
+
+	collection_db := client.Database("CarsGo").Collection(principalCollections[principal])
+	return collection_db
+}
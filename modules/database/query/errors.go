@@ -0,0 +1,31 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/domain"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// dbError turns a driver error into a domain error. what names the kind of document the
+// query was about, e.g. "product", and ends up in the message shown to clients.
+func dbError(err error, what string) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, domain.ErrNotFound), errors.Is(err, domain.ErrConflict),
+		errors.Is(err, domain.ErrValidation), errors.Is(err, domain.ErrUnavailable):
+		return err
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return domain.Wrap(domain.ErrNotFound, what+" not found", err)
+	case mongo.IsDuplicateKeyError(err):
+		return domain.Wrap(domain.ErrConflict, what+" already exists", err)
+	case mongo.IsTimeout(err), mongo.IsNetworkError(err), errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, mongo.ErrClientDisconnected):
+		return domain.Unavailable("database is unavailable", err)
+	default:
+		return fmt.Errorf("%s query failed: %w", what, err)
+	}
+}
@@ -0,0 +1,94 @@
+package query
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EnsureIndexes creates the indexes the queries rely on. It is safe to call on every start.
+func (g *GoAppDB) EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	indexes := map[string][]mongo.IndexModel{
+		"password_resets": {
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+			{Keys: bson.D{{Key: "principal", Value: 1}, {Key: "email", Value: 1}}},
+		},
+		"login_attempts": {
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"login_sessions": {
+			{Keys: bson.D{{Key: "principal", Value: 1}, {Key: "subject_id", Value: 1}, {Key: "last_seen_at", Value: -1}}},
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"oidc_logins": {
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"user": {
+			{
+				Keys: bson.D{{Key: "identities.provider", Value: 1}, {Key: "identities.subject", Value: 1}},
+				Options: options.Index().SetUnique(true).SetPartialFilterExpression(
+					bson.D{{Key: "identities.subject", Value: bson.D{{Key: "$exists", Value: true}}}}),
+			},
+		},
+		"api_keys": {
+			{Keys: bson.D{{Key: "prefix", Value: 1}}, Options: options.Index().SetUnique(true)},
+		},
+		"api_key_calls": {
+			{Keys: bson.D{{Key: "key_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		},
+		"product": {
+			{Keys: bson.D{{Key: "createdat", Value: -1}, {Key: "_id", Value: -1}}},
+			{Keys: bson.D{{Key: "effective_price", Value: 1}, {Key: "_id", Value: 1}}},
+			{Keys: bson.D{{Key: "overall_rating", Value: -1}, {Key: "_id", Value: -1}}},
+			{Keys: bson.D{{Key: "category", Value: 1}, {Key: "createdat", Value: -1}, {Key: "_id", Value: -1}}},
+			{Keys: bson.D{{Key: "category", Value: 1}, {Key: "effective_price", Value: 1}, {Key: "_id", Value: 1}}},
+			{Keys: bson.D{{Key: "company_name", Value: 1}, {Key: "createdat", Value: -1}, {Key: "_id", Value: -1}}},
+			{Keys: bson.D{{Key: "company_name", Value: 1}, {Key: "effective_price", Value: 1}, {Key: "_id", Value: 1}}},
+			{Keys: bson.D{{Key: "description.fueltype", Value: 1}, {Key: "description.seatingcapacity", Value: 1}, {Key: "effective_price", Value: 1}}},
+			{Keys: bson.D{{Key: "instock", Value: 1}, {Key: "effective_price", Value: 1}}},
+			{Keys: bson.D{{Key: "category_id", Value: 1}, {Key: "createdat", Value: -1}, {Key: "_id", Value: -1}}},
+			{Keys: bson.D{{Key: "category_id", Value: 1}, {Key: "effective_price", Value: 1}, {Key: "_id", Value: 1}}},
+			{
+				Keys: bson.D{{Key: "variants.sku", Value: 1}},
+				Options: options.Index().SetUnique(true).SetPartialFilterExpression(
+					bson.D{{Key: "variants.sku", Value: bson.D{{Key: "$type", Value: "string"}}}}),
+			},
+			{Keys: bson.D{{Key: "options.fuel_types", Value: 1}}},
+			{Keys: bson.D{{Key: "specs.power_output.value", Value: -1}, {Key: "_id", Value: -1}}},
+			{Keys: bson.D{{Key: "specs.mileage.value", Value: -1}, {Key: "_id", Value: -1}}},
+			{Keys: bson.D{{Key: "specs.engine.value", Value: 1}}},
+			{Keys: bson.D{{Key: "specs.top_speed.value", Value: 1}}},
+			{Keys: bson.D{{Key: "on_sale", Value: 1}}},
+			{Keys: bson.D{{Key: "saleprice", Value: 1}}},
+		},
+		"category": {
+			{Keys: bson.D{{Key: "path", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "parent_id", Value: 1}}},
+			{Keys: bson.D{{Key: "ancestors", Value: 1}}},
+		},
+		"comparisons": {
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"price_history": {
+			{Keys: bson.D{{Key: "product_id", Value: 1}, {Key: "changed_at", Value: -1}}},
+		},
+		"security_events": {
+			{Keys: bson.D{{Key: "created_at", Value: -1}}},
+		},
+	}
+
+	for collection, models := range indexes {
+		if _, err := User(g.DB, collection).Indexes().CreateMany(ctx, models); err != nil {
+			g.App.ErrorLogger.Printf("cannot create indexes on %s : %v ", collection, err)
+			return dbError(err, collection)
+		}
+	}
+
+	return nil
+}
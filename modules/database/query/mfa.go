@@ -0,0 +1,219 @@
+package query
+
+import (
+	"context"
+	"time"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MaxMFAFailures is how many wrong codes a single login challenge tolerates before the
+// password has to be entered again.
+const MaxMFAFailures = 5
+
+const securitySettingsID = "security"
+
+// SetPendingTOTPSecret stores a secret that only becomes active once a code generated from it is confirmed.
+func (g *GoAppDB) SetPendingTOTPSecret(principal string, id primitive.ObjectID, secret string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := bson.D{{Key: "_id", Value: id}}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "totp_pending_secret", Value: secret}}}}
+
+	_, err := Principal(g.DB, principal).UpdateOne(ctx, filter, update)
+	if err != nil {
+		g.App.ErrorLogger.Printf("cannot store pending totp secret of %s : %v ", principal, err)
+		return dbError(err, principal)
+	}
+
+	return nil
+}
+
+// EnableTOTP activates the pending secret and replaces the recovery codes with the given hashes.
+// It reports false when secret is no longer the pending one.
+func (g *GoAppDB) EnableTOTP(principal string, id primitive.ObjectID, secret string, hashedRecoveryCodes []string, step int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := bson.D{{Key: "_id", Value: id}, {Key: "totp_pending_secret", Value: secret}}
+	update := bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "totp_enabled", Value: true},
+			{Key: "totp_secret", Value: secret},
+			{Key: "totp_last_step", Value: step},
+			{Key: "recovery_codes", Value: hashedRecoveryCodes},
+		}},
+		{Key: "$unset", Value: bson.D{{Key: "totp_pending_secret", Value: ""}}},
+	}
+
+	result, err := Principal(g.DB, principal).UpdateOne(ctx, filter, update)
+	if err != nil {
+		g.App.ErrorLogger.Printf("cannot enable totp of %s : %v ", principal, err)
+		return false, dbError(err, principal)
+	}
+
+	return result.MatchedCount == 1, nil
+}
+
+func (g *GoAppDB) DisableTOTP(principal string, id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := bson.D{{Key: "_id", Value: id}}
+	update := bson.D{
+		{Key: "$set", Value: bson.D{{Key: "totp_enabled", Value: false}}},
+		{Key: "$unset", Value: bson.D{
+			{Key: "totp_secret", Value: ""},
+			{Key: "totp_pending_secret", Value: ""},
+			{Key: "totp_last_step", Value: ""},
+			{Key: "recovery_codes", Value: ""},
+		}},
+	}
+
+	_, err := Principal(g.DB, principal).UpdateOne(ctx, filter, update)
+	if err != nil {
+		g.App.ErrorLogger.Printf("cannot disable totp of %s : %v ", principal, err)
+		return dbError(err, principal)
+	}
+
+	return nil
+}
+
+// RecordTOTPStep remembers the time step of an accepted code. It reports false when that step or a
+// later one was already used, so every code can only be used once.
+func (g *GoAppDB) RecordTOTPStep(principal string, id primitive.ObjectID, step int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := bson.D{
+		{Key: "_id", Value: id},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "totp_last_step", Value: bson.D{{Key: "$lt", Value: step}}}},
+			bson.D{{Key: "totp_last_step", Value: bson.D{{Key: "$exists", Value: false}}}},
+		}},
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "totp_last_step", Value: step}}}}
+
+	result, err := Principal(g.DB, principal).UpdateOne(ctx, filter, update)
+	if err != nil {
+		g.App.ErrorLogger.Printf("cannot record totp step of %s : %v ", principal, err)
+		return false, dbError(err, principal)
+	}
+
+	return result.MatchedCount == 1, nil
+}
+
+// ConsumeRecoveryCode removes a used recovery code. It reports false when the code was already used.
+func (g *GoAppDB) ConsumeRecoveryCode(principal string, id primitive.ObjectID, hashedCode string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := bson.D{{Key: "_id", Value: id}, {Key: "recovery_codes", Value: hashedCode}}
+	update := bson.D{{Key: "$pull", Value: bson.D{{Key: "recovery_codes", Value: hashedCode}}}}
+
+	result, err := Principal(g.DB, principal).UpdateOne(ctx, filter, update)
+	if err != nil {
+		g.App.ErrorLogger.Printf("cannot consume recovery code of %s : %v ", principal, err)
+		return false, dbError(err, principal)
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+// StartMFAChallenge binds the second login step to the token with the given jti. Starting a new
+// challenge invalidates the previous one.
+func (g *GoAppDB) StartMFAChallenge(principal string, id primitive.ObjectID, jti string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := bson.D{{Key: "_id", Value: id}}
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "mfa_challenge", Value: jti},
+		{Key: "mfa_challenge_failures", Value: 0},
+	}}}
+
+	_, err := Principal(g.DB, principal).UpdateOne(ctx, filter, update)
+	if err != nil {
+		g.App.ErrorLogger.Printf("cannot start mfa challenge of %s : %v ", principal, err)
+		return dbError(err, principal)
+	}
+
+	return nil
+}
+
+func (g *GoAppDB) RecordMFAFailure(principal string, id primitive.ObjectID, jti string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := bson.D{{Key: "_id", Value: id}, {Key: "mfa_challenge", Value: jti}}
+	update := bson.D{{Key: "$inc", Value: bson.D{{Key: "mfa_challenge_failures", Value: 1}}}}
+
+	_, err := Principal(g.DB, principal).UpdateOne(ctx, filter, update)
+	if err != nil {
+		g.App.ErrorLogger.Printf("cannot record mfa failure of %s : %v ", principal, err)
+		return dbError(err, principal)
+	}
+
+	return nil
+}
+
+// CompleteMFAChallenge ends the challenge with the given jti. It reports false when the challenge was
+// already completed, replaced by a newer one or failed too often.
+func (g *GoAppDB) CompleteMFAChallenge(principal string, id primitive.ObjectID, jti string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := bson.D{
+		{Key: "_id", Value: id},
+		{Key: "mfa_challenge", Value: jti},
+		{Key: "mfa_challenge_failures", Value: bson.D{{Key: "$lt", Value: MaxMFAFailures}}},
+	}
+	update := bson.D{{Key: "$unset", Value: bson.D{
+		{Key: "mfa_challenge", Value: ""},
+		{Key: "mfa_challenge_failures", Value: ""},
+	}}}
+
+	result, err := Principal(g.DB, principal).UpdateOne(ctx, filter, update)
+	if err != nil {
+		g.App.ErrorLogger.Printf("cannot complete mfa challenge of %s : %v ", principal, err)
+		return false, dbError(err, principal)
+	}
+
+	return result.MatchedCount == 1, nil
+}
+
+// GetSecuritySettings returns the security policies, or their defaults when none were saved yet.
+func (g *GoAppDB) GetSecuritySettings() (model.SecuritySettings, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	settings := model.SecuritySettings{ID: securitySettingsID}
+
+	err := User(g.DB, "settings").FindOne(ctx, bson.D{{Key: "_id", Value: securitySettingsID}}).Decode(&settings)
+	if err != nil && err != mongo.ErrNoDocuments {
+		g.App.ErrorLogger.Printf("cannot fetch security settings : %v ", err)
+		return settings, dbError(err, "security settings")
+	}
+
+	return settings, nil
+}
+
+func (g *GoAppDB) UpdateSecuritySettings(settings *model.SecuritySettings) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	settings.ID = securitySettingsID
+
+	_, err := User(g.DB, "settings").ReplaceOne(ctx, bson.D{{Key: "_id", Value: securitySettingsID}}, settings, options.Replace().SetUpsert(true))
+	if err != nil {
+		g.App.ErrorLogger.Printf("cannot update security settings : %v ", err)
+		return dbError(err, "security settings")
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package query
+
+import (
+	"context"
+	"time"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func (g *GoAppDB) CreateOIDCLogin(login *model.OIDCLogin) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	_, err := User(g.DB, "oidc_logins").InsertOne(ctx, login)
+	if err != nil {
+		g.App.ErrorLogger.Printf("Error storing OIDC login: %v", err)
+		return dbError(err, "sign in")
+	}
+
+	return nil
+}
+
+// ConsumeOIDCLogin removes and returns the pending sign in with the given id, so a provider
+// response can only be redeemed once.
+func (g *GoAppDB) ConsumeOIDCLogin(id string) (model.OIDCLogin, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	var login model.OIDCLogin
+
+	err := User(g.DB, "oidc_logins").FindOneAndDelete(ctx, bson.D{{Key: "_id", Value: id}}).Decode(&login)
+	if err != nil {
+		return login, dbError(err, "sign in")
+	}
+
+	return login, nil
+}
+
+// FindUserByIdentity returns the user linked to the given account of an OIDC provider.
+func (g *GoAppDB) FindUserByIdentity(provider string, subject string) (primitive.M, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	var res bson.M
+
+	filter := bson.D{{Key: "identities", Value: bson.D{{Key: "$elemMatch", Value: bson.D{
+		{Key: "provider", Value: provider},
+		{Key: "subject", Value: subject},
+	}}}}}
+
+	err := User(g.DB, "user").FindOne(ctx, filter).Decode(&res)
+	if err != nil {
+		return nil, dbError(err, "user")
+	}
+
+	return res, nil
+}
+
+// LinkIdentity adds an OIDC account to a user. It reports false when the user already has an
+// account of that provider linked.
+func (g *GoAppDB) LinkIdentity(userID primitive.ObjectID, identity *model.Identity) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := bson.D{
+		{Key: "_id", Value: userID},
+		{Key: "identities.provider", Value: bson.D{{Key: "$ne", Value: identity.Provider}}},
+	}
+	update := bson.D{
+		{Key: "$push", Value: bson.D{{Key: "identities", Value: identity}}},
+		{Key: "$set", Value: bson.D{{Key: "updatedat", Value: time.Now()}}},
+	}
+
+	result, err := User(g.DB, "user").UpdateOne(ctx, filter, update)
+	if err != nil {
+		g.App.ErrorLogger.Printf("Error linking identity to user: %v", err)
+		return false, dbError(err, "identity")
+	}
+
+	return result.MatchedCount == 1, nil
+}
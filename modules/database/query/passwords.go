@@ -0,0 +1,138 @@
+package query
+
+import (
+	"context"
+	"time"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreatePasswordReset stores a new reset code for an account, replacing any code still pending for it.
+func (g *GoAppDB) CreatePasswordReset(reset *model.PasswordReset) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	pending := bson.D{
+		{Key: "principal", Value: reset.Principal},
+		{Key: "account_id", Value: reset.AccountID},
+		{Key: "used_at", Value: nil},
+	}
+
+	_, err := User(g.DB, "password_resets").DeleteMany(ctx, pending)
+	if err != nil {
+		g.App.ErrorLogger.Printf("Error discarding pending password resets: %v", err)
+		return dbError(err, "password reset")
+	}
+
+	reset.ID = primitive.NewObjectID()
+
+	_, err = User(g.DB, "password_resets").InsertOne(ctx, reset)
+	if err != nil {
+		g.App.ErrorLogger.Printf("Error creating password reset: %v", err)
+		return dbError(err, "password reset")
+	}
+
+	return nil
+}
+
+// GetPasswordReset returns the unused, unexpired reset code pending for email.
+// It returns mongo.ErrNoDocuments when there is none.
+func (g *GoAppDB) GetPasswordReset(principal string, email string) (*model.PasswordReset, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := bson.D{
+		{Key: "principal", Value: principal},
+		{Key: "email", Value: email},
+		{Key: "used_at", Value: nil},
+		{Key: "expires_at", Value: bson.D{{Key: "$gt", Value: time.Now()}}},
+	}
+	opts := options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	var reset model.PasswordReset
+
+	err := User(g.DB, "password_resets").FindOne(ctx, filter, opts).Decode(&reset)
+	if err != nil {
+		return nil, dbError(err, "password reset")
+	}
+
+	return &reset, nil
+}
+
+func (g *GoAppDB) RecordPasswordResetAttempt(resetID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := bson.D{{Key: "_id", Value: resetID}}
+	update := bson.D{{Key: "$inc", Value: bson.D{{Key: "attempts", Value: 1}}}}
+
+	_, err := User(g.DB, "password_resets").UpdateOne(ctx, filter, update)
+	if err != nil {
+		g.App.ErrorLogger.Printf("Error recording password reset attempt: %v", err)
+		return dbError(err, "password reset")
+	}
+
+	return nil
+}
+
+// ConsumePasswordReset marks a reset code as used. It reports false when the code was already used,
+// so a code can only ever reset one password.
+func (g *GoAppDB) ConsumePasswordReset(resetID primitive.ObjectID) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := bson.D{{Key: "_id", Value: resetID}, {Key: "used_at", Value: nil}}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "used_at", Value: time.Now()}}}}
+
+	result, err := User(g.DB, "password_resets").UpdateOne(ctx, filter, update)
+	if err != nil {
+		g.App.ErrorLogger.Printf("Error consuming password reset: %v", err)
+		return false, dbError(err, "password reset")
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+// ResetPassword stores an already hashed password for a user or admin and clears its tokens.
+func (g *GoAppDB) ResetPassword(principal string, id primitive.ObjectID, hashedPassword string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := bson.D{{Key: "_id", Value: id}}
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "password", Value: hashedPassword},
+		{Key: "token", Value: ""},
+		{Key: "new_token", Value: ""},
+		{Key: "token_family", Value: ""},
+		{Key: "updatedat", Value: time.Now()},
+	}}}
+
+	result, err := Principal(g.DB, principal).UpdateOne(ctx, filter, update)
+	if err != nil {
+		g.App.ErrorLogger.Printf("cannot reset %s password : %v ", principal, err)
+		return false, dbError(err, principal)
+	}
+
+	return result.MatchedCount == 1, nil
+}
+
+// UpdatePasswordHash replaces a password hash with a stronger hash of the same password, unless
+// the password changed in the meantime.
+func (g *GoAppDB) UpdatePasswordHash(principal string, id primitive.ObjectID, oldHash string, newHash string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := bson.D{{Key: "_id", Value: id}, {Key: "password", Value: oldHash}}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "password", Value: newHash}}}}
+
+	result, err := Principal(g.DB, principal).UpdateOne(ctx, filter, update)
+	if err != nil {
+		g.App.ErrorLogger.Printf("cannot update %s password hash : %v ", principal, err)
+		return false, dbError(err, principal)
+	}
+
+	return result.MatchedCount == 1, nil
+}
@@ -0,0 +1,34 @@
+package query
+
+import (
+	"context"
+	"time"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetOrder returns the order with the given id.
+func (g *GoAppDB) GetOrder(id primitive.ObjectID) (model.Order, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	var order model.Order
+	if err := User(g.DB, "orders").FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Decode(&order); err != nil {
+		return order, dbError(err, "order")
+	}
+	return order, nil
+}
+
+// GetPayment returns the payment with the given id.
+func (g *GoAppDB) GetPayment(id primitive.ObjectID) (model.Payment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	var payment model.Payment
+	if err := User(g.DB, "payment").FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Decode(&payment); err != nil {
+		return payment, dbError(err, "payment")
+	}
+	return payment, nil
+}
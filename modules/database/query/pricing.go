@@ -0,0 +1,124 @@
+package query
+
+import (
+	"context"
+	"time"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetProductsToReprice returns the products whose price may have moved since it was stored:
+// those with a sale price, those shown on sale and those never priced. Reviews are left out.
+func (g *GoAppDB) GetProductsToReprice() ([]model.Product, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "saleprice", Value: bson.D{{Key: "$gt", Value: 0}}}},
+		bson.D{{Key: "on_sale", Value: true}},
+		bson.D{{Key: "priced_at", Value: bson.D{{Key: "$exists", Value: false}}}},
+	}}}
+	opts := options.Find().SetProjection(bson.D{{Key: "reviews", Value: 0}})
+
+	cursor, err := Product(g.DB, "product").Find(ctx, filter, opts)
+	if err != nil {
+		g.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return nil, dbError(err, "product")
+	}
+
+	var products []model.Product
+	if err = cursor.All(ctx, &products); err != nil {
+		g.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return nil, dbError(err, "product")
+	}
+
+	return products, nil
+}
+
+// SetProductPrice stores the price and sale badge of product and records the change in its price
+// history. It reports false, recording nothing, when product was edited or priced since it was
+// read, as the change was then worked out from what it no longer is.
+func (g *GoAppDB) SetProductPrice(product *model.Product, change *model.PriceChange) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	// A zero time also matches products that never had the field.
+	seen := func(t time.Time) interface{} {
+		if t.IsZero() {
+			return bson.D{{Key: "$in", Value: bson.A{t, nil}}}
+		}
+		return t
+	}
+	filter := bson.D{
+		{Key: "_id", Value: product.ID},
+		{Key: "updatedat", Value: seen(product.UpdatedAt)},
+		{Key: "priced_at", Value: seen(product.PricedAt)},
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "effective_price", Value: change.EffectivePrice},
+		{Key: "on_sale", Value: change.OnSale},
+		{Key: "priced_at", Value: change.ChangedAt},
+	}}}
+
+	res, err := Product(g.DB, "product").UpdateOne(ctx, filter, update)
+	if err != nil {
+		g.App.ErrorLogger.Printf("cannot update the price of product : %v ", err)
+		return false, dbError(err, "product")
+	}
+	if res.MatchedCount == 0 {
+		return false, nil
+	}
+
+	change.ID = primitive.NewObjectID()
+	if _, err := User(g.DB, "price_history").InsertOne(ctx, change); err != nil {
+		g.App.ErrorLogger.Printf("cannot record price change : %v ", err)
+		return true, dbError(err, "price change")
+	}
+
+	return true, nil
+}
+
+// GetPriceHistory returns the latest price changes of a product, newest first.
+func (g *GoAppDB) GetPriceHistory(productID primitive.ObjectID, limit int64) ([]model.PriceChange, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "changed_at", Value: -1}}).SetLimit(limit)
+
+	cursor, err := User(g.DB, "price_history").Find(ctx, bson.D{{Key: "product_id", Value: productID}}, opts)
+	if err != nil {
+		g.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return nil, dbError(err, "price change")
+	}
+
+	changes := []model.PriceChange{}
+	if err = cursor.All(ctx, &changes); err != nil {
+		g.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return nil, dbError(err, "price change")
+	}
+
+	return changes, nil
+}
+
+// GetCart returns the items in the cart of a user.
+func (g *GoAppDB) GetCart(userID primitive.ObjectID) ([]model.CartItems, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	opts := options.FindOne().SetProjection(bson.D{{Key: "cart", Value: 1}})
+
+	var user struct {
+		Cart []model.CartItems `bson:"cart"`
+	}
+	if err := User(g.DB, "user").FindOne(ctx, bson.D{{Key: "_id", Value: userID}}, opts).Decode(&user); err != nil {
+		return nil, dbError(err, "user")
+	}
+
+	if user.Cart == nil {
+		return []model.CartItems{}, nil
+	}
+	return user.Cart, nil
+}
@@ -0,0 +1,407 @@
+package query
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/domain"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// defaultProductPage is how many products a page holds when the query does not say.
+const defaultProductPage = 20
+
+// productSort is the field a listing is sorted on and its direction; ties are broken by _id in
+// the same direction so that every product has a unique position to resume from. Sparse fields
+// are not set on every product, and those without them are left out of the listing.
+type productSort struct {
+	field  string
+	order  int
+	sparse bool
+}
+
+var productSorts = map[string]productSort{
+	model.SortNewest:    {field: "createdat", order: -1},
+	model.SortPriceAsc:  {field: "effective_price", order: 1},
+	model.SortPriceDesc: {field: "effective_price", order: -1},
+	model.SortRating:    {field: "overall_rating", order: -1},
+	model.SortPower:     {field: "specs.power_output.value", order: -1, sparse: true},
+	model.SortMileage:   {field: "specs.mileage.value", order: -1, sparse: true},
+}
+
+// productCursor is the position after the last product of a page. It is handed to clients as
+// base64 encoded JSON and only valid for the sort it was made for.
+type productCursor struct {
+	Sort  string             `json:"s"`
+	Value json.RawMessage    `json:"v"`
+	ID    primitive.ObjectID `json:"id"`
+}
+
+// ViewProducts returns one page of the products matching q, in the order q asks for.
+func (g *GoAppDB) ViewProducts(q model.ProductQuery) (*model.ProductPage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	if q.Sort == "" {
+		q.Sort = model.SortNewest
+	}
+	sort, ok := productSorts[q.Sort]
+	if !ok {
+		return nil, domain.Validation("unknown sort " + q.Sort)
+	}
+
+	if q.Limit <= 0 {
+		q.Limit = defaultProductPage
+	}
+
+	filter := productFilter(q)
+
+	if !q.CategoryID.IsZero() {
+		ids, err := g.categorySubtree(ctx, q.CategoryID)
+		if err != nil {
+			return nil, err
+		}
+		filter = append(filter, bson.E{Key: "category_id", Value: bson.D{{Key: "$in", Value: ids}}})
+	}
+
+	if sort.sparse {
+		filter = append(filter, bson.E{Key: sort.field, Value: bson.D{{Key: "$exists", Value: true}}})
+	}
+
+	total, err := Product(g.DB, "product").CountDocuments(ctx, filter)
+	if err != nil {
+		g.App.ErrorLogger.Printf("cannot count products : %v ", err)
+		return nil, dbError(err, "product")
+	}
+
+	if q.Cursor != "" {
+		after, err := decodeProductCursor(q.Cursor, q.Sort)
+		if err != nil {
+			return nil, err
+		}
+		filter = append(filter, after)
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: sort.field, Value: sort.order}, {Key: "_id", Value: sort.order}}).
+		SetLimit(int64(q.Limit) + 1)
+
+	cursor, err := Product(g.DB, "product").Find(ctx, filter, opts)
+	if err != nil {
+		g.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return nil, dbError(err, "product")
+	}
+
+	products := []primitive.M{}
+	if err = cursor.All(ctx, &products); err != nil {
+		g.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return nil, dbError(err, "product")
+	}
+
+	page := &model.ProductPage{Products: products, Total: total}
+
+	if len(products) > q.Limit {
+		page.Products = products[:q.Limit]
+		last := page.Products[q.Limit-1]
+		page.NextCursor, err = encodeProductCursor(q.Sort, fieldValue(last, sort.field), last["_id"])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
+}
+
+func productFilter(q model.ProductQuery) bson.D {
+	filter := bson.D{}
+
+	exact := bson.D{
+		{Key: "category", Value: q.Category},
+		{Key: "company_name", Value: q.CompanyName},
+		{Key: "description.seatingcapacity", Value: q.SeatingCapacity},
+		{Key: "options.colors", Value: q.Color},
+		{Key: "options.trims", Value: q.Trim},
+		{Key: "options.transmissions", Value: q.Transmission},
+	}
+	for _, e := range exact {
+		if e.Value != "" {
+			filter = append(filter, e)
+		}
+	}
+
+	// A product sold in variants may come in several fuel types. The condition sits in an $and
+	// as the cursor condition takes the $or.
+	if q.FuelType != "" {
+		filter = append(filter, bson.E{Key: "$and", Value: bson.A{
+			bson.D{{Key: "$or", Value: bson.A{
+				bson.D{{Key: "description.fueltype", Value: q.FuelType}},
+				bson.D{{Key: "options.fuel_types", Value: q.FuelType}},
+			}}},
+		}})
+	}
+
+	price := bson.D{}
+	if q.MinPrice != nil {
+		price = append(price, bson.E{Key: "$gte", Value: *q.MinPrice})
+	}
+	if q.MaxPrice != nil {
+		price = append(price, bson.E{Key: "$lte", Value: *q.MaxPrice})
+	}
+	// Listings go by what customers pay, which the price scheduler keeps on every product.
+	if len(price) > 0 {
+		filter = append(filter, bson.E{Key: "effective_price", Value: price})
+	}
+
+	if q.InStock != nil {
+		filter = append(filter, bson.E{Key: "instock", Value: *q.InStock})
+	}
+	if q.OnSale != nil {
+		filter = append(filter, bson.E{Key: "on_sale", Value: *q.OnSale})
+	}
+
+	if q.MinRating != nil {
+		filter = append(filter, bson.E{Key: "overall_rating", Value: bson.D{{Key: "$gte", Value: *q.MinRating}}})
+	}
+
+	for spec, r := range q.SpecRanges {
+		bounds := bson.D{}
+		if r.Min != nil {
+			bounds = append(bounds, bson.E{Key: "$gte", Value: *r.Min})
+		}
+		if r.Max != nil {
+			bounds = append(bounds, bson.E{Key: "$lte", Value: *r.Max})
+		}
+		if len(bounds) > 0 {
+			filter = append(filter, bson.E{Key: "specs." + spec + ".value", Value: bounds})
+		}
+	}
+
+	return filter
+}
+
+// fieldValue returns the value at a dotted path of doc, or nil when there is none.
+func fieldValue(doc primitive.M, path string) interface{} {
+	var value interface{} = doc
+	for _, key := range strings.Split(path, ".") {
+		switch d := value.(type) {
+		case primitive.M:
+			value = d[key]
+		case primitive.D:
+			value = d.Map()[key]
+		default:
+			return nil
+		}
+	}
+	return value
+}
+
+func encodeProductCursor(sort string, value interface{}, id interface{}) (string, error) {
+	oid, ok := id.(primitive.ObjectID)
+	if !ok {
+		return "", domain.Validation("product has no usable id")
+	}
+
+	// Products without the field, or with it null, resume from a null value.
+	v := json.RawMessage("null")
+	if value != nil {
+		var err error
+		if v, err = json.Marshal(value); err != nil {
+			return "", err
+		}
+	}
+
+	raw, err := json.Marshal(productCursor{Sort: sort, Value: v, ID: oid})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// decodeProductCursor returns the condition matching the products after the cursor.
+func decodeProductCursor(encoded string, sort string) (bson.E, error) {
+	invalid := domain.Validation("invalid cursor")
+
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return bson.E{}, invalid
+	}
+
+	var c productCursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.Sort != sort || c.ID.IsZero() {
+		return bson.E{}, invalid
+	}
+
+	var value interface{}
+	null := string(c.Value) == "null"
+	switch {
+	case null:
+	case sort == model.SortNewest:
+		var t time.Time
+		err = json.Unmarshal(c.Value, &t)
+		value = t
+	case sort == model.SortPriceAsc, sort == model.SortPriceDesc:
+		var n int64
+		err = json.Unmarshal(c.Value, &n)
+		value = n
+	case sort == model.SortRating, sort == model.SortPower, sort == model.SortMileage:
+		var f float64
+		err = json.Unmarshal(c.Value, &f)
+		value = f
+	}
+	if err != nil {
+		return bson.E{}, invalid
+	}
+
+	s := productSorts[sort]
+	op := "$gt"
+	if s.order < 0 {
+		op = "$lt"
+	}
+
+	// Null and missing values sort before every other value, so they come last in descending
+	// order and first in ascending order, and a comparison with a value never matches them.
+	if null {
+		after := bson.A{
+			bson.D{{Key: s.field, Value: nil}, {Key: "_id", Value: bson.D{{Key: op, Value: c.ID}}}},
+		}
+		if s.order > 0 {
+			after = append(after, bson.D{{Key: s.field, Value: bson.D{{Key: "$ne", Value: nil}}}})
+		}
+		return bson.E{Key: "$or", Value: after}, nil
+	}
+
+	after := bson.A{
+		bson.D{{Key: s.field, Value: bson.D{{Key: op, Value: value}}}},
+		bson.D{{Key: s.field, Value: value}, {Key: "_id", Value: bson.D{{Key: op, Value: c.ID}}}},
+	}
+	if s.order < 0 {
+		after = append(after, bson.D{{Key: s.field, Value: nil}})
+	}
+	return bson.E{Key: "$or", Value: after}, nil
+}
+
+// GetAllProducts returns every product, e.g. to build the search index from.
+func (g *GoAppDB) GetAllProducts() ([]model.Product, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	cursor, err := Product(g.DB, "product").Find(ctx, bson.D{})
+	if err != nil {
+		g.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return nil, dbError(err, "product")
+	}
+
+	products := []model.Product{}
+	if err = cursor.All(ctx, &products); err != nil {
+		g.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return nil, dbError(err, "product")
+	}
+
+	return products, nil
+}
+
+// GetProductsByIDs returns the products with the given ids, in the order of ids. Ids of products
+// that no longer exist are skipped.
+func (g *GoAppDB) GetProductsByIDs(ids []primitive.ObjectID) ([]primitive.M, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: ids}}}}
+
+	cursor, err := Product(g.DB, "product").Find(ctx, filter)
+	if err != nil {
+		g.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return nil, dbError(err, "product")
+	}
+
+	var found []primitive.M
+	if err = cursor.All(ctx, &found); err != nil {
+		g.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return nil, dbError(err, "product")
+	}
+
+	byID := make(map[primitive.ObjectID]primitive.M, len(found))
+	for _, product := range found {
+		if id, ok := product["_id"].(primitive.ObjectID); ok {
+			byID[id] = product
+		}
+	}
+
+	products := make([]primitive.M, 0, len(ids))
+	for _, id := range ids {
+		if product, ok := byID[id]; ok {
+			products = append(products, product)
+		}
+	}
+
+	return products, nil
+}
+
+// GetProducts is GetProductsByIDs decoded into products.
+func (g *GoAppDB) GetProducts(ids []primitive.ObjectID) ([]model.Product, error) {
+	found, err := g.GetProductsByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	products := make([]model.Product, len(found))
+	for i, doc := range found {
+		raw, err := bson.Marshal(doc)
+		if err != nil {
+			return nil, dbError(err, "product")
+		}
+		if err := bson.Unmarshal(raw, &products[i]); err != nil {
+			g.App.ErrorLogger.Printf("cannot decode product %v : %v ", doc["_id"], err)
+			return nil, dbError(err, "product")
+		}
+	}
+
+	return products, nil
+}
+
+// MigrateSpecs reads the specs of products entered before they were kept apart from the free
+// text description. Descriptions that cannot be read are logged and those specs left unknown;
+// every product gets a specs document so it is not visited again.
+func (g *GoAppDB) MigrateSpecs() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := bson.D{{Key: "specs", Value: bson.D{{Key: "$exists", Value: false}}}}
+	opts := options.Find().SetProjection(bson.D{{Key: "description", Value: 1}})
+
+	cursor, err := Product(g.DB, "product").Find(ctx, filter, opts)
+	if err != nil {
+		return dbError(err, "product")
+	}
+
+	var products []model.Product
+	if err = cursor.All(ctx, &products); err != nil {
+		return dbError(err, "product")
+	}
+
+	for _, p := range products {
+		specs, errs := p.Description.Specs()
+		for _, e := range errs {
+			g.App.ErrorLogger.Printf("cannot read %s of product %s : %v", e.Field, p.ID.Hex(), e.Err)
+		}
+
+		_, err := Product(g.DB, "product").UpdateOne(ctx, bson.D{{Key: "_id", Value: p.ID}},
+			bson.D{{Key: "$set", Value: bson.D{{Key: "specs", Value: specs}}}})
+		if err != nil {
+			return dbError(err, "product")
+		}
+	}
+
+	if len(products) > 0 {
+		g.App.InfoLogger.Printf("Specs of %d products migrated", len(products))
+	}
+
+	return nil
+}
@@ -2,13 +2,13 @@ package query
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/PraveenRajPurak/CarsGo-Backend/modules/config"
-	"github.com/PraveenRajPurak/CarsGo-Backend/modules/encrypt"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/domain"
 	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -39,7 +39,7 @@ func (g *GoAppDB) InsertUser(user *model.User) (bool, int, error) {
 	if !regMail.MatchString(user.Email) {
 
 		g.App.ErrorLogger.Println("invalid registered details - email")
-		return false, 0, errors.New("invalid registered details - email")
+		return false, 0, domain.Validation("invalid registered details - email")
 
 	}
 
@@ -53,11 +53,13 @@ func (g *GoAppDB) InsertUser(user *model.User) (bool, int, error) {
 			user.ID = primitive.NewObjectID()
 			_, insertErr := User(g.DB, "user").InsertOne(ctx, user)
 			if insertErr != nil {
-				g.App.ErrorLogger.Fatalf("cannot add user to the database : %v ", insertErr)
+				g.App.ErrorLogger.Printf("cannot add user to the database : %v ", insertErr)
+				return false, 0, dbError(insertErr, "user")
 			}
 			return true, 1, nil
 		}
-		g.App.ErrorLogger.Fatal(err)
+		g.App.ErrorLogger.Println(err)
+		return false, 0, dbError(err, "user")
 	}
 	return true, 2, nil
 }
@@ -73,9 +75,10 @@ func (g *GoAppDB) VerifyUser(email string) (primitive.M, error) {
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			g.App.ErrorLogger.Println("no document found for this query")
-			return nil, err
+			return nil, dbError(err, "user")
 		}
-		g.App.ErrorLogger.Fatalf("cannot execute the database query perfectly : %v ", err)
+		g.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return nil, dbError(err, "user")
 	}
 
 	return res, nil
@@ -88,12 +91,12 @@ func (g *GoAppDB) UpdateUser(userID primitive.ObjectID, tk map[string]string) (b
 	defer cancel()
 
 	filter := bson.D{{Key: "_id", Value: userID}}
-	update := bson.D{{Key: "$set", Value: bson.D{{Key: "token", Value: tk["t1"]}, {Key: "new_token", Value: tk["t2"]}}}}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "token", Value: tk["token"]}, {Key: "new_token", Value: tk["newToken"]}, {Key: "token_family", Value: tk["family"]}}}}
 
 	_, err := User(g.DB, "user").UpdateOne(ctx, filter, update)
 	if err != nil {
-		g.App.ErrorLogger.Fatalf("cannot update user's tokens in the database : %v ", err)
-		return false, err
+		g.App.ErrorLogger.Printf("cannot update user's tokens in the database : %v ", err)
+		return false, dbError(err, "user")
 	}
 	return true, nil
 }
@@ -116,13 +119,15 @@ func (g *GoAppDB) SignUpAdmin(admin *model.Admin) (bool, int, error) {
 			admin.ID = primitive.NewObjectID()
 			_, insertErr := User(g.DB, "admin").InsertOne(ctx, admin)
 			if insertErr != nil {
-				g.App.ErrorLogger.Fatalf("cannot add admin to the database : %v ", insertErr)
+				g.App.ErrorLogger.Printf("cannot add admin to the database : %v ", insertErr)
+				return false, 0, dbError(insertErr, "admin")
 			}
 
 			return true, 1, nil
 		}
 
-		g.App.ErrorLogger.Fatalf("cannot execute the database query perfectly : %v ", err)
+		g.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return false, 0, dbError(err, "admin")
 	}
 
 	return true, 2, nil
@@ -139,9 +144,10 @@ func (g *GoAppDB) VerifyAdmin(email string) (primitive.M, error) {
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			g.App.ErrorLogger.Println("no document found for this query")
-			return nil, err
+			return nil, dbError(err, "admin")
 		}
-		g.App.ErrorLogger.Fatalf("cannot execute the database query perfectly : %v ", err)
+		g.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return nil, dbError(err, "admin")
 	}
 
 	return res, nil
@@ -154,27 +160,132 @@ func (g *GoAppDB) UpdateAdmin(userID primitive.ObjectID, tk map[string]string) (
 	defer cancel()
 
 	filter := bson.D{{Key: "_id", Value: userID}}
-	update := bson.D{{Key: "$set", Value: bson.D{{Key: "token", Value: tk["t1"]}, {Key: "new_token", Value: tk["t2"]}}}}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "token", Value: tk["token"]}, {Key: "new_token", Value: tk["newToken"]}, {Key: "token_family", Value: tk["family"]}}}}
 
 	_, err := User(g.DB, "admin").UpdateOne(ctx, filter, update)
 	if err != nil {
-		g.App.ErrorLogger.Fatalf("cannot update user's tokens in the database : %v ", err)
-		return false, err
+		g.App.ErrorLogger.Printf("cannot update user's tokens in the database : %v ", err)
+		return false, dbError(err, "admin")
+	}
+	return true, nil
+}
+
+func (g *GoAppDB) UpdateCSE(cseID primitive.ObjectID, tk map[string]string) (bool, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+	defer cancel()
+
+	filter := bson.D{{Key: "_id", Value: cseID}}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "token", Value: tk["token"]}, {Key: "new_token", Value: tk["newToken"]}, {Key: "token_family", Value: tk["family"]}}}}
+
+	_, err := User(g.DB, "cses").UpdateOne(ctx, filter, update)
+	if err != nil {
+		g.App.ErrorLogger.Printf("cannot update cse's tokens in the database : %v ", err)
+		return false, dbError(err, "cse")
 	}
 	return true, nil
 }
 
+// MigrateEmails rewrites the emails of users, admins and cses stored before emails were normalised:
+// trimmed and in lower case, the form they are looked up in. An account whose normalised email
+// another one already has is logged and left alone, as it could not be signed in to either way.
+func (g *GoAppDB) MigrateEmails() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	normalised := bson.D{{Key: "$toLower", Value: bson.D{{Key: "$trim", Value: bson.D{{Key: "input", Value: "$email"}}}}}}
+	filter := bson.D{
+		{Key: "email", Value: bson.D{{Key: "$type", Value: "string"}}},
+		{Key: "$expr", Value: bson.D{{Key: "$ne", Value: bson.A{"$email", normalised}}}},
+	}
+	opts := options.Find().SetProjection(bson.D{{Key: "email", Value: 1}})
+
+	for _, principal := range []string{"user", "admin", "cse"} {
+		collection := Principal(g.DB, principal)
+
+		cursor, err := collection.Find(ctx, filter, opts)
+		if err != nil {
+			return dbError(err, principal)
+		}
+		var accounts []struct {
+			ID    primitive.ObjectID `bson:"_id"`
+			Email string             `bson:"email"`
+		}
+		if err = cursor.All(ctx, &accounts); err != nil {
+			return dbError(err, principal)
+		}
+
+		for _, account := range accounts {
+			email := strings.ToLower(strings.TrimSpace(account.Email))
+
+			taken, err := collection.CountDocuments(ctx, bson.D{
+				{Key: "email", Value: email},
+				{Key: "_id", Value: bson.D{{Key: "$ne", Value: account.ID}}},
+			})
+			if err != nil {
+				return dbError(err, principal)
+			}
+			if taken > 0 {
+				g.App.ErrorLogger.Printf("cannot normalise the email of %s %s, %s is already taken", principal, account.ID.Hex(), email)
+				continue
+			}
+
+			_, err = collection.UpdateOne(ctx, bson.D{{Key: "_id", Value: account.ID}},
+				bson.D{{Key: "$set", Value: bson.D{{Key: "email", Value: email}}}})
+			if err != nil {
+				return dbError(err, principal)
+			}
+		}
+	}
+
+	return nil
+}
+
+// FindPrincipalByEmail returns the user, admin or cse with the given email.
+// It returns mongo.ErrNoDocuments when there is none.
+func (g *GoAppDB) FindPrincipalByEmail(principal string, email string) (primitive.M, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	var res bson.M
+
+	filter := bson.D{{Key: "email", Value: email}}
+
+	err := Principal(g.DB, principal).FindOne(ctx, filter).Decode(&res)
+	if err != nil {
+		return nil, dbError(err, principal)
+	}
+
+	return res, nil
+}
+
+// GetPrincipalByID returns the user, admin or cse with the given id.
+func (g *GoAppDB) GetPrincipalByID(principal string, id primitive.ObjectID) (primitive.M, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	var res bson.M
+
+	err := Principal(g.DB, principal).FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Decode(&res)
+	if err != nil {
+		return nil, dbError(err, principal)
+	}
+
+	return res, nil
+}
+
 func (g *GoAppDB) SignOutUser(userID primitive.ObjectID) (bool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
 	defer cancel()
 
 	filter := bson.D{{Key: "_id", Value: userID}}
-	update := bson.D{{Key: "$set", Value: bson.D{{Key: "token", Value: ""}, {Key: "new_token", Value: ""}}}}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "token", Value: ""}, {Key: "new_token", Value: ""}, {Key: "token_family", Value: ""}}}}
 
 	_, err := User(g.DB, "user").UpdateOne(ctx, filter, update)
 	if err != nil {
-		g.App.ErrorLogger.Fatalf("cannot update user's tokens in the database : %v ", err)
-		return false, err
+		g.App.ErrorLogger.Printf("cannot update user's tokens in the database : %v ", err)
+		return false, dbError(err, "user")
 	}
 	return true, nil
 
@@ -185,12 +296,12 @@ func (g *GoAppDB) SignOutAdmin(adminID primitive.ObjectID) (bool, error) {
 	defer cancel()
 
 	filter := bson.D{{Key: "_id", Value: adminID}}
-	update := bson.D{{Key: "$set", Value: bson.D{{Key: "token", Value: ""}, {Key: "new_token", Value: ""}}}}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "token", Value: ""}, {Key: "new_token", Value: ""}, {Key: "token_family", Value: ""}}}}
 
 	_, err := User(g.DB, "admin").UpdateOne(ctx, filter, update)
 	if err != nil {
-		g.App.ErrorLogger.Fatalf("cannot update user's tokens in the database : %v ", err)
-		return false, err
+		g.App.ErrorLogger.Printf("cannot update user's tokens in the database : %v ", err)
+		return false, dbError(err, "admin")
 	}
 	return true, nil
 
@@ -215,15 +326,22 @@ func (g *GoAppDB) InsertProduct(product *model.Product) (bool, int, error) {
 		if err == mongo.ErrNoDocuments {
 
 			product.ID = primitive.NewObjectID()
+			prepareVariants(product)
 			_, insertErr := Product(g.DB, "product").InsertOne(ctx, product)
 			if insertErr != nil {
-				g.App.ErrorLogger.Fatalf("cannot add product to the database : %v ", insertErr)
+				g.App.ErrorLogger.Printf("cannot add product to the database : %v ", insertErr)
+				return false, 0, dbError(insertErr, "product")
+			}
+
+			if err := g.summarizeVariants(ctx, bson.D{{Key: "_id", Value: product.ID}}); err != nil {
+				return false, 0, err
 			}
 
 			return true, 1, nil
 		}
 
-		g.App.ErrorLogger.Fatalf("cannot execute the database query perfectly : %v ", err)
+		g.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return false, 0, dbError(err, "product")
 	}
 
 	return true, 2, nil
@@ -244,7 +362,7 @@ func (g *GoAppDB) InsertMultipleProductsBulk(products []*model.Product) (int, in
 	cursor, err := Product(g.DB, "product").Find(ctx, filter)
 	if err != nil {
 		g.App.ErrorLogger.Printf("Error querying existing products: %v", err)
-		return 0, 0, err
+		return 0, 0, dbError(err, "product")
 	}
 
 	// Create a map of existing product names
@@ -252,7 +370,7 @@ func (g *GoAppDB) InsertMultipleProductsBulk(products []*model.Product) (int, in
 	var results []bson.M
 	if err = cursor.All(ctx, &results); err != nil {
 		g.App.ErrorLogger.Printf("Error processing cursor: %v", err)
-		return 0, 0, err
+		return 0, 0, dbError(err, "product")
 	}
 
 	for _, result := range results {
@@ -266,6 +384,7 @@ func (g *GoAppDB) InsertMultipleProductsBulk(products []*model.Product) (int, in
 	for _, product := range products {
 		if _, exists := existingProducts[product.Name]; !exists {
 			product.ID = primitive.NewObjectID()
+			prepareVariants(product)
 			product.CreatedAt, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 			product.UpdatedAt = product.CreatedAt
 			newProducts = append(newProducts, product)
@@ -277,7 +396,11 @@ func (g *GoAppDB) InsertMultipleProductsBulk(products []*model.Product) (int, in
 		result, err := Product(g.DB, "product").InsertMany(ctx, newProducts)
 		if err != nil {
 			g.App.ErrorLogger.Printf("Error bulk inserting products: %v", err)
-			return 0, len(existingProducts), err
+			return 0, len(existingProducts), dbError(err, "product")
+		}
+		filter := bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: result.InsertedIDs}}}}
+		if err := g.summarizeVariants(ctx, filter); err != nil {
+			return len(result.InsertedIDs), len(existingProducts), err
 		}
 		return len(result.InsertedIDs), len(existingProducts), nil
 	}
@@ -297,8 +420,8 @@ func (g *GoAppDB) Update_Stock(id primitive.ObjectID, new_stock int) (bool, erro
 	updateResult, err := User(g.DB, "product").UpdateOne(ctx, filter, update)
 
 	if err != nil {
-		g.App.ErrorLogger.Fatalf("cannot update product's stock in the database : %v ", err)
-		return false, err
+		g.App.ErrorLogger.Printf("cannot update product's stock in the database : %v ", err)
+		return false, dbError(err, "product")
 	}
 
 	g.App.InfoLogger.Printf("Matched %v documents and updated %v documents.\n", updateResult.MatchedCount, updateResult.ModifiedCount)
@@ -306,77 +429,6 @@ func (g *GoAppDB) Update_Stock(id primitive.ObjectID, new_stock int) (bool, erro
 	return true, nil
 }
 
-func (g *GoAppDB) CreateNewPassword(email string, password string) (bool, error) {
-
-	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
-
-	hashed_Password, err := encrypt.Hash(password)
-
-	if err != nil {
-		g.App.ErrorLogger.Fatalf("cannot hash password : %v ", err)
-		return false, err
-	}
-
-	defer cancel()
-
-	filter := bson.D{{Key: "email", Value: email}}
-	update := bson.D{{Key: "$set", Value: bson.D{{Key: "password", Value: hashed_Password}}}}
-
-	_, err = User(g.DB, "user").UpdateOne(ctx, filter, update)
-	if err != nil {
-		g.App.ErrorLogger.Fatalf("cannot update user's password in the database : %v ", err)
-		return false, err
-	}
-
-	fmt.Println("Creating new password...")
-	return true, nil
-}
-
-func (ga *GoAppDB) CreateNewPasswordAdmin(email string, password string) (bool, error) {
-
-	fmt.Println("Creating new password...")
-
-	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
-	defer cancel()
-
-	hashed_Password, err := encrypt.Hash(password)
-
-	if err != nil {
-		ga.App.ErrorLogger.Fatalf("cannot hash password : %v ", err)
-		return false, err
-	}
-
-	filter := bson.D{{Key: "email", Value: email}}
-	update := bson.D{{Key: "$set", Value: bson.D{{Key: "password", Value: hashed_Password}}}}
-
-	_, err = User(ga.DB, "admin").UpdateOne(ctx, filter, update)
-	if err != nil {
-		ga.App.ErrorLogger.Fatalf("cannot update user's password in the database : %v ", err)
-		return false, err
-	}
-
-	fmt.Println("Created new password...")
-
-	return true, nil
-}
-
-func (g *GoAppDB) UpdateEmailUser(current_email string, new_email string) (bool, error) {
-
-	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
-
-	defer cancel()
-
-	filter := bson.D{{Key: "email", Value: current_email}}
-	update := bson.D{{Key: "$set", Value: bson.D{{Key: "email", Value: new_email}}}}
-
-	_, err := User(g.DB, "user").UpdateOne(ctx, filter, update)
-	if err != nil {
-		g.App.ErrorLogger.Fatalf("cannot update user's email in the database : %v ", err)
-		return false, err
-	}
-	return true, nil
-}
-
 func (g *GoAppDB) UpdateEmailAdmin(current_email string, new_email string) (bool, error) {
 
 	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
@@ -388,8 +440,8 @@ func (g *GoAppDB) UpdateEmailAdmin(current_email string, new_email string) (bool
 
 	_, err := User(g.DB, "admin").UpdateOne(ctx, filter, update)
 	if err != nil {
-		g.App.ErrorLogger.Fatalf("cannot update user's email in the database : %v ", err)
-		return false, err
+		g.App.ErrorLogger.Printf("cannot update user's email in the database : %v ", err)
+		return false, dbError(err, "admin")
 	}
 	return true, nil
 }
@@ -405,8 +457,8 @@ func (g *GoAppDB) UpdateNameUser(email string, new_name string) (bool, error) {
 
 	_, err := User(g.DB, "user").UpdateOne(ctx, filter, update)
 	if err != nil {
-		g.App.ErrorLogger.Fatalf("cannot update user's name in the database : %v ", err)
-		return false, err
+		g.App.ErrorLogger.Printf("cannot update user's name in the database : %v ", err)
+		return false, dbError(err, "user")
 	}
 	return true, nil
 }
@@ -421,8 +473,8 @@ func (g *GoAppDB) UpdateNameAdmin(email string, new_name string) (bool, error) {
 
 	_, err := User(g.DB, "admin").UpdateOne(ctx, filter, update)
 	if err != nil {
-		g.App.ErrorLogger.Fatalf("cannot update user's name in the database : %v ", err)
-		return false, err
+		g.App.ErrorLogger.Printf("cannot update user's name in the database : %v ", err)
+		return false, dbError(err, "admin")
 	}
 	return true, nil
 }
@@ -437,8 +489,8 @@ func (g *GoAppDB) UpdatePhoneUser(email string, new_phone string) (bool, error)
 
 	_, err := User(g.DB, "user").UpdateOne(ctx, filter, update)
 	if err != nil {
-		g.App.ErrorLogger.Fatalf("cannot update user's phone in the database : %v ", err)
-		return false, err
+		g.App.ErrorLogger.Printf("cannot update user's phone in the database : %v ", err)
+		return false, dbError(err, "user")
 	}
 	return true, nil
 }
@@ -453,64 +505,11 @@ func (g *GoAppDB) UpdatePhoneAdmin(email string, new_phone string) (bool, error)
 
 	_, err := User(g.DB, "admin").UpdateOne(ctx, filter, update)
 	if err != nil {
-		g.App.ErrorLogger.Fatalf("cannot update user's phone in the database : %v ", err)
-		return false, err
+		g.App.ErrorLogger.Printf("cannot update user's phone in the database : %v ", err)
+		return false, dbError(err, "admin")
 	}
 	return true, nil
 }
-func (g *GoAppDB) ViewProducts() ([]primitive.M, error) {
-
-	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
-	defer cancel()
-
-	var res []primitive.M
-	cursor, err := Product(g.DB, "product").Find(ctx, bson.D{})
-	if err != nil {
-		g.App.ErrorLogger.Fatalf("cannot execute the database query perfectly : %v ", err)
-		return nil, err
-	}
-
-	if err = cursor.All(ctx, &res); err != nil {
-		g.App.ErrorLogger.Fatalf("cannot execute the database query perfectly : %v ", err)
-		return nil, err
-	}
-
-	return res, nil
-}
-
-func (g *GoAppDB) CreateCategory(category *model.Category) (bool, int, error) {
-	fmt.Println("Inserting category...")
-
-	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
-	defer cancel()
-
-	filter := bson.D{{Key: "name", Value: category.Name}}
-
-	var res bson.M
-
-	err := User(g.DB, "category").FindOne(ctx, filter).Decode(&res)
-
-	if err != nil {
-
-		if err == mongo.ErrNoDocuments {
-
-			category.ID = primitive.NewObjectID()
-			_, insertErr := User(g.DB, "category").InsertOne(ctx, category)
-
-			if insertErr != nil {
-				g.App.ErrorLogger.Fatalf("cannot add category to the database : %v ", insertErr)
-			}
-
-			return true, 1, nil
-		}
-
-		g.App.ErrorLogger.Fatalf("cannot execute the database query perfectly : %v ", err)
-
-	}
-
-	return true, 2, nil
-}
-
 func (g *GoAppDB) UpdateProduct(product *model.Product) (bool, error) {
 
 	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
@@ -521,34 +520,39 @@ func (g *GoAppDB) UpdateProduct(product *model.Product) (bool, error) {
 		{Key: "$set", Value: bson.D{
 			{Key: "name", Value: product.Name},
 			{Key: "description", Value: product.Description},
-			{Key: "description.dimension", Value: product.Description.Dimension},
-
+			{Key: "specs", Value: product.Specs},
 			{Key: "category", Value: product.Category},
+			{Key: "category_id", Value: product.CategoryID},
 			{Key: "company_name", Value: product.Company_Name},
 			{Key: "model_name", Value: product.Model_Name},
 			{Key: "regularprice", Value: product.RegularPrice},
 			{Key: "saleprice", Value: product.SalePrice},
 			{Key: "salestarts", Value: product.SaleStarts},
 			{Key: "saleends", Value: product.SaleEnds},
+			{Key: "sale_timezone", Value: product.SaleTimezone},
 			{Key: "instock", Value: product.InStock},
 			{Key: "sku", Value: product.SKU},
-			{Key: "createdat", Value: product.CreatedAt},
+			// The request carries the whole list of images, so it replaces the stored one.
+			{Key: "images", Value: product.Images},
 			{Key: "updatedat", Value: time.Now()},
 		}},
-		{Key: "$push", Value: bson.D{
-			{Key: "images", Value: bson.D{
-				{Key: "$each", Value: product.Images},
-			}},
-		}},
 	}
 
 	updateDetails, err := Product(g.DB, "product").UpdateOne(ctx, filter, update)
 	if err != nil {
-		g.App.ErrorLogger.Fatalf("cannot update product in the database : %v ", err)
-		return false, err
+		g.App.ErrorLogger.Printf("cannot update product in the database : %v ", err)
+		return false, dbError(err, "product")
 	}
 
 	g.App.InfoLogger.Printf("Matched %v documents and updated %v documents.\n", updateDetails.MatchedCount, updateDetails.ModifiedCount)
+	if updateDetails.MatchedCount == 0 {
+		return false, domain.NotFound("product not found")
+	}
+
+	// Prices and stock of a product sold in variants remain theirs.
+	if err := g.summarizeVariants(ctx, filter); err != nil {
+		return false, err
+	}
 	return true, nil
 }
 
@@ -564,8 +568,8 @@ func (g *GoAppDB) Toggle_Stock(Id primitive.ObjectID) (bool, error) {
 	err := Product(g.DB, "product").FindOne(ctx, filter).Decode(&res)
 
 	if err != nil {
-		g.App.ErrorLogger.Fatalf("cannot execute the database query perfectly : %v ", err)
-		return false, err
+		g.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return false, dbError(err, "product")
 	}
 
 	in_stock := res["in_stock"].(bool)
@@ -574,8 +578,8 @@ func (g *GoAppDB) Toggle_Stock(Id primitive.ObjectID) (bool, error) {
 
 	updateDetails, err := Product(g.DB, "product").UpdateOne(ctx, filter, update)
 	if err != nil {
-		g.App.ErrorLogger.Fatalf("cannot update product in the database : %v ", err)
-		return false, err
+		g.App.ErrorLogger.Printf("cannot update product in the database : %v ", err)
+		return false, dbError(err, "product")
 	}
 
 	g.App.InfoLogger.Printf("Matched %v documents and updated %v documents.\n", updateDetails.MatchedCount, updateDetails.ModifiedCount)
@@ -594,8 +598,8 @@ func (g *GoAppDB) AddProductToWishlist(Product_Id primitive.ObjectID, User_Id pr
 	updateDetails, err := User(g.DB, "user").UpdateOne(ctx, filter, update)
 
 	if err != nil {
-		g.App.ErrorLogger.Fatalf("cannot update product in the database : %v ", err)
-		return false, err
+		g.App.ErrorLogger.Printf("cannot update product in the database : %v ", err)
+		return false, dbError(err, "user")
 	}
 
 	g.App.InfoLogger.Printf("Matched %v documents and updated %v documents.\n", updateDetails.MatchedCount, updateDetails.ModifiedCount)
@@ -615,8 +619,8 @@ func (g *GoAppDB) RemoveProductFromWishlist(Product_Id primitive.ObjectID, User_
 	updateDetails, err := User(g.DB, "user").UpdateOne(ctx, filter, update)
 
 	if err != nil {
-		g.App.ErrorLogger.Fatalf("cannot update product in the database : %v ", err)
-		return false, err
+		g.App.ErrorLogger.Printf("cannot update product in the database : %v ", err)
+		return false, dbError(err, "user")
 	}
 
 	g.App.InfoLogger.Printf("Matched %v documents and updated %v documents.\n", updateDetails.MatchedCount, updateDetails.ModifiedCount)
@@ -636,8 +640,8 @@ func (g *GoAppDB) GetSingleProduct(Id primitive.ObjectID) (primitive.M, error) {
 	err := Product(g.DB, "product").FindOne(ctx, filter).Decode(&res)
 
 	if err != nil {
-		g.App.ErrorLogger.Fatalf("cannot execute the database query perfectly : %v ", err)
-		return nil, err
+		g.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return nil, dbError(err, "product")
 	}
 
 	return res, nil
@@ -656,8 +660,8 @@ func (g *GoAppDB) AddToCart(userID primitive.ObjectID, cartItems *model.CartItem
 	updateDetails, err := User(g.DB, "user").UpdateOne(ctx, filter, update)
 
 	if err != nil {
-		g.App.ErrorLogger.Fatalf("cannot update product in the database : %v ", err)
-		return false, err
+		g.App.ErrorLogger.Printf("cannot update product in the database : %v ", err)
+		return false, dbError(err, "user")
 	}
 
 	g.App.InfoLogger.Printf("Matched %v documents and updated %v documents.\n", updateDetails.MatchedCount, updateDetails.ModifiedCount)
@@ -678,8 +682,8 @@ func (g *GoAppDB) Empty_the_Cart(userID primitive.ObjectID) (bool, error) {
 	updateDetails, err := User(g.DB, "user").UpdateOne(ctx, filter, update)
 
 	if err != nil {
-		g.App.ErrorLogger.Fatalf("cannot update product in the database : %v ", err)
-		return false, err
+		g.App.ErrorLogger.Printf("cannot update product in the database : %v ", err)
+		return false, dbError(err, "user")
 	}
 
 	g.App.InfoLogger.Printf("Matched %v documents and updated %v documents.\n", updateDetails.MatchedCount, updateDetails.ModifiedCount)
@@ -688,7 +692,8 @@ func (g *GoAppDB) Empty_the_Cart(userID primitive.ObjectID) (bool, error) {
 
 }
 
-func (g *GoAppDB) RemoveFromCart(userID primitive.ObjectID, productID primitive.ObjectID) (bool, error) {
+// RemoveFromCart removes a product from the cart; when variantID is set, only that variant of it.
+func (g *GoAppDB) RemoveFromCart(userID primitive.ObjectID, productID primitive.ObjectID, variantID primitive.ObjectID) (bool, error) {
 
 	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
 
@@ -696,13 +701,18 @@ func (g *GoAppDB) RemoveFromCart(userID primitive.ObjectID, productID primitive.
 
 	filter := bson.D{{Key: "_id", Value: userID}}
 
-	update := bson.D{{Key: "$pull", Value: bson.D{{Key: "cart", Value: bson.M{"product_id": productID}}}}}
+	item := bson.M{"productid": productID}
+	if !variantID.IsZero() {
+		item["variant_id"] = variantID
+	}
+
+	update := bson.D{{Key: "$pull", Value: bson.D{{Key: "cart", Value: item}}}}
 
 	updateDetails, err := User(g.DB, "user").UpdateOne(ctx, filter, update)
 
 	if err != nil {
-		g.App.ErrorLogger.Fatalf("cannot update product in the database : %v ", err)
-		return false, err
+		g.App.ErrorLogger.Printf("cannot update product in the database : %v ", err)
+		return false, dbError(err, "user")
 	}
 
 	g.App.InfoLogger.Printf("Matched %v documents and updated %v documents.\n", updateDetails.MatchedCount, updateDetails.ModifiedCount)
@@ -719,42 +729,19 @@ func (g *GoAppDB) GetAllUsers() ([]bson.M, error) {
 	cursor, err := User(g.DB, "user").Find(ctx, bson.D{})
 
 	if err != nil {
-		g.App.ErrorLogger.Fatalf("cannot execute the database query perfectly : %v ", err)
-		return nil, err
+		g.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return nil, dbError(err, "user")
 	}
 
 	if err = cursor.All(ctx, &res); err != nil {
-		g.App.ErrorLogger.Fatalf("cannot execute the database query perfectly : %v ", err)
-		return nil, err
+		g.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return nil, dbError(err, "user")
 	}
 
 	return res, nil
 
 }
 
-func (g *GoAppDB) GetAllCategories() ([]bson.M, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
-	defer cancel()
-
-	var res []bson.M
-
-	cursor, err := User(g.DB, "category").Find(ctx, bson.D{})
-
-	if err != nil {
-		g.App.ErrorLogger.Fatalf("cannot execute the database query perfectly : %v ", err)
-		return nil, err
-	}
-
-	if err = cursor.All(ctx, &res); err != nil {
-		g.App.ErrorLogger.Fatalf("cannot execute the database query perfectly : %v ", err)
-		return nil, err
-	}
-
-	fmt.Println("res : ", res)
-
-	return res, nil
-}
-
 func (g *GoAppDB) InitializeUser(userId primitive.ObjectID) (bool, error) {
 
 	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
@@ -770,8 +757,8 @@ func (g *GoAppDB) InitializeUser(userId primitive.ObjectID) (bool, error) {
 	_, err := User(g.DB, "user").UpdateOne(ctx, filter, update)
 
 	if err != nil {
-		g.App.ErrorLogger.Fatalf("cannot update product in the database : %v ", err)
-		return false, err
+		g.App.ErrorLogger.Printf("cannot update product in the database : %v ", err)
+		return false, dbError(err, "user")
 	}
 
 	return true, nil
@@ -790,8 +777,8 @@ func (g *GoAppDB) GetUserByID(userId primitive.ObjectID) (primitive.M, error) {
 	err := User(g.DB, "user").FindOne(ctx, filter).Decode(&res)
 
 	if err != nil {
-		g.App.ErrorLogger.Fatalf("could not fetch the user from the database : %v ", err)
-		return nil, err
+		g.App.ErrorLogger.Printf("could not fetch the user from the database : %v ", err)
+		return nil, dbError(err, "user")
 	}
 
 	return res, nil
@@ -809,8 +796,8 @@ func (g *GoAppDB) CreateOrder(order *model.Order) (primitive.M, error) {
 
 	cr, err := User(g.DB, "orders").InsertOne(ctx, order)
 	if err != nil {
-		g.App.ErrorLogger.Fatalf("cannot insert order in the database : %v ", err)
-		return nil, err
+		g.App.ErrorLogger.Printf("cannot insert order in the database : %v ", err)
+		return nil, dbError(err, "order")
 	}
 
 	g.App.InfoLogger.Printf("Inserted order with id : %v", cr.InsertedID)
@@ -822,8 +809,8 @@ func (g *GoAppDB) CreateOrder(order *model.Order) (primitive.M, error) {
 	err = User(g.DB, "orders").FindOne(ctx, filter).Decode(&res)
 
 	if err != nil {
-		g.App.ErrorLogger.Fatalf("could not fetch the user from the database : %v ", err)
-		return nil, err
+		g.App.ErrorLogger.Printf("could not fetch the user from the database : %v ", err)
+		return nil, dbError(err, "order")
 	}
 	return res, nil
 }
@@ -840,8 +827,8 @@ func (g *GoAppDB) InsertOrdertoUser(userID primitive.ObjectID, OrderId primitive
 	_, err := User(g.DB, "user").UpdateOne(ctx, filter, update)
 
 	if err != nil {
-		g.App.ErrorLogger.Fatalf("cannot insert order in the database : %v ", err)
-		return false, err
+		g.App.ErrorLogger.Printf("cannot insert order in the database : %v ", err)
+		return false, dbError(err, "user")
 	}
 	return true, nil
 }
@@ -859,19 +846,16 @@ func (g *GoAppDB) UpdatePaymentToIncludeOrderId(paymentId primitive.ObjectID, or
 	updateDetails, err := User(g.DB, "payment").UpdateOne(ctx, filter, update)
 
 	if err != nil {
-		g.App.ErrorLogger.Fatalf("cannot update the payment to include the order : %v ", err)
-		return false, err
+		g.App.ErrorLogger.Printf("cannot update the payment to include the order : %v ", err)
+		return false, dbError(err, "payment")
 	}
 
 	if updateDetails.MatchedCount == 0 {
-		g.App.ErrorLogger.Fatalf("No matching payment could be found. Recheck your Payload")
-		return false, err
-	} else if updateDetails.ModifiedCount == 0 {
-		g.App.ErrorLogger.Fatalf("No matching payment could be found. Recheck your Payload")
-		return false, err
+		g.App.ErrorLogger.Println("no payment matched the update")
+		return false, domain.NotFound("payment not found")
 	}
 
-	return true, err
+	return true, nil
 }
 
 func (ga *GoAppDB) FindUserWithEmail(email string) (primitive.M, error) {
@@ -886,8 +870,8 @@ func (ga *GoAppDB) FindUserWithEmail(email string) (primitive.M, error) {
 	err := User(ga.DB, "user").FindOne(ctx, filter).Decode(&res)
 
 	if err != nil {
-		ga.App.ErrorLogger.Fatalf("cannot execute the database query perfectly : %v ", err)
-		return nil, err
+		ga.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return nil, dbError(err, "user")
 	}
 
 	return res, nil
@@ -921,7 +905,9 @@ func (ga *GoAppDB) GetUserOrders(userId primitive.ObjectID) ([]primitive.M, erro
 			{Key: "userID", Value: userId},
 			{Key: "productID", Value: "$productDetails._id"},
 			{Key: "productName", Value: "$productDetails.name"},
-			{Key: "price", Value: "$productDetails.saleprice"},
+			{Key: "variantID", Value: "$userOrders.order_items.orderitems.variant_id"},
+			{Key: "variant", Value: orderedVariant("userOrders.order_items.orderitems")},
+			{Key: "price", Value: orderedPrice("userOrders.order_items.orderitems")},
 			{Key: "quantity", Value: "$userOrders.order_items.orderitems.quantity"},
 			{Key: "order_amount", Value: "$userOrders.order_amount"},
 			{Key: "order_date", Value: "$userOrders.order_date"},
@@ -935,8 +921,8 @@ func (ga *GoAppDB) GetUserOrders(userId primitive.ObjectID) ([]primitive.M, erro
 	cursor, err := User(ga.DB, "user").Aggregate(ctx, pipeline)
 
 	if err != nil {
-		ga.App.ErrorLogger.Fatalf("cannot execute the database query perfectly : %v ", err)
-		return nil, err
+		ga.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return nil, dbError(err, "user")
 	}
 
 	defer cursor.Close(ctx)
@@ -945,8 +931,8 @@ func (ga *GoAppDB) GetUserOrders(userId primitive.ObjectID) ([]primitive.M, erro
 	fmt.Printf("Pipeline execution result: %+v\n", res)
 
 	if err := cursor.All(ctx, &res); err != nil {
-		ga.App.ErrorLogger.Fatalf("cannot execute the database query perfectly. There is some problem in cursor : %v ", err)
-		return nil, err
+		ga.App.ErrorLogger.Printf("cannot execute the database query perfectly. There is some problem in cursor : %v ", err)
+		return nil, dbError(err, "user")
 	}
 
 	fmt.Printf("Pipeline execution result: %+v\n", res)
@@ -979,7 +965,9 @@ func (ga *GoAppDB) GetAllOrders() ([]primitive.M, error) {
 			{Key: "_id", Value: 0},
 			{Key: "productID", Value: "$productDetails._id"},
 			{Key: "productName", Value: "$productDetails.name"},
-			{Key: "price", Value: "$productDetails.saleprice"},
+			{Key: "variantID", Value: "$userOrders.order_items.orderitems.variant_id"},
+			{Key: "variant", Value: orderedVariant("userOrders.order_items.orderitems")},
+			{Key: "price", Value: orderedPrice("userOrders.order_items.orderitems")},
 			{Key: "quantity", Value: "$userOrders.order_items.orderitems.quantity"},
 			{Key: "order_amount", Value: "$userOrders.order_amount"},
 			{Key: "order_date", Value: "$userOrders.order_date"},
@@ -996,8 +984,8 @@ func (ga *GoAppDB) GetAllOrders() ([]primitive.M, error) {
 	cursor, err := User(ga.DB, "user").Aggregate(ctx, pipeline)
 
 	if err != nil {
-		ga.App.ErrorLogger.Fatalf("cannot execute the database query perfectly : %v ", err)
-		return nil, err
+		ga.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return nil, dbError(err, "user")
 	}
 
 	defer cursor.Close(ctx)
@@ -1006,8 +994,8 @@ func (ga *GoAppDB) GetAllOrders() ([]primitive.M, error) {
 	fmt.Printf("Pipeline execution result: %+v\n", res)
 
 	if err := cursor.All(ctx, &res); err != nil {
-		ga.App.ErrorLogger.Fatalf("cannot execute the database query perfectly. There is some problem in cursor : %v ", err)
-		return nil, err
+		ga.App.ErrorLogger.Printf("cannot execute the database query perfectly. There is some problem in cursor : %v ", err)
+		return nil, dbError(err, "user")
 	}
 
 	return res, nil
@@ -1023,8 +1011,8 @@ func (ga *GoAppDB) DeleteProduct(id primitive.ObjectID) (bool, error) {
 	_, err := Product(ga.DB, "product").DeleteOne(ctx, filter)
 
 	if err != nil {
-		ga.App.ErrorLogger.Fatalf("cannot execute the database query perfectly : %v ", err)
-		return false, err
+		ga.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return false, dbError(err, "product")
 	}
 
 	return true, nil
@@ -1042,8 +1030,8 @@ func (ga *GoAppDB) DeleteOrder(id primitive.ObjectID) (bool, error) {
 	err := User(ga.DB, "orders").FindOne(ctx, filter).Decode(&res)
 
 	if err != nil {
-		ga.App.ErrorLogger.Fatalf("cannot execute the database query perfectly : %v ", err)
-		return false, err
+		ga.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return false, dbError(err, "order")
 	}
 
 	filter = bson.D{{Key: "_id", Value: res["customer_id"]}}
@@ -1053,8 +1041,8 @@ func (ga *GoAppDB) DeleteOrder(id primitive.ObjectID) (bool, error) {
 	updateInformation, err := User(ga.DB, "user").UpdateOne(ctx, filter, update)
 
 	if err != nil {
-		ga.App.ErrorLogger.Fatalf("cannot execute the database query perfectly : %v ", err)
-		return false, err
+		ga.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return false, dbError(err, "user")
 	}
 
 	ga.App.InfoLogger.Printf("Matched %v documents and updated %v documents.\n", updateInformation.MatchedCount, updateInformation.ModifiedCount)
@@ -1062,8 +1050,8 @@ func (ga *GoAppDB) DeleteOrder(id primitive.ObjectID) (bool, error) {
 	_, err = User(ga.DB, "orders").DeleteOne(ctx, filter)
 
 	if err != nil {
-		ga.App.ErrorLogger.Fatalf("cannot execute the database query perfectly : %v ", err)
-		return false, err
+		ga.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return false, dbError(err, "order")
 	}
 
 	return true, nil
@@ -1075,8 +1063,8 @@ func (ga *GoAppDB) ShipmentCreation(shipment *model.Shipment) (primitive.M, erro
 
 	cr, err := User(ga.DB, "shipment").InsertOne(ctx, shipment)
 	if err != nil {
-		ga.App.ErrorLogger.Fatalf("cannot insert shipment in the database : %v ", err)
-		return nil, err
+		ga.App.ErrorLogger.Printf("cannot insert shipment in the database : %v ", err)
+		return nil, dbError(err, "shipment")
 	}
 
 	filter1 := bson.D{{Key: "_id", Value: shipment.CustomerID}}
@@ -1085,17 +1073,14 @@ func (ga *GoAppDB) ShipmentCreation(shipment *model.Shipment) (primitive.M, erro
 
 	updateDetails, err := User(ga.DB, "user").UpdateOne(ctx, filter1, update)
 	if err != nil {
-		ga.App.ErrorLogger.Fatalf("cannot execute the database query perfectly : %v ", err)
-		return nil, err
+		ga.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return nil, dbError(err, "user")
 	}
 
 	ga.App.InfoLogger.Printf("Matched %v documents and updated %v documents.\n", updateDetails.MatchedCount, updateDetails.ModifiedCount)
 	if updateDetails.MatchedCount == 0 {
-		ga.App.ErrorLogger.Fatalf("cannot execute the database query perfectly as matched count is 0: %v ", err)
-		return nil, err
-	} else if updateDetails.ModifiedCount == 0 {
-		ga.App.ErrorLogger.Fatalf("cannot execute the database query perfectly as modified count is 0: %v ", err)
-		return nil, err
+		ga.App.ErrorLogger.Println("no user matched the update")
+		return nil, domain.NotFound("user not found")
 	}
 
 	var res primitive.M
@@ -1105,8 +1090,8 @@ func (ga *GoAppDB) ShipmentCreation(shipment *model.Shipment) (primitive.M, erro
 	err = User(ga.DB, "shipment").FindOne(ctx, filter).Decode(&res)
 
 	if err != nil {
-		ga.App.ErrorLogger.Fatalf("cannot execute the database query perfectly : %v ", err)
-		return nil, err
+		ga.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return nil, dbError(err, "shipment")
 	}
 
 	return res, nil
@@ -1118,8 +1103,8 @@ func (ga *GoAppDB) PaymentCreation(payment *model.Payment) (primitive.M, error)
 
 	cr, err := User(ga.DB, "payment").InsertOne(ctx, payment)
 	if err != nil {
-		ga.App.ErrorLogger.Fatalf("cannot insert payment in the database : %v ", err)
-		return nil, err
+		ga.App.ErrorLogger.Printf("cannot insert payment in the database : %v ", err)
+		return nil, dbError(err, "payment")
 	}
 	fmt.Println(cr)
 
@@ -1129,17 +1114,14 @@ func (ga *GoAppDB) PaymentCreation(payment *model.Payment) (primitive.M, error)
 
 	updateDetails, err := User(ga.DB, "user").UpdateOne(ctx, userFilter, update)
 	if err != nil {
-		ga.App.ErrorLogger.Fatalf("cannot execute the database query perfectly : %v ", err)
-		return nil, err
+		ga.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return nil, dbError(err, "user")
 	}
 
 	ga.App.InfoLogger.Printf("Matched %v documents and updated %v documents.\n", updateDetails.MatchedCount, updateDetails.ModifiedCount)
 	if updateDetails.MatchedCount == 0 {
-		ga.App.ErrorLogger.Fatalf("cannot execute the database query perfectly as matched count is 0: %v ", err)
-		return nil, err
-	} else if updateDetails.ModifiedCount == 0 {
-		ga.App.ErrorLogger.Fatalf("cannot execute the database query perfectly as modified count is 0: %v ", err)
-		return nil, err
+		ga.App.ErrorLogger.Println("no user matched the update")
+		return nil, domain.NotFound("user not found")
 	}
 
 	filter := bson.D{{Key: "_id", Value: cr.InsertedID}}
@@ -1149,8 +1131,8 @@ func (ga *GoAppDB) PaymentCreation(payment *model.Payment) (primitive.M, error)
 	err = User(ga.DB, "payment").FindOne(ctx, filter).Decode(&res)
 
 	if err != nil {
-		ga.App.ErrorLogger.Fatalf("cannot execute the database query perfectly : %v ", err)
-		return nil, err
+		ga.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return nil, dbError(err, "payment")
 	}
 
 	return res, nil
@@ -1166,13 +1148,13 @@ func (ga *GoAppDB) GetAllShipments() ([]primitive.M, error) {
 	cursor, err := User(ga.DB, "shipment").Find(ctx, bson.D{})
 
 	if err != nil {
-		ga.App.ErrorLogger.Fatalf("cannot execute the database query perfectly : %v ", err)
-		return nil, err
+		ga.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return nil, dbError(err, "shipment")
 	}
 
 	if err = cursor.All(ctx, &res); err != nil {
-		ga.App.ErrorLogger.Fatalf("cannot execute the database query perfectly. There is some problem in cursor : %v ", err)
-		return nil, err
+		ga.App.ErrorLogger.Printf("cannot execute the database query perfectly. There is some problem in cursor : %v ", err)
+		return nil, dbError(err, "shipment")
 	}
 
 	return res, nil
@@ -1189,17 +1171,14 @@ func (ga *GoAppDB) AddAddress(userId primitive.ObjectID, address *model.Address)
 
 	updateDetails, err := User(ga.DB, "user").UpdateOne(ctx, filter, update)
 	if err != nil {
-		ga.App.ErrorLogger.Fatalf("cannot execute the database query perfectly : %v ", err)
-		return false, err
+		ga.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return false, dbError(err, "user")
 	}
 
 	ga.App.InfoLogger.Printf("Matched %v documents and updated %v documents.\n", updateDetails.MatchedCount, updateDetails.ModifiedCount)
 	if updateDetails.MatchedCount == 0 {
-		ga.App.ErrorLogger.Fatalf("cannot execute the database query perfectly as matched count is 0: %v ", err)
-		return false, err
-	} else if updateDetails.ModifiedCount == 0 {
-		ga.App.ErrorLogger.Fatalf("cannot execute the database query perfectly as modified count is 0: %v ", err)
-		return false, err
+		ga.App.ErrorLogger.Println("no user matched the update")
+		return false, domain.NotFound("user not found")
 	}
 
 	return true, nil
@@ -1215,13 +1194,13 @@ func (ga *GoAppDB) GetAllPayments() ([]primitive.M, error) {
 	cursor, err := User(ga.DB, "payment").Find(ctx, bson.D{})
 
 	if err != nil {
-		ga.App.ErrorLogger.Fatalf("cannot execute the database query perfectly : %v ", err)
-		return nil, err
+		ga.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return nil, dbError(err, "payment")
 	}
 
 	if err = cursor.All(ctx, &res); err != nil {
-		ga.App.ErrorLogger.Fatalf("cannot execute the database query perfectly. There is some problem in cursor : %v ", err)
-		return nil, err
+		ga.App.ErrorLogger.Printf("cannot execute the database query perfectly. There is some problem in cursor : %v ", err)
+		return nil, dbError(err, "payment")
 	}
 
 	return res, nil
@@ -1236,7 +1215,7 @@ func (ga *GoAppDB) InsertCSE(cse *model.CSE) (bool, int, error) {
 	if !regMail.MatchString(cse.Email) {
 
 		ga.App.ErrorLogger.Println("invalid registered details - email")
-		return false, 0, errors.New("invalid registered details - email")
+		return false, 0, domain.Validation("invalid registered details - email")
 
 	}
 
@@ -1250,11 +1229,13 @@ func (ga *GoAppDB) InsertCSE(cse *model.CSE) (bool, int, error) {
 			cse.ID = primitive.NewObjectID()
 			_, insertErr := User(ga.DB, "cses").InsertOne(ctx, cse)
 			if insertErr != nil {
-				ga.App.ErrorLogger.Fatalf("cannot add user to the database : %v ", insertErr)
+				ga.App.ErrorLogger.Printf("cannot add cse to the database : %v ", insertErr)
+				return false, 0, dbError(insertErr, "cse")
 			}
 			return true, 1, nil
 		}
-		ga.App.ErrorLogger.Fatal(err)
+		ga.App.ErrorLogger.Println(err)
+		return false, 0, dbError(err, "cse")
 	}
 	return true, 2, nil
 }
@@ -1268,13 +1249,13 @@ func (ga *GoAppDB) GetAllCSEs() ([]primitive.M, error) {
 	cursor, err := User(ga.DB, "cses").Find(ctx, bson.D{})
 
 	if err != nil {
-		ga.App.ErrorLogger.Fatalf("cannot execute the database query perfectly : %v ", err)
-		return nil, err
+		ga.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return nil, dbError(err, "cse")
 	}
 
 	if err = cursor.All(ctx, &res); err != nil {
-		ga.App.ErrorLogger.Fatalf("cannot execute the database query perfectly. There is some problem in cursor : %v ", err)
-		return nil, err
+		ga.App.ErrorLogger.Printf("cannot execute the database query perfectly. There is some problem in cursor : %v ", err)
+		return nil, dbError(err, "cse")
 	}
 
 	return res, nil
@@ -1293,9 +1274,10 @@ func (g *GoAppDB) GetCSEByCredentials(cseID string) (primitive.M, error) {
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			g.App.ErrorLogger.Println("no document found for this query")
-			return nil, err
+			return nil, dbError(err, "cse")
 		}
-		g.App.ErrorLogger.Fatalf("cannot execute the database query perfectly : %v ", err)
+		g.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return nil, dbError(err, "cse")
 	}
 
 	return res, nil
@@ -1311,7 +1293,7 @@ func (g *GoAppDB) UpdateCSEStatus(id primitive.ObjectID, status string) error {
 	_, err := User(g.DB, "cses").UpdateOne(ctx, filter, update)
 	if err != nil {
 		g.App.ErrorLogger.Printf("Error updating CSE status: %v", err)
-		return err
+		return dbError(err, "cse")
 	}
 
 	return nil
@@ -1333,20 +1315,20 @@ func (g *GoAppDB) CreateChat(chat *model.Chat) (primitive.ObjectID, error) {
 
 	if err != nil {
 		g.App.ErrorLogger.Printf("Error creating order with chat ID due to order not found: %v", err)
-		return primitive.NilObjectID, err
+		return primitive.NilObjectID, dbError(err, "order")
 	}
 
 	if res["chat_id"] != nil {
 		if res["chat_id"].(primitive.ObjectID) != primitive.NilObjectID {
 			g.App.ErrorLogger.Println("chat already exists for this order")
-			return primitive.NilObjectID, errors.New("chat already exists for this order")
+			return primitive.NilObjectID, domain.Conflict("chat already exists for this order")
 		} else { // The 'else' keyword should be on the same line as the closing brace of the 'if' block.
 			chat.ID = primitive.NewObjectID()
 
 			_, err = User(g.DB, "chats").InsertOne(ctx, chat)
 			if err != nil {
 				g.App.ErrorLogger.Printf("Error creating chat: %v", err)
-				return primitive.NilObjectID, err
+				return primitive.NilObjectID, dbError(err, "chat")
 			}
 
 			err = g.UpdateOrderWithChatID(orderID, chat.ID)
@@ -1374,7 +1356,7 @@ func (g *GoAppDB) UpdateOrderWithChatID(orderID primitive.ObjectID, chatID primi
 	_, err := User(g.DB, "orders").UpdateOne(ctx, filter, update)
 	if err != nil {
 		g.App.ErrorLogger.Printf("Error updating order with chat ID: %v", err)
-		return err
+		return dbError(err, "order")
 	}
 
 	return nil
@@ -1388,13 +1370,13 @@ func (g *GoAppDB) GetAllTheOrders() ([]primitive.M, error) {
 
 	cursor, err := User(g.DB, "orders").Find(ctx, bson.D{})
 	if err != nil {
-		g.App.ErrorLogger.Fatalf("cannot execute the database query perfectly. There is some problem in cursor : %v ", err)
-		return nil, err
+		g.App.ErrorLogger.Printf("cannot execute the database query perfectly. There is some problem in cursor : %v ", err)
+		return nil, dbError(err, "order")
 	}
 
 	if err = cursor.All(ctx, &res); err != nil {
-		g.App.ErrorLogger.Fatalf("cannot execute the database query perfectly. There is some problem in cursor : %v ", err)
-		return nil, err
+		g.App.ErrorLogger.Printf("cannot execute the database query perfectly. There is some problem in cursor : %v ", err)
+		return nil, dbError(err, "order")
 	}
 
 	return res, nil
@@ -1412,13 +1394,13 @@ func (g *GoAppDB) GetAvailableCSEs() ([]model.CSE, error) {
 	cursor, err := User(g.DB, "cses").Find(ctx, filter)
 	if err != nil {
 		g.App.ErrorLogger.Printf("Error finding available CSEs: %v", err)
-		return nil, err
+		return nil, dbError(err, "cse")
 	}
 	defer cursor.Close(ctx)
 
 	if err = cursor.All(ctx, &cses); err != nil {
 		g.App.ErrorLogger.Printf("Error decoding CSEs: %v", err)
-		return nil, err
+		return nil, dbError(err, "cse")
 	}
 
 	return cses, nil
@@ -1445,7 +1427,7 @@ func (g *GoAppDB) AssignChatToCSE(chatID, cseID primitive.ObjectID, isActive boo
 	_, err := User(g.DB, "chats").UpdateOne(ctx, chatFilter, chatUpdate)
 	if err != nil {
 		g.App.ErrorLogger.Printf("Error updating chat with CSE ID: %v", err)
-		return err
+		return dbError(err, "chat")
 	}
 
 	// Update CSE's chat lists and counts
@@ -1467,7 +1449,7 @@ func (g *GoAppDB) AssignChatToCSE(chatID, cseID primitive.ObjectID, isActive boo
 	_, err = User(g.DB, "cses").UpdateOne(ctx, cseFilter, cseUpdate)
 	if err != nil {
 		g.App.ErrorLogger.Printf("Error updating CSE with chat: %v", err)
-		return err
+		return dbError(err, "cse")
 	}
 
 	return nil
@@ -1484,7 +1466,7 @@ func (g *GoAppDB) GetChatByID(chatID primitive.ObjectID) (model.Chat, error) {
 	err := User(g.DB, "chats").FindOne(ctx, filter).Decode(&chat)
 	if err != nil {
 		g.App.ErrorLogger.Printf("Error finding chat: %v", err)
-		return chat, err
+		return chat, dbError(err, "chat")
 	}
 
 	return chat, nil
@@ -1502,7 +1484,7 @@ func (g *GoAppDB) UpdateChatStatus(chatID primitive.ObjectID, status string) err
 	_, err := User(g.DB, "chats").UpdateOne(ctx, filter, update)
 	if err != nil {
 		g.App.ErrorLogger.Printf("Error updating chat status: %v", err)
-		return err
+		return dbError(err, "chat")
 	}
 
 	return nil
@@ -1520,7 +1502,7 @@ func (g *GoAppDB) AddMessage(message *model.Message) (primitive.ObjectID, error)
 	_, err := User(g.DB, "messages").InsertOne(ctx, message)
 	if err != nil {
 		g.App.ErrorLogger.Printf("Error adding message: %v", err)
-		return primitive.NilObjectID, err
+		return primitive.NilObjectID, dbError(err, "message")
 	}
 
 	// Update chat with message ID and last message time
@@ -1533,7 +1515,7 @@ func (g *GoAppDB) AddMessage(message *model.Message) (primitive.ObjectID, error)
 	_, err = User(g.DB, "chats").UpdateOne(ctx, chatFilter, chatUpdate)
 	if err != nil {
 		g.App.ErrorLogger.Printf("Error updating chat with message: %v", err)
-		return message.ID, err // Return message ID anyway since message was created
+		return message.ID, dbError(err, "chat") // Return message ID anyway since message was created
 	}
 
 	return message.ID, nil
@@ -1554,13 +1536,13 @@ func (g *GoAppDB) GetMessagesByChat(chatID primitive.ObjectID) ([]model.Message,
 	cursor, err := User(g.DB, "messages").Find(ctx, filter, opts)
 	if err != nil {
 		g.App.ErrorLogger.Printf("Error finding messages: %v", err)
-		return nil, err
+		return nil, dbError(err, "message")
 	}
 	defer cursor.Close(ctx)
 
 	if err = cursor.All(ctx, &messages); err != nil {
 		g.App.ErrorLogger.Printf("Error decoding messages: %v", err)
-		return nil, err
+		return nil, dbError(err, "message")
 	}
 
 	return messages, nil
@@ -1578,7 +1560,7 @@ func (g *GoAppDB) UpdateChatLastMessageTime(chatID primitive.ObjectID) error {
 	_, err := User(g.DB, "chats").UpdateOne(ctx, filter, update)
 	if err != nil {
 		g.App.ErrorLogger.Printf("Error updating chat last message time: %v", err)
-		return err
+		return dbError(err, "chat")
 	}
 
 	return nil
@@ -1595,15 +1577,15 @@ func (g *GoAppDB) MoveChatFromPendingToActive(chatID, cseID primitive.ObjectID)
 	err := User(g.DB, "chats").FindOne(ctx, chatFilter).Decode(&chat)
 	if err != nil {
 		g.App.ErrorLogger.Printf("Error finding chat: %v", err)
-		return err
+		return dbError(err, "chat")
 	}
 
 	if chat.Status != "pending" {
-		return errors.New("chat is not in pending status")
+		return domain.Conflict("chat is not in pending status")
 	}
 
 	if chat.CseID != cseID {
-		return errors.New("chat is not assigned to this CSE")
+		return domain.Conflict("chat is not assigned to this CSE")
 	}
 
 	// Update chat status to active
@@ -1614,7 +1596,7 @@ func (g *GoAppDB) MoveChatFromPendingToActive(chatID, cseID primitive.ObjectID)
 	_, err = User(g.DB, "chats").UpdateOne(ctx, chatFilter, chatUpdate)
 	if err != nil {
 		g.App.ErrorLogger.Printf("Error updating chat status: %v", err)
-		return err
+		return dbError(err, "chat")
 	}
 
 	// Update CSE's collections - remove from pending, add to active
@@ -1631,7 +1613,7 @@ func (g *GoAppDB) MoveChatFromPendingToActive(chatID, cseID primitive.ObjectID)
 	_, err = User(g.DB, "cses").UpdateOne(ctx, cseFilter, cseUpdate)
 	if err != nil {
 		g.App.ErrorLogger.Printf("Error updating CSE collections: %v", err)
-		return err
+		return dbError(err, "cse")
 	}
 
 	return nil
@@ -1648,11 +1630,11 @@ func (g *GoAppDB) CloseChat(chatID primitive.ObjectID) error {
 	err := User(g.DB, "chats").FindOne(ctx, chatFilter).Decode(&chat)
 	if err != nil {
 		g.App.ErrorLogger.Printf("Error finding chat: %v", err)
-		return err
+		return dbError(err, "chat")
 	}
 
 	if chat.Status == "closed" {
-		return errors.New("chat is already closed")
+		return domain.Conflict("chat is already closed")
 	}
 
 	// Update chat status to closed and set close date
@@ -1666,7 +1648,7 @@ func (g *GoAppDB) CloseChat(chatID primitive.ObjectID) error {
 	_, err = User(g.DB, "chats").UpdateOne(ctx, chatFilter, chatUpdate)
 	if err != nil {
 		g.App.ErrorLogger.Printf("Error updating chat status: %v", err)
-		return err
+		return dbError(err, "chat")
 	}
 
 	// If a CSE is assigned, update their collections
@@ -1691,7 +1673,7 @@ func (g *GoAppDB) CloseChat(chatID primitive.ObjectID) error {
 		_, err = User(g.DB, "cses").UpdateOne(ctx, cseFilter, cseUpdate)
 		if err != nil {
 			g.App.ErrorLogger.Printf("Error updating CSE collections: %v", err)
-			return err
+			return dbError(err, "cse")
 		}
 	}
 
@@ -1709,19 +1691,19 @@ func (g *GoAppDB) ReopenChat(chatID, userID primitive.ObjectID) error {
 	err := User(g.DB, "chats").FindOne(ctx, chatFilter).Decode(&chat)
 	if err != nil {
 		g.App.ErrorLogger.Printf("Error finding chat: %v", err)
-		return err
+		return dbError(err, "chat")
 	}
 
 	fmt.Print("Inside the query to reopen the chat. chat :", chat)
 
 	// Verify that the user owns this chat
 	if chat.UserID != userID {
-		return errors.New("you don't have permission to reopen this chat")
+		return domain.NotFound("chat not found")
 	}
 
 	// Verify that the chat is closed
 	if chat.Status != "closed" {
-		return errors.New("chat is not closed")
+		return domain.Conflict("chat is not closed")
 	}
 
 	// If a CSE was previously assigned, update their collections
@@ -1751,7 +1733,7 @@ func (g *GoAppDB) ReopenChat(chatID, userID primitive.ObjectID) error {
 	_, err = User(g.DB, "chats").UpdateOne(ctx, chatFilter, chatUpdate)
 	if err != nil {
 		g.App.ErrorLogger.Printf("Error updating chat status: %v", err)
-		return err
+		return dbError(err, "chat")
 	}
 
 	return nil
@@ -1773,14 +1755,14 @@ func (g *GoAppDB) CloseIdleChats() error {
 	cursor, err := User(g.DB, "chats").Find(ctx, filter)
 	if err != nil {
 		g.App.ErrorLogger.Printf("Error finding idle chats: %v", err)
-		return err
+		return dbError(err, "chat")
 	}
 	defer cursor.Close(ctx)
 
 	var chats []model.Chat
 	if err = cursor.All(ctx, &chats); err != nil {
 		g.App.ErrorLogger.Printf("Error decoding chats: %v", err)
-		return err
+		return dbError(err, "chat")
 	}
 
 	if len(chats) > 0 {
@@ -1816,7 +1798,7 @@ func (g *GoAppDB) CreateReview(review *model.Review) (*model.Review, error) {
 	_, err := User(g.DB, "reviews").InsertOne(ctx, review)
 	if err != nil {
 		g.App.ErrorLogger.Printf("Failed to create review: %v", err)
-		return nil, err
+		return nil, dbError(err, "review")
 	}
 
 	fmt.Print("Review being created: ", review)
@@ -1836,7 +1818,7 @@ func (g *GoAppDB) UpdateProductWithReview(productID primitive.ObjectID, review *
 	err := User(g.DB, "product").FindOne(ctx, filter).Decode(&product)
 	if err != nil {
 		g.App.ErrorLogger.Printf("Failed to find product for review update: %v", err)
-		return err
+		return dbError(err, "product")
 	}
 
 	fmt.Print("Product updation phase 1 : ", product)
@@ -1864,7 +1846,7 @@ func (g *GoAppDB) UpdateProductWithReview(productID primitive.ObjectID, review *
 	_, err = User(g.DB, "product").UpdateOne(ctx, filter, update)
 	if err != nil {
 		g.App.ErrorLogger.Printf("Failed to update product with review: %v", err)
-		return err
+		return dbError(err, "product")
 	}
 
 	fmt.Print("Product updation phase 3 : ", product)
@@ -1885,7 +1867,7 @@ func (g *GoAppDB) UpdateOrderWithRated(orderID primitive.ObjectID) error {
 	_, err := User(g.DB, "orders").UpdateOne(ctx, filter, update)
 	if err != nil {
 		g.App.ErrorLogger.Printf("Failed to update order with rated: %v", err)
-		return err
+		return dbError(err, "order")
 	}
 
 	return nil
@@ -1902,13 +1884,13 @@ func (g *GoAppDB) GetReviewsByProductID(productID primitive.ObjectID) ([]model.R
 	cursor, err := User(g.DB, "reviews").Find(ctx, filter)
 	if err != nil {
 		g.App.ErrorLogger.Printf("Failed to find reviews for product: %v", err)
-		return nil, err
+		return nil, dbError(err, "review")
 	}
 
 	var reviews []model.Review
 	if err = cursor.All(ctx, &reviews); err != nil {
 		g.App.ErrorLogger.Printf("Failed to decode reviews: %v", err)
-		return nil, err
+		return nil, dbError(err, "review")
 	}
 
 	fmt.Print("Reviews fetched : ", reviews)
@@ -1927,13 +1909,13 @@ func (g *GoAppDB) GetReviewsByCustomerID(customerID primitive.ObjectID) ([]model
 	cursor, err := User(g.DB, "reviews").Find(ctx, filter)
 	if err != nil {
 		g.App.ErrorLogger.Printf("Failed to find reviews for customer: %v", err)
-		return nil, err
+		return nil, dbError(err, "review")
 	}
 
 	var reviews []model.Review
 	if err = cursor.All(ctx, &reviews); err != nil {
 		g.App.ErrorLogger.Printf("Failed to decode reviews: %v", err)
-		return nil, err
+		return nil, dbError(err, "review")
 	}
 
 	fmt.Print("Reviews fetched : ", reviews)
@@ -2025,13 +2007,13 @@ func (g *GoAppDB) UpdateProductSummarizedReview(productID primitive.ObjectID, su
 	result, err := User(g.DB, "product").UpdateOne(ctx, filter, update)
 	if err != nil {
 		g.App.ErrorLogger.Printf("Failed to update product with summarized review: %v", err)
-		return err
+		return dbError(err, "product")
 	}
 
 	fmt.Print("Result : ", result)
 
 	if result.MatchedCount == 0 {
-		return fmt.Errorf("no product found with ID %s", productID.Hex())
+		return domain.NotFound("product not found")
 	}
 
 	return nil
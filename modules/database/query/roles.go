@@ -0,0 +1,139 @@
+package query
+
+import (
+	"context"
+	"time"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// CreateRole stores a custom role. Like CreateCategory it reports 1 when the role was
+// created and 2 when a role with that name already exists.
+func (g *GoAppDB) CreateRole(role *model.Role) (bool, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := bson.D{{Key: "name", Value: role.Name}}
+
+	var res bson.M
+
+	err := User(g.DB, "roles").FindOne(ctx, filter).Decode(&res)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			role.ID = primitive.NewObjectID()
+			_, insertErr := User(g.DB, "roles").InsertOne(ctx, role)
+			if insertErr != nil {
+				g.App.ErrorLogger.Printf("cannot add role to the database : %v ", insertErr)
+				return false, 0, dbError(insertErr, "role")
+			}
+			return true, 1, nil
+		}
+		g.App.ErrorLogger.Printf("cannot execute the database query perfectly : %v ", err)
+		return false, 0, dbError(err, "role")
+	}
+
+	return true, 2, nil
+}
+
+// GetRole returns the custom role with the given name.
+func (g *GoAppDB) GetRole(name string) (model.Role, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	var role model.Role
+
+	err := User(g.DB, "roles").FindOne(ctx, bson.D{{Key: "name", Value: name}}).Decode(&role)
+	if err != nil {
+		g.App.ErrorLogger.Printf("Error finding role %s: %v", name, err)
+		return role, dbError(err, "role")
+	}
+
+	return role, nil
+}
+
+func (g *GoAppDB) GetAllRoles() ([]model.Role, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	cursor, err := User(g.DB, "roles").Find(ctx, bson.D{})
+	if err != nil {
+		g.App.ErrorLogger.Printf("Error finding roles: %v", err)
+		return nil, dbError(err, "role")
+	}
+	defer cursor.Close(ctx)
+
+	roles := []model.Role{}
+	if err = cursor.All(ctx, &roles); err != nil {
+		g.App.ErrorLogger.Printf("Error decoding roles: %v", err)
+		return nil, dbError(err, "role")
+	}
+
+	return roles, nil
+}
+
+// UpdateRolePermissions replaces the permission set of a custom role.
+func (g *GoAppDB) UpdateRolePermissions(name string, permissions []string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := bson.D{{Key: "name", Value: name}}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "permissions", Value: permissions}, {Key: "updated_at", Value: time.Now()}}}}
+
+	updateDetails, err := User(g.DB, "roles").UpdateOne(ctx, filter, update)
+	if err != nil {
+		g.App.ErrorLogger.Printf("Error updating role %s: %v", name, err)
+		return false, dbError(err, "role")
+	}
+
+	return updateDetails.MatchedCount == 1, nil
+}
+
+// DeleteRole removes a custom role unless an admin still holds it.
+// The returned int is 1 when deleted, 2 when the role is still assigned and 3 when it does not exist.
+func (g *GoAppDB) DeleteRole(name string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	assigned, err := User(g.DB, "admin").CountDocuments(ctx, bson.D{{Key: "role", Value: name}})
+	if err != nil {
+		g.App.ErrorLogger.Printf("Error counting admins with role %s: %v", name, err)
+		return 0, dbError(err, "admin")
+	}
+
+	if assigned > 0 {
+		return 2, nil
+	}
+
+	deleteDetails, err := User(g.DB, "roles").DeleteOne(ctx, bson.D{{Key: "name", Value: name}})
+	if err != nil {
+		g.App.ErrorLogger.Printf("Error deleting role %s: %v", name, err)
+		return 0, dbError(err, "role")
+	}
+
+	if deleteDetails.DeletedCount == 0 {
+		return 3, nil
+	}
+
+	return 1, nil
+}
+
+// AssignRoleToAdmin sets the role an admin's next tokens are issued with.
+func (g *GoAppDB) AssignRoleToAdmin(adminID primitive.ObjectID, role string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := bson.D{{Key: "_id", Value: adminID}}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "role", Value: role}}}}
+
+	updateDetails, err := User(g.DB, "admin").UpdateOne(ctx, filter, update)
+	if err != nil {
+		g.App.ErrorLogger.Printf("Error assigning role to admin %s: %v", adminID.Hex(), err)
+		return false, dbError(err, "admin")
+	}
+
+	return updateDetails.MatchedCount == 1, nil
+}
@@ -0,0 +1,194 @@
+package query
+
+import (
+	"context"
+	"time"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/domain"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// lastSeenResolution is how stale last_seen_at may get before a request writes it again.
+const lastSeenResolution = time.Minute
+
+// activeSession matches sessions that were neither revoked nor expired at now.
+func activeSession(now time.Time) bson.D {
+	return bson.D{
+		{Key: "revoked_at", Value: bson.D{{Key: "$exists", Value: false}}},
+		{Key: "expires_at", Value: bson.D{{Key: "$gt", Value: now}}},
+	}
+}
+
+func (g *GoAppDB) CreateLoginSession(session *model.LoginSession) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	_, err := User(g.DB, "login_sessions").InsertOne(ctx, session)
+	if err != nil {
+		g.App.ErrorLogger.Printf("Error creating login session: %v", err)
+		return dbError(err, "session")
+	}
+
+	return nil
+}
+
+// GetLoginSession returns the session with the given id, revoked or not.
+func (g *GoAppDB) GetLoginSession(id primitive.ObjectID) (model.LoginSession, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	var session model.LoginSession
+
+	err := User(g.DB, "login_sessions").FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Decode(&session)
+	if err != nil {
+		return session, dbError(err, "session")
+	}
+
+	return session, nil
+}
+
+// ListLoginSessions returns the active sessions of one user, admin or CSE, most recently used first.
+func (g *GoAppDB) ListLoginSessions(principal string, subjectID primitive.ObjectID) ([]model.LoginSession, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := append(bson.D{
+		{Key: "principal", Value: principal},
+		{Key: "subject_id", Value: subjectID},
+	}, activeSession(time.Now())...)
+
+	opts := options.Find().SetSort(bson.D{{Key: "last_seen_at", Value: -1}})
+
+	cursor, err := User(g.DB, "login_sessions").Find(ctx, filter, opts)
+	if err != nil {
+		g.App.ErrorLogger.Printf("Error fetching login sessions: %v", err)
+		return nil, dbError(err, "session")
+	}
+
+	sessions := []model.LoginSession{}
+	if err = cursor.All(ctx, &sessions); err != nil {
+		g.App.ErrorLogger.Printf("Error decoding login sessions: %v", err)
+		return nil, dbError(err, "session")
+	}
+
+	return sessions, nil
+}
+
+// RotateSessionRefresh swaps the refresh token hash of an active session only if oldHash is still
+// the stored one, so two concurrent refreshes with the same token cannot both succeed. The session
+// then lasts until expiresAt.
+func (g *GoAppDB) RotateSessionRefresh(id primitive.ObjectID, oldHash string, newHash string, expiresAt time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	now := time.Now()
+
+	filter := append(bson.D{
+		{Key: "_id", Value: id},
+		{Key: "refresh_hash", Value: oldHash},
+	}, activeSession(now)...)
+
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "refresh_hash", Value: newHash},
+		{Key: "last_seen_at", Value: now},
+		{Key: "expires_at", Value: expiresAt},
+	}}}
+
+	result, err := User(g.DB, "login_sessions").UpdateOne(ctx, filter, update)
+	if err != nil {
+		g.App.ErrorLogger.Printf("Error rotating session refresh token: %v", err)
+		return false, dbError(err, "session")
+	}
+
+	return result.MatchedCount == 1, nil
+}
+
+// TouchLoginSession records that the session was used from ip and reports whether it is still
+// active. last_seen_at is only written when it is older than lastSeenResolution.
+func (g *GoAppDB) TouchLoginSession(id primitive.ObjectID, ip string, now time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := append(bson.D{
+		{Key: "_id", Value: id},
+		{Key: "last_seen_at", Value: bson.D{{Key: "$lt", Value: now.Add(-lastSeenResolution)}}},
+	}, activeSession(now)...)
+
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "last_seen_at", Value: now},
+		{Key: "ip", Value: ip},
+	}}}
+
+	result, err := User(g.DB, "login_sessions").UpdateOne(ctx, filter, update)
+	if err != nil {
+		g.App.ErrorLogger.Printf("Error touching login session: %v", err)
+		return false, dbError(err, "session")
+	}
+
+	if result.MatchedCount == 1 {
+		return true, nil
+	}
+
+	// Seen recently, or not active any more.
+	count, err := User(g.DB, "login_sessions").CountDocuments(ctx, append(bson.D{{Key: "_id", Value: id}}, activeSession(now)...))
+	if err != nil {
+		g.App.ErrorLogger.Printf("Error checking login session: %v", err)
+		return false, dbError(err, "session")
+	}
+
+	return count == 1, nil
+}
+
+// RevokeLoginSession ends one active session of a user, admin or CSE.
+func (g *GoAppDB) RevokeLoginSession(principal string, subjectID primitive.ObjectID, id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	now := time.Now()
+
+	filter := append(bson.D{
+		{Key: "_id", Value: id},
+		{Key: "principal", Value: principal},
+		{Key: "subject_id", Value: subjectID},
+	}, activeSession(now)...)
+
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "revoked_at", Value: now}}}}
+
+	result, err := User(g.DB, "login_sessions").UpdateOne(ctx, filter, update)
+	if err != nil {
+		g.App.ErrorLogger.Printf("Error revoking login session: %v", err)
+		return dbError(err, "session")
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.NotFound("session not found")
+	}
+
+	return nil
+}
+
+// RevokeLoginSessions ends every active session of a user, admin or CSE and returns how many there were.
+func (g *GoAppDB) RevokeLoginSessions(principal string, subjectID primitive.ObjectID) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	now := time.Now()
+
+	filter := append(bson.D{
+		{Key: "principal", Value: principal},
+		{Key: "subject_id", Value: subjectID},
+	}, activeSession(now)...)
+
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "revoked_at", Value: now}}}}
+
+	result, err := User(g.DB, "login_sessions").UpdateMany(ctx, filter, update)
+	if err != nil {
+		g.App.ErrorLogger.Printf("Error revoking login sessions: %v", err)
+		return 0, dbError(err, "session")
+	}
+
+	return result.ModifiedCount, nil
+}
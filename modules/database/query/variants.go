@@ -0,0 +1,251 @@
+package query
+
+import (
+	"context"
+	"time"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/domain"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// variantSummary is the update that keeps the fields a product derives from its variants up to
+// date: the lowest regular and sale price, the total stock and the options offered. Products
+// without variants keep their own values.
+func variantSummary() mongo.Pipeline {
+	hasVariants := bson.D{{Key: "$gt", Value: bson.A{
+		bson.D{{Key: "$size", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$variants", bson.A{}}}}}}, 0,
+	}}}
+	either := func(withVariants interface{}, without interface{}) bson.D {
+		return bson.D{{Key: "$cond", Value: bson.A{hasVariants, withVariants, without}}}
+	}
+	// offered lists the distinct values the variants have for an attribute, leaving out blanks.
+	offered := func(field string) bson.D {
+		return bson.D{{Key: "$setDifference", Value: bson.A{
+			bson.D{{Key: "$setUnion", Value: bson.A{bson.D{{Key: "$ifNull", Value: bson.A{"$variants." + field, bson.A{}}}}}}},
+			bson.A{nil, ""},
+		}}}
+	}
+	onSale := bson.D{{Key: "$filter", Value: bson.D{
+		{Key: "input", Value: "$variants.sale_price"},
+		{Key: "cond", Value: bson.D{{Key: "$gt", Value: bson.A{"$$this", 0}}}},
+	}}}
+	totalStock := bson.D{{Key: "$sum", Value: "$variants.stock"}}
+
+	return mongo.Pipeline{
+		bson.D{{Key: "$set", Value: bson.D{
+			{Key: "regularprice", Value: either(bson.D{{Key: "$min", Value: "$variants.regular_price"}}, "$regularprice")},
+			{Key: "saleprice", Value: either(bson.D{{Key: "$ifNull", Value: bson.A{bson.D{{Key: "$min", Value: onSale}}, 0}}}, "$saleprice")},
+			{Key: "stock", Value: either(totalStock, "$stock")},
+			{Key: "instock", Value: either(bson.D{{Key: "$gt", Value: bson.A{totalStock, 0}}}, "$instock")},
+			{Key: "options", Value: bson.D{
+				{Key: "colors", Value: offered("color")},
+				{Key: "trims", Value: offered("trim")},
+				{Key: "transmissions", Value: offered("transmission")},
+				{Key: "fuel_types", Value: offered("fuel_type")},
+			}},
+		}}},
+	}
+}
+
+// summarizeVariants refreshes what the products matching filter derive from their variants.
+func (g *GoAppDB) summarizeVariants(ctx context.Context, filter interface{}) error {
+	if _, err := Product(g.DB, "product").UpdateMany(ctx, filter, variantSummary()); err != nil {
+		g.App.ErrorLogger.Printf("cannot summarize product variants : %v ", err)
+		return dbError(err, "product")
+	}
+	return nil
+}
+
+// prepareVariants gives the variants of a new product their ids.
+func prepareVariants(product *model.Product) {
+	if product.Variants == nil {
+		product.Variants = []model.Variant{}
+	}
+	for i := range product.Variants {
+		if product.Variants[i].ID.IsZero() {
+			product.Variants[i].ID = primitive.NewObjectID()
+		}
+		if product.Variants[i].Images == nil {
+			product.Variants[i].Images = []string{}
+		}
+	}
+}
+
+// GetProduct returns the product with the given id.
+func (g *GoAppDB) GetProduct(id primitive.ObjectID) (model.Product, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	var product model.Product
+	if err := Product(g.DB, "product").FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Decode(&product); err != nil {
+		return product, dbError(err, "product")
+	}
+	return product, nil
+}
+
+// AddVariant adds a variant to a product. Its SKU must not be used by any other variant.
+func (g *GoAppDB) AddVariant(productID primitive.ObjectID, variant *model.Variant) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	variant.ID = primitive.NewObjectID()
+	if variant.Images == nil {
+		variant.Images = []string{}
+	}
+
+	filter := bson.D{
+		{Key: "_id", Value: productID},
+		{Key: "variants.sku", Value: bson.D{{Key: "$ne", Value: variant.SKU}}},
+	}
+	update := bson.D{
+		{Key: "$push", Value: bson.D{{Key: "variants", Value: variant}}},
+		{Key: "$set", Value: bson.D{{Key: "updatedat", Value: time.Now()}}},
+	}
+
+	res, err := Product(g.DB, "product").UpdateOne(ctx, filter, update)
+	if err != nil {
+		g.App.ErrorLogger.Printf("cannot add variant to product : %v ", err)
+		return dbError(err, "variant")
+	}
+	if res.MatchedCount == 0 {
+		if _, err := g.GetProduct(productID); err != nil {
+			return err
+		}
+		return domain.Conflict("variant already exists")
+	}
+
+	return g.summarizeVariants(ctx, bson.D{{Key: "_id", Value: productID}})
+}
+
+// UpdateVariant edits a variant of a product and returns it as it now is.
+func (g *GoAppDB) UpdateVariant(productID primitive.ObjectID, variantID primitive.ObjectID, changes model.VariantChanges) (*model.Variant, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	set := bson.D{{Key: "updatedat", Value: time.Now()}}
+	fields := []struct {
+		key   string
+		value interface{}
+		ok    bool
+	}{
+		{"sku", changes.SKU, changes.SKU != nil},
+		{"color", changes.Color, changes.Color != nil},
+		{"trim", changes.Trim, changes.Trim != nil},
+		{"transmission", changes.Transmission, changes.Transmission != nil},
+		{"fuel_type", changes.FuelType, changes.FuelType != nil},
+		{"regular_price", changes.RegularPrice, changes.RegularPrice != nil},
+		{"sale_price", changes.SalePrice, changes.SalePrice != nil},
+		{"stock", changes.Stock, changes.Stock != nil},
+		{"images", changes.Images, changes.Images != nil},
+	}
+	for _, f := range fields {
+		if f.ok {
+			set = append(set, bson.E{Key: "variants.$[v]." + f.key, Value: f.value})
+		}
+	}
+
+	filter := bson.D{
+		{Key: "_id", Value: productID},
+		{Key: "variants._id", Value: variantID},
+	}
+	if changes.SKU != nil {
+		// Another variant of the product already using the SKU keeps the update from matching.
+		filter = append(filter, bson.E{Key: "variants", Value: bson.D{{Key: "$not", Value: bson.D{{Key: "$elemMatch", Value: bson.D{
+			{Key: "sku", Value: *changes.SKU},
+			{Key: "_id", Value: bson.D{{Key: "$ne", Value: variantID}}},
+		}}}}}})
+	}
+
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{
+		Filters: []interface{}{bson.D{{Key: "v._id", Value: variantID}}},
+	})
+
+	res, err := Product(g.DB, "product").UpdateOne(ctx, filter, bson.D{{Key: "$set", Value: set}}, opts)
+	if err != nil {
+		g.App.ErrorLogger.Printf("cannot update variant of product : %v ", err)
+		return nil, dbError(err, "variant")
+	}
+
+	if res.MatchedCount == 0 {
+		product, err := g.GetProduct(productID)
+		if err != nil {
+			return nil, err
+		}
+		if product.Variant(variantID) == nil {
+			return nil, domain.NotFound("variant not found")
+		}
+		return nil, domain.Conflict("variant already exists")
+	}
+
+	if err := g.summarizeVariants(ctx, bson.D{{Key: "_id", Value: productID}}); err != nil {
+		return nil, err
+	}
+
+	product, err := g.GetProduct(productID)
+	if err != nil {
+		return nil, err
+	}
+	variant := product.Variant(variantID)
+	if variant == nil {
+		return nil, domain.NotFound("variant not found")
+	}
+	return variant, nil
+}
+
+// DeleteVariant removes a variant from a product. Carts and orders holding it keep its id.
+func (g *GoAppDB) DeleteVariant(productID primitive.ObjectID, variantID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := bson.D{
+		{Key: "_id", Value: productID},
+		{Key: "variants._id", Value: variantID},
+	}
+	update := bson.D{
+		{Key: "$pull", Value: bson.D{{Key: "variants", Value: bson.D{{Key: "_id", Value: variantID}}}}},
+		{Key: "$set", Value: bson.D{{Key: "updatedat", Value: time.Now()}}},
+	}
+
+	res, err := Product(g.DB, "product").UpdateOne(ctx, filter, update)
+	if err != nil {
+		g.App.ErrorLogger.Printf("cannot delete variant of product : %v ", err)
+		return dbError(err, "variant")
+	}
+	if res.MatchedCount == 0 {
+		if _, err := g.GetProduct(productID); err != nil {
+			return err
+		}
+		return domain.NotFound("variant not found")
+	}
+
+	return g.summarizeVariants(ctx, bson.D{{Key: "_id", Value: productID}})
+}
+
+// orderedVariant picks, in an order pipeline, the variant of productDetails the order item at
+// path is for, if any.
+func orderedVariant(path string) bson.D {
+	return bson.D{{Key: "$arrayElemAt", Value: bson.A{
+		bson.D{{Key: "$filter", Value: bson.D{
+			{Key: "input", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$productDetails.variants", bson.A{}}}}},
+			{Key: "cond", Value: bson.D{{Key: "$eq", Value: bson.A{"$$this._id", "$" + path + ".variant_id"}}}},
+		}}},
+		0,
+	}}}
+}
+
+// orderedPrice is the price the order item at path was ordered at. Items of orders placed before
+// it was kept fall back to the sale price of their variant, if they have one, or product.
+func orderedPrice(path string) bson.D {
+	return bson.D{{Key: "$ifNull", Value: bson.A{"$" + path + ".unit_price", bson.D{{Key: "$let", Value: bson.D{
+		{Key: "vars", Value: bson.D{{Key: "v", Value: orderedVariant(path)}}},
+		{Key: "in", Value: bson.D{{Key: "$cond", Value: bson.A{
+			bson.D{{Key: "$ifNull", Value: bson.A{"$$v", false}}},
+			bson.D{{Key: "$cond", Value: bson.A{bson.D{{Key: "$gt", Value: bson.A{"$$v.sale_price", 0}}}, "$$v.sale_price", "$$v.regular_price"}}},
+			"$productDetails.saleprice",
+		}}}},
+	}}}}}}
+}
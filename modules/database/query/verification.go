@@ -0,0 +1,102 @@
+package query
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MarkEmailVerified flags the user as verified if email is still their current email.
+func (g *GoAppDB) MarkEmailVerified(userID primitive.ObjectID, email string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := bson.D{{Key: "_id", Value: userID}, {Key: "email", Value: email}}
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "verified", Value: true},
+		{Key: "updatedat", Value: time.Now()},
+	}}}
+
+	result, err := User(g.DB, "user").UpdateOne(ctx, filter, update)
+	if err != nil {
+		g.App.ErrorLogger.Printf("cannot mark user's email as verified : %v ", err)
+		return false, dbError(err, "user")
+	}
+
+	return result.MatchedCount == 1, nil
+}
+
+// SetPendingEmail records the email a user wants to change to until they prove they own it.
+func (g *GoAppDB) SetPendingEmail(userID primitive.ObjectID, email string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := bson.D{{Key: "_id", Value: userID}}
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "pending_email", Value: email},
+		{Key: "updatedat", Value: time.Now()},
+	}}}
+
+	_, err := User(g.DB, "user").UpdateOne(ctx, filter, update)
+	if err != nil {
+		g.App.ErrorLogger.Printf("cannot set user's pending email : %v ", err)
+		return dbError(err, "user")
+	}
+
+	return nil
+}
+
+// ConfirmPendingEmail makes the pending email the user's verified email and clears their tokens,
+// which carry the old email. It reports false when email is no longer pending.
+func (g *GoAppDB) ConfirmPendingEmail(userID primitive.ObjectID, email string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := bson.D{{Key: "_id", Value: userID}, {Key: "pending_email", Value: email}}
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "email", Value: email},
+		{Key: "pending_email", Value: ""},
+		{Key: "verified", Value: true},
+		{Key: "token", Value: ""},
+		{Key: "new_token", Value: ""},
+		{Key: "token_family", Value: ""},
+		{Key: "updatedat", Value: time.Now()},
+	}}}
+
+	result, err := User(g.DB, "user").UpdateOne(ctx, filter, update)
+	if err != nil {
+		g.App.ErrorLogger.Printf("cannot confirm user's new email : %v ", err)
+		return false, dbError(err, "user")
+	}
+
+	return result.MatchedCount == 1, nil
+}
+
+// GetEmailVerification returns the email, pending_email and verified fields of a user.
+func (g *GoAppDB) GetEmailVerification(userID primitive.ObjectID) (primitive.M, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	filter := bson.D{{Key: "_id", Value: userID}}
+	opts := options.FindOne().SetProjection(bson.D{
+		{Key: "email", Value: 1},
+		{Key: "pending_email", Value: 1},
+		{Key: "verified", Value: 1},
+	})
+
+	var res bson.M
+
+	err := User(g.DB, "user").FindOne(ctx, filter, opts).Decode(&res)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			g.App.ErrorLogger.Printf("cannot fetch user's email verification : %v ", err)
+		}
+		return nil, dbError(err, "user")
+	}
+
+	return res, nil
+}
@@ -0,0 +1,448 @@
+package dto
+
+import (
+	"strings"
+	"time"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/pricing"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/units"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreateCategoryRequest adds a category under ParentID, or at the root without one. The slug
+// defaults to the name.
+type CreateCategoryRequest struct {
+	Name                string              `json:"name" binding:"required,max=100"`
+	General_Description string              `json:"general_description" binding:"required,max=2000"`
+	CategoryImage       string              `json:"category_image" binding:"required"`
+	Slug                string              `json:"slug" binding:"omitempty,max=100"`
+	ParentID            *primitive.ObjectID `json:"parent_id"`
+}
+
+func (r *CreateCategoryRequest) ToCategory() *model.Category {
+	return &model.Category{
+		Name:                strings.TrimSpace(r.Name),
+		General_Description: r.General_Description,
+		CategoryImage:       r.CategoryImage,
+		Slug:                r.Slug,
+		ParentID:            r.ParentID,
+	}
+}
+
+// UpdateCategoryRequest edits a category; fields left out are kept.
+type UpdateCategoryRequest struct {
+	Name                *string `json:"name" binding:"omitempty,min=1,max=100"`
+	General_Description *string `json:"general_description" binding:"omitempty,max=2000"`
+	CategoryImage       *string `json:"category_image" binding:"omitempty,min=1"`
+	Slug                *string `json:"slug" binding:"omitempty,min=1,max=100"`
+}
+
+func (r *UpdateCategoryRequest) ToChanges() model.CategoryChanges {
+	if r.Name != nil {
+		name := strings.TrimSpace(*r.Name)
+		r.Name = &name
+	}
+	return model.CategoryChanges{
+		Name:                r.Name,
+		General_Description: r.General_Description,
+		CategoryImage:       r.CategoryImage,
+		Slug:                r.Slug,
+	}
+}
+
+// MoveCategoryRequest moves a category under another one, or to the root when ParentID is null.
+type MoveCategoryRequest struct {
+	ParentID *primitive.ObjectID `json:"parent_id"`
+}
+
+// CategoryView is a category with how many products it holds, directly and with its subcategories.
+type CategoryView struct {
+	model.Category
+	ProductCount      int64 `json:"product_count"`
+	TotalProductCount int64 `json:"total_product_count"`
+}
+
+// Breadcrumb is one step of the way from the root to a category.
+type Breadcrumb struct {
+	ID   primitive.ObjectID `json:"_id"`
+	Name string             `json:"name"`
+	Slug string             `json:"slug"`
+	Path string             `json:"path"`
+}
+
+// CategoryViews counts the products of every category, given the number each holds directly.
+// Categories keep their order.
+func CategoryViews(categories []model.Category, counts map[primitive.ObjectID]int64) []CategoryView {
+	totals := make(map[primitive.ObjectID]int64, len(categories))
+	for _, c := range categories {
+		n := counts[c.ID]
+		totals[c.ID] += n
+		for _, ancestor := range c.Ancestors {
+			totals[ancestor] += n
+		}
+	}
+
+	views := make([]CategoryView, 0, len(categories))
+	for _, c := range categories {
+		views = append(views, CategoryView{Category: c, ProductCount: counts[c.ID], TotalProductCount: totals[c.ID]})
+	}
+	return views
+}
+
+// Breadcrumbs returns the way from the root down to category, itself included.
+func Breadcrumbs(category model.Category, categories []model.Category) []Breadcrumb {
+	byID := make(map[primitive.ObjectID]model.Category, len(categories))
+	for _, c := range categories {
+		byID[c.ID] = c
+	}
+
+	crumbs := make([]Breadcrumb, 0, len(category.Ancestors)+1)
+	for _, id := range append(append([]primitive.ObjectID{}, category.Ancestors...), category.ID) {
+		c, ok := byID[id]
+		if !ok {
+			continue
+		}
+		crumbs = append(crumbs, Breadcrumb{ID: c.ID, Name: c.Name, Slug: c.Slug, Path: c.Path})
+	}
+	return crumbs
+}
+
+// ProductDescRequest describes a product. Measurements are text with a unit, e.g. "18.5 kmpl",
+// "1.2 L" or "88.5 bhp @ 6000 rpm", and are read into the specs of the product.
+type ProductDescRequest struct {
+	FuelType        string            `json:"fuel_type" binding:"max=50"`
+	Mileage         string            `json:"mileage" binding:"omitempty,max=50,spec=fuel_economy"`
+	Engine          string            `json:"engine" binding:"omitempty,max=50,spec=displacement"`
+	PowerOutput     string            `json:"power_output" binding:"omitempty,max=50,spec=power"`
+	SeatingCapacity string            `json:"seating_capacity" binding:"max=20"`
+	Tyre            string            `json:"tyre" binding:"max=100"`
+	TopSpeed        string            `json:"top_speed" binding:"omitempty,max=50,spec=speed"`
+	Dimension       DimensionsRequest `json:"dimensions"`
+	Weight          int               `json:"weight" binding:"min=0"`
+}
+
+type DimensionsRequest struct {
+	Length string `json:"length" binding:"omitempty,max=50,spec=length"`
+	Width  string `json:"width" binding:"omitempty,max=50,spec=length"`
+	Height string `json:"height" binding:"omitempty,max=50,spec=length"`
+}
+
+func (r *ProductDescRequest) ToDesc() model.ProductDesc {
+	return model.ProductDesc{
+		FuelType:        strings.TrimSpace(r.FuelType),
+		Mileage:         strings.TrimSpace(r.Mileage),
+		Engine:          strings.TrimSpace(r.Engine),
+		PowerOutput:     strings.TrimSpace(r.PowerOutput),
+		SeatingCapacity: strings.TrimSpace(r.SeatingCapacity),
+		Tyre:            strings.TrimSpace(r.Tyre),
+		TopSpeed:        strings.TrimSpace(r.TopSpeed),
+		Dimension: model.Dimensions{
+			Length: strings.TrimSpace(r.Dimension.Length),
+			Width:  strings.TrimSpace(r.Dimension.Width),
+			Height: strings.TrimSpace(r.Dimension.Height),
+		},
+		Weight: r.Weight,
+	}
+}
+
+// ProductRequest is the part of a product admins edit. Reviews and ratings are left to customers.
+// Sale times are read by pricing.ParseTime, in SaleTimezone or else the time zone of the store.
+type ProductRequest struct {
+	Name         string             `json:"name" binding:"required,max=200"`
+	Description  ProductDescRequest `json:"description"`
+	CategoryID   primitive.ObjectID `json:"category_id" binding:"required"`
+	Company_Name string             `json:"company_name" binding:"required,max=100"`
+	Model_Name   string             `json:"model_name" binding:"required,max=100"`
+	RegularPrice int                `json:"regular_price" binding:"required,gt=0"`
+	SalePrice    int                `json:"sale_price" binding:"omitempty,gt=0,ltfield=RegularPrice"`
+	SaleStarts   string             `json:"sale_starts" binding:"omitempty,sale_time"`
+	SaleEnds     string             `json:"sale_ends" binding:"omitempty,sale_time"`
+	SaleTimezone string             `json:"sale_timezone" binding:"omitempty,timezone"`
+	InStock      bool               `json:"in_stock"`
+	Stock        int                `json:"stock" binding:"min=0"`
+	SKU          string             `json:"sku" binding:"required,max=64"`
+	Images       []string           `json:"images" binding:"required,min=1,dive,required"`
+	Variants     []VariantRequest   `json:"variants" binding:"omitempty,max=100,unique=SKU,dive"`
+}
+
+// ToProduct returns the product, with its sale times read in location.
+func (r *ProductRequest) ToProduct(location *time.Location) *model.Product {
+	variants := make([]model.Variant, 0, len(r.Variants))
+	for i := range r.Variants {
+		variants = append(variants, *r.Variants[i].ToVariant())
+	}
+
+	description := r.Description.ToDesc()
+	// Validation has already made sure every measurement and sale time reads.
+	specs, _ := description.Specs()
+	saleStarts, _ := pricing.ParseTime(r.SaleStarts, location, false)
+	saleEnds, _ := pricing.ParseTime(r.SaleEnds, location, true)
+
+	return &model.Product{
+		Name:         strings.TrimSpace(r.Name),
+		Description:  description,
+		Specs:        specs,
+		CategoryID:   r.CategoryID,
+		Company_Name: r.Company_Name,
+		Model_Name:   r.Model_Name,
+		RegularPrice: r.RegularPrice,
+		SalePrice:    r.SalePrice,
+		SaleStarts:   saleStarts,
+		SaleEnds:     saleEnds,
+		SaleTimezone: r.SaleTimezone,
+		InStock:      r.InStock,
+		Stock:        r.Stock,
+		SKU:          strings.TrimSpace(r.SKU),
+		Images:       r.Images,
+		Variants:     variants,
+		Reviews:      []model.Review{},
+	}
+}
+
+// UpdateProductRequest edits a product. Its variants are edited on their own, so the ones it
+// carries are ignored.
+type UpdateProductRequest struct {
+	ID primitive.ObjectID `json:"_id" binding:"required"`
+	ProductRequest
+}
+
+func (r *UpdateProductRequest) ToProduct(location *time.Location) *model.Product {
+	product := r.ProductRequest.ToProduct(location)
+	product.ID = r.ID
+	product.Variants = nil
+	return product
+}
+
+// VariantRequest is one variant of a product. At least one of its attributes must say what sets
+// it apart.
+type VariantRequest struct {
+	SKU          string   `json:"sku" binding:"required,max=64"`
+	Color        string   `json:"color" binding:"required_without_all=Trim Transmission FuelType,max=50"`
+	Trim         string   `json:"trim" binding:"max=50"`
+	Transmission string   `json:"transmission" binding:"max=50"`
+	FuelType     string   `json:"fuel_type" binding:"max=50"`
+	RegularPrice int      `json:"regular_price" binding:"required,gt=0"`
+	SalePrice    int      `json:"sale_price" binding:"omitempty,gt=0,ltfield=RegularPrice"`
+	Stock        int      `json:"stock" binding:"min=0"`
+	Images       []string `json:"images" binding:"omitempty,dive,required"`
+}
+
+func (r *VariantRequest) ToVariant() *model.Variant {
+	images := r.Images
+	if images == nil {
+		images = []string{}
+	}
+	return &model.Variant{
+		SKU:          strings.TrimSpace(r.SKU),
+		Color:        strings.TrimSpace(r.Color),
+		Trim:         strings.TrimSpace(r.Trim),
+		Transmission: strings.TrimSpace(r.Transmission),
+		FuelType:     strings.TrimSpace(r.FuelType),
+		RegularPrice: r.RegularPrice,
+		SalePrice:    r.SalePrice,
+		Stock:        r.Stock,
+		Images:       images,
+	}
+}
+
+// UpdateVariantRequest edits a variant; fields left out are kept, and a sale price of 0 ends
+// the variant's sale.
+type UpdateVariantRequest struct {
+	SKU          *string  `json:"sku" binding:"omitempty,min=1,max=64"`
+	Color        *string  `json:"color" binding:"omitempty,max=50"`
+	Trim         *string  `json:"trim" binding:"omitempty,max=50"`
+	Transmission *string  `json:"transmission" binding:"omitempty,max=50"`
+	FuelType     *string  `json:"fuel_type" binding:"omitempty,max=50"`
+	RegularPrice *int     `json:"regular_price" binding:"omitempty,gt=0"`
+	SalePrice    *int     `json:"sale_price" binding:"omitempty,min=0"`
+	Stock        *int     `json:"stock" binding:"omitempty,min=0"`
+	Images       []string `json:"images" binding:"omitempty,dive,required"`
+}
+
+func (r *UpdateVariantRequest) ToChanges() model.VariantChanges {
+	trim := func(s *string) *string {
+		if s == nil {
+			return nil
+		}
+		t := strings.TrimSpace(*s)
+		return &t
+	}
+	return model.VariantChanges{
+		SKU:          trim(r.SKU),
+		Color:        trim(r.Color),
+		Trim:         trim(r.Trim),
+		Transmission: trim(r.Transmission),
+		FuelType:     trim(r.FuelType),
+		RegularPrice: r.RegularPrice,
+		SalePrice:    r.SalePrice,
+		Stock:        r.Stock,
+		Images:       r.Images,
+	}
+}
+
+// ProductListRequest is the query string of the product listing.
+type ProductListRequest struct {
+	Category        string   `json:"category" form:"category"`
+	CategoryID      string   `json:"category_id" form:"category_id" binding:"omitempty,mongodb"`
+	CompanyName     string   `json:"company_name" form:"company_name"`
+	FuelType        string   `json:"fuel_type" form:"fuel_type"`
+	Color           string   `json:"color" form:"color"`
+	Trim            string   `json:"trim" form:"trim"`
+	Transmission    string   `json:"transmission" form:"transmission"`
+	SeatingCapacity string   `json:"seating_capacity" form:"seating_capacity"`
+	MinPrice        *int     `json:"min_price" form:"min_price" binding:"omitempty,min=0"`
+	MaxPrice        *int     `json:"max_price" form:"max_price" binding:"omitempty,min=0"`
+	InStock         *bool    `json:"in_stock" form:"in_stock"`
+	OnSale          *bool    `json:"on_sale" form:"on_sale"`
+	MinRating       *float64 `json:"min_rating" form:"min_rating" binding:"omitempty,min=0,max=5"`
+	MinMileage      *float64 `json:"min_mileage" form:"min_mileage" binding:"omitempty,min=0"`
+	MaxMileage      *float64 `json:"max_mileage" form:"max_mileage" binding:"omitempty,min=0"`
+	MinEngine       *float64 `json:"min_engine" form:"min_engine" binding:"omitempty,min=0"`
+	MaxEngine       *float64 `json:"max_engine" form:"max_engine" binding:"omitempty,min=0"`
+	MinPowerOutput  *float64 `json:"min_power_output" form:"min_power_output" binding:"omitempty,min=0"`
+	MaxPowerOutput  *float64 `json:"max_power_output" form:"max_power_output" binding:"omitempty,min=0"`
+	MinTopSpeed     *float64 `json:"min_top_speed" form:"min_top_speed" binding:"omitempty,min=0"`
+	MaxTopSpeed     *float64 `json:"max_top_speed" form:"max_top_speed" binding:"omitempty,min=0"`
+	MinLength       *float64 `json:"min_length" form:"min_length" binding:"omitempty,min=0"`
+	MaxLength       *float64 `json:"max_length" form:"max_length" binding:"omitempty,min=0"`
+	MinWidth        *float64 `json:"min_width" form:"min_width" binding:"omitempty,min=0"`
+	MaxWidth        *float64 `json:"max_width" form:"max_width" binding:"omitempty,min=0"`
+	MinHeight       *float64 `json:"min_height" form:"min_height" binding:"omitempty,min=0"`
+	MaxHeight       *float64 `json:"max_height" form:"max_height" binding:"omitempty,min=0"`
+	MinWeight       *float64 `json:"min_weight" form:"min_weight" binding:"omitempty,min=0"`
+	MaxWeight       *float64 `json:"max_weight" form:"max_weight" binding:"omitempty,min=0"`
+	Units           string   `json:"units" form:"units" binding:"omitempty,oneof=metric imperial"`
+	Sort            string   `json:"sort" form:"sort" binding:"omitempty,oneof=newest price_asc price_desc rating power_desc mileage_desc"`
+	Limit           int      `json:"limit" form:"limit" binding:"omitempty,min=1,max=100"`
+	Cursor          string   `json:"cursor" form:"cursor" binding:"omitempty,max=512"`
+}
+
+// specRange is a spec filter of the listing, in the units the request is made in.
+type specRange struct {
+	spec     string
+	quantity units.Quantity
+	min      *float64
+	max      *float64
+}
+
+func (r *ProductListRequest) specRanges() []specRange {
+	return []specRange{
+		{"mileage", units.FuelEconomy, r.MinMileage, r.MaxMileage},
+		{"engine", units.Displacement, r.MinEngine, r.MaxEngine},
+		{"power_output", units.Power, r.MinPowerOutput, r.MaxPowerOutput},
+		{"top_speed", units.Speed, r.MinTopSpeed, r.MaxTopSpeed},
+		{"length", units.Length, r.MinLength, r.MaxLength},
+		{"width", units.Length, r.MinWidth, r.MaxWidth},
+		{"height", units.Length, r.MinHeight, r.MaxHeight},
+		{"weight", units.Mass, r.MinWeight, r.MaxWeight},
+	}
+}
+
+// InvertedRange returns the name of the first spec whose minimum is above its maximum, if any.
+func (r *ProductListRequest) InvertedRange() (string, bool) {
+	for _, sr := range r.specRanges() {
+		if sr.min != nil && sr.max != nil && *sr.min > *sr.max {
+			return sr.spec, true
+		}
+	}
+	return "", false
+}
+
+func (r *ProductListRequest) ToQuery() model.ProductQuery {
+	// Validation has already made sure the id is well formed.
+	categoryID, _ := primitive.ObjectIDFromHex(r.CategoryID)
+
+	// Specs are stored in base units, which the bounds are given in unless asked otherwise.
+	system := units.System(r.Units)
+	ranges := map[string]model.SpecRange{}
+	for _, sr := range r.specRanges() {
+		if sr.min == nil && sr.max == nil {
+			continue
+		}
+		var bound model.SpecRange
+		if sr.min != nil {
+			n := units.FromSystem(*sr.min, sr.quantity, system)
+			bound.Min = &n
+		}
+		if sr.max != nil {
+			n := units.FromSystem(*sr.max, sr.quantity, system)
+			bound.Max = &n
+		}
+		ranges[sr.spec] = bound
+	}
+
+	return model.ProductQuery{
+		Category:        r.Category,
+		CategoryID:      categoryID,
+		CompanyName:     r.CompanyName,
+		FuelType:        r.FuelType,
+		Color:           r.Color,
+		Trim:            r.Trim,
+		Transmission:    r.Transmission,
+		SeatingCapacity: r.SeatingCapacity,
+		MinPrice:        r.MinPrice,
+		MaxPrice:        r.MaxPrice,
+		InStock:         r.InStock,
+		OnSale:          r.OnSale,
+		MinRating:       r.MinRating,
+		SpecRanges:      ranges,
+		Sort:            r.Sort,
+		Limit:           r.Limit,
+		Cursor:          r.Cursor,
+	}
+}
+
+// ProductPage is one page of the product listing.
+type ProductPage struct {
+	Data       []primitive.M `json:"data"`
+	Total      int64         `json:"total"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+func NewProductPage(p *model.ProductPage, system units.System) *ProductPage {
+	ShowSpecs(p.Products, system)
+	return &ProductPage{Data: p.Products, Total: p.Total, NextCursor: p.NextCursor}
+}
+
+// ShowSpecs converts the specs of products, as read from the database, to the units of system.
+// Products are left in the base units when it is empty.
+func ShowSpecs(products []primitive.M, system units.System) {
+	if system == "" {
+		return
+	}
+	for _, product := range products {
+		raw, err := bson.Marshal(bson.M{"specs": product["specs"]})
+		if err != nil {
+			continue
+		}
+		var doc struct {
+			Specs model.Specs `bson:"specs"`
+		}
+		if err := bson.Unmarshal(raw, &doc); err != nil {
+			continue
+		}
+		product["specs"] = doc.Specs.In(system)
+	}
+}
+
+// ProductSearchRequest is the query string of the product search.
+type ProductSearchRequest struct {
+	Q      string `json:"q" form:"q" binding:"required,max=200"`
+	Limit  int    `json:"limit" form:"limit" binding:"omitempty,min=1,max=100"`
+	Offset int    `json:"offset" form:"offset" binding:"omitempty,min=0,max=10000"`
+	Units  string `json:"units" form:"units" binding:"omitempty,oneof=metric imperial"`
+}
+
+// SingleProductRequest fetches one product, with its specs in the given units.
+type SingleProductRequest struct {
+	ProductID primitive.ObjectID `json:"product_id" binding:"required"`
+	Units     string             `json:"units" binding:"omitempty,oneof=metric imperial"`
+}
+
+// ProductSuggestRequest is the query string of search suggestions.
+type ProductSuggestRequest struct {
+	Q     string `json:"q" form:"q" binding:"required,max=100"`
+	Limit int    `json:"limit" form:"limit" binding:"omitempty,min=1,max=20"`
+}
@@ -0,0 +1,269 @@
+package dto
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/pricing"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/units"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CompareRequest is the query string of a comparison, naming each product with an ids parameter.
+type CompareRequest struct {
+	IDs   []string `json:"ids" form:"ids" binding:"required,min=2,max=4,unique,dive,mongodb"`
+	Units string   `json:"units" form:"units" binding:"omitempty,oneof=metric imperial"`
+}
+
+// ProductIDs returns the ids of the products to compare, in the order they were given.
+func (r *CompareRequest) ProductIDs() []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, 0, len(r.IDs))
+	for _, raw := range r.IDs {
+		// Validation has already made sure every id is well formed.
+		id, _ := primitive.ObjectIDFromHex(raw)
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// SaveComparisonRequest saves a comparison to share it by link.
+type SaveComparisonRequest struct {
+	ProductIDs []primitive.ObjectID `json:"product_ids" binding:"required,min=2,max=4,unique"`
+}
+
+// ComparisonQuery is the query string of a saved comparison.
+type ComparisonQuery struct {
+	Units string `json:"units" form:"units" binding:"omitempty,oneof=metric imperial"`
+}
+
+// RatingSummary is how customers rated a product.
+type RatingSummary struct {
+	Average      float32     `json:"average"`
+	Count        int         `json:"count"`
+	Distribution map[int]int `json:"distribution"`
+}
+
+// ComparedProduct heads a column of a comparison.
+type ComparedProduct struct {
+	ID             primitive.ObjectID `json:"_id"`
+	Name           string             `json:"name"`
+	Company_Name   string             `json:"company_name"`
+	Model_Name     string             `json:"model_name"`
+	Image          string             `json:"image,omitempty"`
+	RegularPrice   int                `json:"regular_price"`
+	EffectivePrice int                `json:"effective_price"`
+	OnSale         bool               `json:"on_sale"`
+	Rating         RatingSummary      `json:"rating"`
+}
+
+// CompareValue is what one product has for a row. Value and Unit are set for measurements.
+type CompareValue struct {
+	Text  string     `json:"text"`
+	Value *float64   `json:"value,omitempty"`
+	Unit  units.Unit `json:"unit,omitempty"`
+}
+
+// CompareRow lines up one specification of the compared products; Values has one entry per
+// product, null where it is unknown. Best and Worst hold the indexes of the products with the
+// best and worst values, for rows where more is better or worse, and only when they differ.
+type CompareRow struct {
+	Key    string          `json:"key"`
+	Label  string          `json:"label"`
+	Group  string          `json:"group"`
+	Values []*CompareValue `json:"values"`
+	Best   []int           `json:"best"`
+	Worst  []int           `json:"worst"`
+	Same   bool            `json:"same"`
+}
+
+// Comparison is products side by side. ID and Link are set once it is saved.
+type Comparison struct {
+	ID       *primitive.ObjectID `json:"_id,omitempty"`
+	Link     string              `json:"link,omitempty"`
+	Products []ComparedProduct   `json:"products"`
+	Rows     []CompareRow        `json:"rows"`
+}
+
+// preference says which way a row's values are better.
+type preference int
+
+const (
+	neither preference = iota
+	higher
+	lower
+)
+
+func ratingSummary(p model.Product) RatingSummary {
+	summary := RatingSummary{Average: p.Overall_Rating, Distribution: map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0}}
+	for _, r := range p.Reviews {
+		summary.Count++
+		summary.Distribution[r.Rating]++
+	}
+	return summary
+}
+
+// NewComparison lines up products for comparison, with the prices engine gives them at now and
+// measurements in the units of system.
+func NewComparison(products []model.Product, system units.System, engine *pricing.Engine, now time.Time) *Comparison {
+	c := &Comparison{Products: make([]ComparedProduct, 0, len(products)), Rows: []CompareRow{}}
+
+	prices := make([]*CompareValue, 0, len(products))
+	ratings := make([]*CompareValue, 0, len(products))
+	reviews := make([]*CompareValue, 0, len(products))
+	specs := make([]model.Specs, 0, len(products))
+
+	for _, p := range products {
+		price := engine.Price(p, now)
+		cp := ComparedProduct{
+			ID:             p.ID,
+			Name:           p.Name,
+			Company_Name:   p.Company_Name,
+			Model_Name:     p.Model_Name,
+			RegularPrice:   price.RegularPrice,
+			EffectivePrice: price.EffectivePrice,
+			OnSale:         price.OnSale,
+			Rating:         ratingSummary(p),
+		}
+		if len(p.Images) > 0 {
+			cp.Image = p.Images[0]
+		}
+		c.Products = append(c.Products, cp)
+
+		prices = append(prices, number(float64(cp.EffectivePrice), ""))
+		if cp.Rating.Count > 0 {
+			ratings = append(ratings, number(float64(cp.Rating.Average), ""))
+		} else {
+			ratings = append(ratings, nil)
+		}
+		reviews = append(reviews, number(float64(cp.Rating.Count), ""))
+		specs = append(specs, p.Specs.In(system))
+	}
+
+	measured := func(key string, label string, better preference, spec func(model.Specs) *units.Value) {
+		values := make([]*CompareValue, 0, len(specs))
+		for _, s := range specs {
+			if v := spec(s); v != nil {
+				values = append(values, number(v.Value, v.Unit))
+			} else {
+				values = append(values, nil)
+			}
+		}
+		c.addRow(key, label, "specifications", better, values)
+	}
+	described := func(key string, label string, text func(model.Product) string) {
+		values := make([]*CompareValue, 0, len(products))
+		for _, p := range products {
+			if t := strings.TrimSpace(text(p)); t != "" {
+				values = append(values, &CompareValue{Text: t})
+			} else {
+				values = append(values, nil)
+			}
+		}
+		c.addRow(key, label, "features", neither, values)
+	}
+
+	c.addRow("effective_price", "Price", "price", lower, prices)
+	c.addRow("rating", "Rating", "reviews", higher, ratings)
+	c.addRow("review_count", "Reviews", "reviews", higher, reviews)
+
+	measured("mileage", "Mileage", higher, func(s model.Specs) *units.Value { return s.Mileage })
+	measured("engine", "Engine", neither, func(s model.Specs) *units.Value { return s.Engine })
+	measured("power_output", "Power", higher, func(s model.Specs) *units.Value { return s.PowerOutput })
+	measured("top_speed", "Top speed", higher, func(s model.Specs) *units.Value { return s.TopSpeed })
+	measured("length", "Length", neither, func(s model.Specs) *units.Value { return s.Length })
+	measured("width", "Width", neither, func(s model.Specs) *units.Value { return s.Width })
+	measured("height", "Height", neither, func(s model.Specs) *units.Value { return s.Height })
+	measured("weight", "Weight", neither, func(s model.Specs) *units.Value { return s.Weight })
+
+	described("fuel_type", "Fuel type", func(p model.Product) string {
+		if len(p.Options.FuelTypes) > 0 {
+			return strings.Join(p.Options.FuelTypes, ", ")
+		}
+		return p.Description.FuelType
+	})
+	described("seating_capacity", "Seating capacity", func(p model.Product) string { return p.Description.SeatingCapacity })
+	described("transmissions", "Transmissions", func(p model.Product) string { return strings.Join(p.Options.Transmissions, ", ") })
+	described("trims", "Trims", func(p model.Product) string { return strings.Join(p.Options.Trims, ", ") })
+	described("colors", "Colors", func(p model.Product) string { return strings.Join(p.Options.Colors, ", ") })
+	described("tyre", "Tyres", func(p model.Product) string { return p.Description.Tyre })
+
+	return c
+}
+
+func number(n float64, unit units.Unit) *CompareValue {
+	text := strconv.FormatFloat(n, 'f', -1, 64)
+	if unit != "" {
+		text += " " + string(unit)
+	}
+	return &CompareValue{Text: text, Value: &n, Unit: unit}
+}
+
+// addRow adds a row, leaving out rows no product has a value for.
+func (c *Comparison) addRow(key string, label string, group string, better preference, values []*CompareValue) {
+	row := CompareRow{Key: key, Label: label, Group: group, Values: values, Best: []int{}, Worst: []int{}, Same: true}
+
+	known := 0
+	var first *CompareValue
+	for _, v := range values {
+		if v == nil {
+			row.Same = false
+			continue
+		}
+		known++
+		if first == nil {
+			first = v
+		} else if v.Text != first.Text {
+			row.Same = false
+		}
+	}
+	if known == 0 {
+		return
+	}
+
+	if better != neither && known > 1 && !row.Same {
+		row.Best, row.Worst = extremes(values, better)
+	}
+
+	c.Rows = append(c.Rows, row)
+}
+
+// extremes returns the indexes of the best and worst values. When every known value is the
+// same, there are neither.
+func extremes(values []*CompareValue, better preference) ([]int, []int) {
+	var hi, lo *float64
+	for _, v := range values {
+		if v == nil || v.Value == nil {
+			continue
+		}
+		if hi == nil || *v.Value > *hi {
+			hi = v.Value
+		}
+		if lo == nil || *v.Value < *lo {
+			lo = v.Value
+		}
+	}
+	if hi == nil || *hi == *lo {
+		return []int{}, []int{}
+	}
+
+	best, worst := *hi, *lo
+	if better == lower {
+		best, worst = *lo, *hi
+	}
+
+	bestAt, worstAt := []int{}, []int{}
+	for i, v := range values {
+		if v == nil || v.Value == nil {
+			continue
+		}
+		if *v.Value == best {
+			bestAt = append(bestAt, i)
+		}
+		if *v.Value == worst {
+			worstAt = append(worstAt, i)
+		}
+	}
+	return bestAt, worstAt
+}
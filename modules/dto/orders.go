@@ -0,0 +1,90 @@
+package dto
+
+import (
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type OrderItemRequest struct {
+	ProductID primitive.ObjectID `json:"product_id" binding:"required"`
+	VariantID primitive.ObjectID `json:"variant_id"`
+	Quantity  int                `json:"quantity" binding:"required,min=1,max=100"`
+}
+
+type OrderItemsRequest struct {
+	OrderItems []OrderItemRequest `json:"order_items" binding:"required,min=1,max=50,dive"`
+}
+
+// PlaceOrderRequest places an order paid by an earlier payment. Only admins name the customer;
+// users always order for themselves. The amount is worked out from the prices of the items;
+// OrderAmount is the one the customer was shown, and the order is refused when it no longer is.
+// TransactionID names the customer's payment for it, which must be of the amount worked out.
+type PlaceOrderRequest struct {
+	CustomerID    primitive.ObjectID `json:"customer_id"`
+	OrderItems    OrderItemsRequest  `json:"order_items"`
+	OrderAmount   int                `json:"order_amount" binding:"omitempty,gt=0"`
+	TransactionID primitive.ObjectID `json:"transaction_id" binding:"required"`
+}
+
+func (r *PlaceOrderRequest) ToOrder(customerID primitive.ObjectID) *model.Order {
+	items := make([]model.OrderItem, 0, len(r.OrderItems.OrderItems))
+	for _, item := range r.OrderItems.OrderItems {
+		items = append(items, model.OrderItem{ProductID: item.ProductID, VariantID: item.VariantID, Quantity: item.Quantity})
+	}
+
+	return &model.Order{
+		OrderItems:    model.OrderItems{OrderItems: items},
+		TransactionID: r.TransactionID,
+		CustomerID:    customerID,
+	}
+}
+
+// PaymentRequest records a payment. Only admins name who paid; users always pay for themselves.
+// A payment naming an order must be of the amount the order was priced at.
+type PaymentRequest struct {
+	PaidBy       primitive.ObjectID `json:"paid_by"`
+	OrderID      primitive.ObjectID `json:"order_id"`
+	Payment_Mode string             `json:"payment_type" binding:"required,max=50"`
+	Paid_Amount  int                `json:"paid_amount" binding:"required,gt=0"`
+}
+
+func (r *PaymentRequest) ToPayment(paidBy primitive.ObjectID) *model.Payment {
+	return &model.Payment{
+		OrderID:      r.OrderID,
+		PaidBy:       paidBy,
+		Payment_Mode: r.Payment_Mode,
+		Paid_Amount:  r.Paid_Amount,
+	}
+}
+
+type ShipmentRequest struct {
+	OrderID              primitive.ObjectID `json:"order_id" binding:"required"`
+	Phone                string             `json:"phone" binding:"required,min=7,max=20"`
+	Shipment_Company     string             `json:"shipment_company" binding:"required,max=100"`
+	Source_Location      AddressRequest     `json:"source_location"`
+	Destination_Location AddressRequest     `json:"destination_location"`
+}
+
+func (r *ShipmentRequest) ToShipment(customerID primitive.ObjectID) *model.Shipment {
+	return &model.Shipment{
+		OrderID:              r.OrderID,
+		CustomerID:           customerID,
+		Phone:                r.Phone,
+		Shipment_Company:     r.Shipment_Company,
+		Source_Location:      r.Source_Location.ToAddress(),
+		Destination_Location: r.Destination_Location.ToAddress(),
+	}
+}
+
+// CreateChatRequest opens a support chat about one of the signed in user's orders.
+type CreateChatRequest struct {
+	OrderID primitive.ObjectID `json:"order_id" binding:"required"`
+}
+
+func (r *CreateChatRequest) ToChat(userID primitive.ObjectID) *model.Chat {
+	return &model.Chat{
+		OrderID:  r.OrderID,
+		UserID:   userID,
+		Messages: []primitive.ObjectID{},
+	}
+}
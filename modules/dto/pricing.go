@@ -0,0 +1,120 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/pricing"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// VariantView is a variant with what it sells for.
+type VariantView struct {
+	model.Variant
+	EffectivePrice int  `json:"effective_price"`
+	OnSale         bool `json:"on_sale"`
+}
+
+// ShowPrices sets what products, as read from the database, sell for at now: effective_price,
+// on_sale and, while a sale with an end runs, sale_ends_at. Their variants get theirs too. The
+// price stored on products may be up to a minute behind their sales; this one is not.
+func ShowPrices(products []primitive.M, engine *pricing.Engine, now time.Time) {
+	for _, product := range products {
+		raw, err := bson.Marshal(product)
+		if err != nil {
+			continue
+		}
+		var p model.Product
+		if err := bson.Unmarshal(raw, &p); err != nil {
+			continue
+		}
+
+		q := engine.Price(p, now)
+		product["effective_price"] = q.EffectivePrice
+		product["on_sale"] = q.OnSale
+		if q.SaleEndsAt != nil {
+			product["sale_ends_at"] = q.SaleEndsAt
+		} else {
+			delete(product, "sale_ends_at")
+		}
+
+		if _, ok := product["variants"]; !ok {
+			continue
+		}
+		variants := make([]VariantView, 0, len(p.Variants))
+		for _, v := range p.Variants {
+			vq := engine.VariantPrice(p, v, now)
+			variants = append(variants, VariantView{Variant: v, EffectivePrice: vq.EffectivePrice, OnSale: vq.OnSale})
+		}
+		product["variants"] = variants
+	}
+}
+
+// CartLine is an item of a cart with what it sells for now. Items that can no longer be bought,
+// as their product or variant is gone, are not Available and count for nothing in the total.
+type CartLine struct {
+	ProductID    primitive.ObjectID  `json:"product_id"`
+	VariantID    *primitive.ObjectID `json:"variant_id,omitempty"`
+	Name         string              `json:"name,omitempty"`
+	Image        string              `json:"image,omitempty"`
+	Quantity     int                 `json:"quantity"`
+	Available    bool                `json:"available"`
+	RegularPrice int                 `json:"regular_price"`
+	UnitPrice    int                 `json:"unit_price"`
+	OnSale       bool                `json:"on_sale"`
+	SaleEndsAt   *time.Time          `json:"sale_ends_at,omitempty"`
+	LineTotal    int                 `json:"line_total"`
+	LineSavings  int                 `json:"line_savings"`
+}
+
+// CartView is a cart priced at a given time.
+type CartView struct {
+	Items   []CartLine `json:"items"`
+	Total   int        `json:"total"`
+	Savings int        `json:"savings"`
+}
+
+// NewCartView prices the items of a cart at now. products holds the products they are for.
+func NewCartView(items []model.CartItems, products []model.Product, engine *pricing.Engine, now time.Time) *CartView {
+	byID := make(map[primitive.ObjectID]model.Product, len(products))
+	for _, p := range products {
+		byID[p.ID] = p
+	}
+
+	cart := &CartView{Items: make([]CartLine, 0, len(items))}
+	for _, item := range items {
+		line := CartLine{ProductID: item.ProductID, Quantity: item.Quantity}
+		if !item.VariantID.IsZero() {
+			id := item.VariantID
+			line.VariantID = &id
+		}
+
+		p, found := byID[item.ProductID]
+		if found {
+			line.Name = p.Name
+			if len(p.Images) > 0 {
+				line.Image = p.Images[0]
+			}
+			if q, ok := engine.ItemPrice(p, item.VariantID, now); ok {
+				line.Available = true
+				line.RegularPrice = q.RegularPrice
+				line.UnitPrice = q.EffectivePrice
+				line.OnSale = q.OnSale
+				line.SaleEndsAt = q.SaleEndsAt
+				line.LineTotal = q.EffectivePrice * item.Quantity
+				line.LineSavings = (q.RegularPrice - q.EffectivePrice) * item.Quantity
+			}
+		}
+
+		cart.Total += line.LineTotal
+		cart.Savings += line.LineSavings
+		cart.Items = append(cart.Items, line)
+	}
+	return cart
+}
+
+// PriceHistoryRequest is the query string of the price history of a product.
+type PriceHistoryRequest struct {
+	Limit int64 `json:"limit" form:"limit" binding:"omitempty,min=1,max=200"`
+}
@@ -0,0 +1,61 @@
+package dto
+
+import (
+	"strings"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AdminSignUpRequest redeems an admin invite. The role comes from the invite, not the client.
+type AdminSignUpRequest struct {
+	Name        string          `json:"name" binding:"required,max=100"`
+	Email       string          `json:"email" binding:"required,email,max=254"`
+	Password    string          `json:"password" binding:"required"`
+	Phone       string          `json:"phone" binding:"omitempty,min=7,max=20"`
+	Website     string          `json:"website" binding:"omitempty,url"`
+	Address     *AddressRequest `json:"address"`
+	InviteToken string          `json:"invite_token" binding:"required"`
+}
+
+func (r *AdminSignUpRequest) ToAdmin() *model.Admin {
+	admin := &model.Admin{
+		Name:     strings.TrimSpace(r.Name),
+		Email:    NormalizeEmail(r.Email),
+		Password: r.Password,
+		Phone:    r.Phone,
+		Website:  r.Website,
+	}
+	if r.Address != nil {
+		admin.Address = r.Address.ToAddress()
+	}
+	return admin
+}
+
+type CreateCSERequest struct {
+	CseID       string `json:"cse_id" binding:"required,max=64"`
+	Password    string `json:"password" binding:"required"`
+	Name        string `json:"name" binding:"required,max=100"`
+	PhoneNumber string `json:"phone_number" binding:"required,min=7,max=20"`
+	Email       string `json:"email" binding:"required,email,max=254"`
+}
+
+// ToCSE returns an offline CSE without any chat.
+func (r *CreateCSERequest) ToCSE() *model.CSE {
+	return &model.CSE{
+		CseID:        strings.TrimSpace(r.CseID),
+		Password:     r.Password,
+		Name:         strings.TrimSpace(r.Name),
+		PhoneNumber:  r.PhoneNumber,
+		Email:        NormalizeEmail(r.Email),
+		Status:       "offline",
+		ActiveChats:  []primitive.ObjectID{},
+		PendingChats: []primitive.ObjectID{},
+		ClosedChats:  []primitive.ObjectID{},
+	}
+}
+
+type CSELoginRequest struct {
+	CseID    string `json:"cse_id" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
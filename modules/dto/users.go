@@ -0,0 +1,155 @@
+// Package dto holds the bodies clients send to and receive from the API. Requests only carry the
+// fields a client may set and validate them with binding tags; they are mapped into model types by
+// the handlers, which fill in every field the server owns.
+package dto
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NormalizeEmail returns email the way accounts store it and are looked up by: trimmed and in
+// lower case.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+type SignUpRequest struct {
+	Name     string `json:"name" binding:"required,max=100"`
+	Email    string `json:"email" binding:"required,email,max=254"`
+	Password string `json:"password" binding:"required"`
+	Phone    string `json:"phone" binding:"omitempty,min=7,max=20"`
+}
+
+// ToUser returns the user to create, with empty lists and no server owned field set.
+func (r *SignUpRequest) ToUser() *model.User {
+	return &model.User{
+		Name:      strings.TrimSpace(r.Name),
+		Email:     NormalizeEmail(r.Email),
+		Password:  r.Password,
+		Phone:     r.Phone,
+		Addresses: []model.Address{},
+		Cart:      []model.CartItems{},
+		Orders:    []primitive.ObjectID{},
+		Payments:  []primitive.ObjectID{},
+		Shipments: []primitive.ObjectID{},
+		Wishlist:  []primitive.ObjectID{},
+	}
+}
+
+type SignInRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+// Normalize puts the email in the form accounts are stored with.
+func (r *SignInRequest) Normalize() {
+	r.Email = NormalizeEmail(r.Email)
+}
+
+// EmailChangeRequest asks to move an account to another email.
+type EmailChangeRequest struct {
+	NewEmail string `json:"new_email"`
+}
+
+// Email returns the new email in the form accounts are stored with.
+func (r *EmailChangeRequest) Email() string {
+	return NormalizeEmail(r.NewEmail)
+}
+
+type AddressRequest struct {
+	AddressField string `json:"address_field" binding:"required,max=200"`
+	City         string `json:"city" binding:"required,max=100"`
+	State        string `json:"state" binding:"required,max=100"`
+	Country      string `json:"country" binding:"required,max=100"`
+	Pincode      string `json:"pincode" binding:"required,max=12"`
+}
+
+func (r AddressRequest) ToAddress() model.Address {
+	return model.Address{
+		AddressField: strings.TrimSpace(r.AddressField),
+		City:         strings.TrimSpace(r.City),
+		State:        strings.TrimSpace(r.State),
+		Country:      strings.TrimSpace(r.Country),
+		Pincode:      strings.TrimSpace(r.Pincode),
+	}
+}
+
+// AddAddressRequest adds an address to the signed in user, never to another one.
+type AddAddressRequest struct {
+	Address AddressRequest `json:"address" binding:"required"`
+}
+
+// AddToCartRequest puts a product in the cart. Products sold in variants need the variant too.
+type AddToCartRequest struct {
+	ProductID primitive.ObjectID `json:"product_id" binding:"required"`
+	VariantID primitive.ObjectID `json:"variant_id"`
+	Quantity  int                `json:"quantity" binding:"required,min=1,max=100"`
+}
+
+func (r *AddToCartRequest) ToCartItem() *model.CartItems {
+	return &model.CartItems{ProductID: r.ProductID, VariantID: r.VariantID, Quantity: r.Quantity}
+}
+
+// RemoveFromCartRequest takes a product out of the cart, or only one variant of it.
+type RemoveFromCartRequest struct {
+	ProductID primitive.ObjectID `json:"product_id" binding:"required"`
+	VariantID primitive.ObjectID `json:"variant_id"`
+}
+
+// UserProfile is what a user, or an admin looking at users, gets to see of an account.
+type UserProfile struct {
+	ID            primitive.ObjectID   `json:"_id"`
+	Name          string               `json:"name"`
+	Email         string               `json:"email"`
+	Pending_Email string               `json:"pending_email,omitempty"`
+	Verified      bool                 `json:"verified"`
+	Phone         string               `json:"phone"`
+	Addresses     []model.Address      `json:"addresses"`
+	Cart          []model.CartItems    `json:"cart"`
+	Wishlist      []primitive.ObjectID `json:"wishlist"`
+	Orders        []primitive.ObjectID `json:"orders"`
+	Payments      []primitive.ObjectID `json:"payments"`
+	Shipments     []primitive.ObjectID `json:"shipments"`
+	CreatedAt     time.Time            `json:"created_At"`
+	UpdatedAt     time.Time            `json:"updated_At"`
+}
+
+func NewUserProfile(u *model.User) *UserProfile {
+	return &UserProfile{
+		ID:            u.ID,
+		Name:          u.Name,
+		Email:         u.Email,
+		Pending_Email: u.Pending_Email,
+		Verified:      u.Verified,
+		Phone:         u.Phone,
+		Addresses:     u.Addresses,
+		Cart:          u.Cart,
+		Wishlist:      u.Wishlist,
+		Orders:        u.Orders,
+		Payments:      u.Payments,
+		Shipments:     u.Shipments,
+		CreatedAt:     u.CreatedAt,
+		UpdatedAt:     u.UpdatedAt,
+	}
+}
+
+// UserProfileFromDoc decodes a user document as read from the database into its profile.
+func UserProfileFromDoc(doc primitive.M) (*UserProfile, error) {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode user document : %w", err)
+	}
+
+	var user model.User
+	if err := bson.Unmarshal(raw, &user); err != nil {
+		return nil, fmt.Errorf("cannot decode user document : %w", err)
+	}
+
+	return NewUserProfile(&user), nil
+}
@@ -0,0 +1,103 @@
+package encrypt
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// rangePrefixLength is how many hex digits of a SHA-1 name a range, as in the Pwned Passwords API.
+const rangePrefixLength = 5
+
+// BreachedList tells whether a password is known from a breach. Passwords are looked up by
+// SHA-1 the way the Pwned Passwords range API works: the first five hex digits of the hash
+// select a range and only the rest of the hash is compared within it.
+//
+// The list is either a single file of SHA-1 hashes, one per line and optionally followed by
+// ":count", which is loaded into memory, or a directory of range files named after their
+// prefix (e.g. 21BD1) holding "SUFFIX:count" lines, which are read on demand. The directory
+// layout suits the full downloaded corpus, which is too big to keep in memory.
+type BreachedList struct {
+	dir    string
+	ranges map[string]map[string]struct{}
+}
+
+// LoadBreachedList opens the list at path, a file or a directory of range files.
+func LoadBreachedList(path string) (*BreachedList, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open breached password list: %w", err)
+	}
+
+	if info.IsDir() {
+		return &BreachedList{dir: path}, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open breached password list: %w", err)
+	}
+	defer file.Close()
+
+	list := &BreachedList{ranges: map[string]map[string]struct{}{}}
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		hash, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if hash == "" || strings.HasPrefix(hash, "#") {
+			continue
+		}
+		hash = strings.ToUpper(hash)
+		if len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("breached password list line %d is not a SHA-1 hash", line)
+		}
+
+		prefix, suffix := hash[:rangePrefixLength], hash[rangePrefixLength:]
+		if list.ranges[prefix] == nil {
+			list.ranges[prefix] = map[string]struct{}{}
+		}
+		list.ranges[prefix][suffix] = struct{}{}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read breached password list: %w", err)
+	}
+
+	return list, nil
+}
+
+// Contains reports whether password is in the list.
+func (b *BreachedList) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:rangePrefixLength], hash[rangePrefixLength:]
+
+	if b.ranges != nil {
+		_, found := b.ranges[prefix][suffix]
+		return found, nil
+	}
+
+	file, err := os.Open(filepath.Join(b.dir, prefix))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("cannot read breached password range %s: %w", prefix, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		candidate, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if strings.EqualFold(candidate, suffix) {
+			return true, nil
+		}
+	}
+
+	return false, scanner.Err()
+}
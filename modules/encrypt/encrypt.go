@@ -2,16 +2,40 @@ package encrypt
 
 import (
 	"fmt"
+	"sync/atomic"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
+// cost is the bcrypt cost new hashes are made with, see SetCost.
+var cost atomic.Int64
+
+func init() {
+	cost.Store(int64(bcrypt.DefaultCost))
+}
+
+// SetCost changes the bcrypt cost of new hashes. Hashes made with a lower cost keep verifying
+// and are upgraded on the next sign in, see NeedsRehash.
+func SetCost(c int) error {
+	if c < bcrypt.MinCost || c > bcrypt.MaxCost {
+		return fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	cost.Store(int64(c))
+	return nil
+}
+
+// NeedsRehash reports whether hash was made with a lower cost than new hashes are.
+func NeedsRehash(hash string) bool {
+	c, err := bcrypt.Cost([]byte(hash))
+	return err == nil && int64(c) < cost.Load()
+}
+
 func Hash(password string) (string, error) {
 
 	if password == "" {
 		return "", fmt.Errorf("password can't be empty")
 	} else {
-		pass, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		pass, err := bcrypt.GenerateFromPassword([]byte(password), int(cost.Load()))
 
 		if err != nil {
 			return "Could not generate password", err
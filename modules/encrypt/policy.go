@@ -0,0 +1,191 @@
+package encrypt
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxPasswordBytes is the longest password bcrypt takes into account.
+const maxPasswordBytes = 72
+
+// Policy is what passwords chosen by people must satisfy.
+type Policy struct {
+	MinLength int
+	// MinClasses is how many of lower case, upper case, digits and symbols must be used.
+	MinClasses int
+	// Breached, when set, rejects passwords known from public breaches.
+	Breached *BreachedList
+}
+
+// DefaultPolicy is used until Configure is called.
+var DefaultPolicy = Policy{MinLength: 10, MinClasses: 3}
+
+var (
+	policyMu sync.RWMutex
+	policy   = DefaultPolicy
+)
+
+// Configure replaces the policy CheckPassword enforces.
+func Configure(p Policy) {
+	policyMu.Lock()
+	defer policyMu.Unlock()
+	policy = p
+}
+
+// Violation is one rule a password broke, named like the validator rule it corresponds to.
+type Violation struct {
+	Rule    string
+	Param   string
+	Message string
+}
+
+// CheckPassword returns every rule of the policy password breaks. personal holds the account's
+// email, name and the like, which the password must not be or contain.
+func CheckPassword(password string, personal ...string) ([]Violation, error) {
+	policyMu.RLock()
+	p := policy
+	policyMu.RUnlock()
+
+	var violations []Violation
+
+	if n := utf8.RuneCountInString(password); n < p.MinLength {
+		violations = append(violations, Violation{
+			Rule:    "min",
+			Param:   strconv.Itoa(p.MinLength),
+			Message: fmt.Sprintf("must be at least %d characters long", p.MinLength),
+		})
+	}
+
+	if len(password) > maxPasswordBytes {
+		violations = append(violations, Violation{
+			Rule:    "max",
+			Param:   strconv.Itoa(maxPasswordBytes),
+			Message: fmt.Sprintf("must be at most %d bytes long", maxPasswordBytes),
+		})
+	}
+
+	if classes := characterClasses(password); classes < p.MinClasses {
+		violations = append(violations, Violation{
+			Rule:    "classes",
+			Param:   strconv.Itoa(p.MinClasses),
+			Message: fmt.Sprintf("must mix at least %d of lower case letters, upper case letters, digits and symbols", p.MinClasses),
+		})
+	}
+
+	lowered := strings.ToLower(password)
+	for _, value := range personal {
+		if containsPersonal(lowered, value) {
+			violations = append(violations, Violation{
+				Rule:    "personal",
+				Message: "must not contain your email or name",
+			})
+			break
+		}
+	}
+
+	if p.Breached != nil && password != "" {
+		breached, err := p.Breached.Contains(password)
+		if err != nil {
+			return nil, err
+		}
+		if breached {
+			violations = append(violations, Violation{
+				Rule:    "breached",
+				Message: "has appeared in a data breach, choose another one",
+			})
+		}
+	}
+
+	return violations, nil
+}
+
+func characterClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	classes := 0
+	for _, used := range []bool{lower, upper, digit, symbol} {
+		if used {
+			classes++
+		}
+	}
+	return classes
+}
+
+// containsPersonal reports whether password contains value, or the local part of value when
+// it is an email. Values too short to be guessed from are ignored.
+func containsPersonal(password string, value string) bool {
+	value = strings.ToLower(strings.TrimSpace(value))
+	candidates := []string{value}
+	if local, _, ok := strings.Cut(value, "@"); ok {
+		candidates = append(candidates, local)
+	}
+	candidates = append(candidates, strings.Fields(value)...)
+
+	for _, c := range candidates {
+		if len(c) >= 4 && strings.Contains(password, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// ConfigureFromEnv sets the bcrypt cost from BCRYPT_COST and the policy from PASSWORD_MIN_LENGTH,
+// PASSWORD_MIN_CLASSES and BREACHED_PASSWORDS_PATH, keeping the defaults for unset variables.
+func ConfigureFromEnv() error {
+	if v := os.Getenv("BCRYPT_COST"); v != "" {
+		c, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid BCRYPT_COST %q", v)
+		}
+		if err := SetCost(c); err != nil {
+			return err
+		}
+	}
+
+	p := DefaultPolicy
+
+	if v := os.Getenv("PASSWORD_MIN_LENGTH"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPasswordBytes {
+			return fmt.Errorf("invalid PASSWORD_MIN_LENGTH %q", v)
+		}
+		p.MinLength = n
+	}
+
+	if v := os.Getenv("PASSWORD_MIN_CLASSES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > 4 {
+			return fmt.Errorf("invalid PASSWORD_MIN_CLASSES %q", v)
+		}
+		p.MinClasses = n
+	}
+
+	if path := os.Getenv("BREACHED_PASSWORDS_PATH"); path != "" {
+		list, err := LoadBreachedList(path)
+		if err != nil {
+			return err
+		}
+		p.Breached = list
+	}
+
+	Configure(p)
+
+	return nil
+}
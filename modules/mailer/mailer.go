@@ -0,0 +1,112 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Mailer delivers plain text emails such as password reset codes.
+type Mailer interface {
+	Send(to string, subject string, body string) error
+}
+
+// SMTPMailer sends emails through an SMTP server, authenticating when a username is set.
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(to string, subject string, body string) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	return smtp.SendMail(m.Host+":"+m.Port, auth, m.From, []string{sanitize(to)}, message(m.From, to, subject, body))
+}
+
+// LogMailer writes emails to a file, or to the logger when no file is set, instead of sending them.
+// It is meant for local development.
+type LogMailer struct {
+	Logger *log.Logger
+	Path   string
+
+	mu sync.Mutex
+}
+
+func (m *LogMailer) Send(to string, subject string, body string) error {
+	msg := message("carsgo@localhost", to, subject, body)
+
+	if m.Path == "" {
+		m.Logger.Printf("mail to %s :\n%s", sanitize(to), msg)
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := os.OpenFile(m.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "%s\n\n", msg)
+	return err
+}
+
+// FromEnv builds the mailer selected by MAILER, either "smtp" or "log" (the default).
+//
+// The smtp mailer reads SMTP_HOST, SMTP_PORT (default 587), SMTP_USERNAME, SMTP_PASSWORD and
+// SMTP_FROM. The log mailer writes to MAILER_LOG_FILE, or to logger when it is empty.
+func FromEnv(logger *log.Logger) (Mailer, error) {
+	switch os.Getenv("MAILER") {
+	case "smtp":
+		m := &SMTPMailer{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     os.Getenv("SMTP_PORT"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("SMTP_FROM"),
+		}
+		if m.Host == "" || m.From == "" {
+			return nil, fmt.Errorf("SMTP_HOST and SMTP_FROM must be set when MAILER is smtp")
+		}
+		if m.Port == "" {
+			m.Port = "587"
+		}
+		return m, nil
+	case "", "log":
+		return &LogMailer{Logger: logger, Path: os.Getenv("MAILER_LOG_FILE")}, nil
+	default:
+		return nil, fmt.Errorf("unknown MAILER %q", os.Getenv("MAILER"))
+	}
+}
+
+func message(from string, to string, subject string, body string) []byte {
+	var b strings.Builder
+
+	b.WriteString("From: " + sanitize(from) + "\r\n")
+	b.WriteString("To: " + sanitize(to) + "\r\n")
+	b.WriteString("Subject: " + sanitize(subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	return []byte(b.String())
+}
+
+// sanitize strips line breaks so header values cannot inject further headers.
+func sanitize(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
@@ -1,8 +1,10 @@
 package model
 
 import (
+	"strings"
 	"time"
 
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/units"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -15,35 +17,63 @@ type Address struct {
 }
 
 type User struct {
-	ID        primitive.ObjectID   `json:"_id" bson:"_id"`
-	Name      string               `json:"name" Usage:"required"`
-	Email     string               `json:"email" Usage:"required"`
-	Password  string               `json:"password" Usage:"required"`
-	Token     string               `json:"token"`
-	New_Token string               `json:"new_token"`
-	Cart      []CartItems          `json:"cart"`
-	Orders    []primitive.ObjectID `json:"orders"`
-	Phone     string               `json:"phone"`
-	Addresses []Address            `json:"addresses"`
-	Payments  []primitive.ObjectID `json:"payments"`
-	Shipments []primitive.ObjectID `json:"shipments"`
-	Wishlist  []primitive.ObjectID `json:"wishlist"`
-	CreatedAt time.Time            `json:"created_At"`
-	UpdatedAt time.Time            `json:"updated_At"`
+	ID            primitive.ObjectID   `json:"_id" bson:"_id"`
+	Name          string               `json:"name"`
+	Email         string               `json:"email"`
+	Password      string               `json:"password"`
+	Token         string               `json:"token"`
+	New_Token     string               `json:"new_token"`
+	Token_Family  string               `json:"token_family"`
+	Verified      bool                 `json:"verified"`
+	Pending_Email string               `json:"pending_email"`
+	Cart          []CartItems          `json:"cart"`
+	Orders        []primitive.ObjectID `json:"orders"`
+	Phone         string               `json:"phone"`
+	Addresses     []Address            `json:"addresses"`
+	Payments      []primitive.ObjectID `json:"payments"`
+	Shipments     []primitive.ObjectID `json:"shipments"`
+	Wishlist      []primitive.ObjectID `json:"wishlist"`
+	Identities    []Identity           `json:"-" bson:"identities,omitempty"`
+	CreatedAt     time.Time            `json:"created_At"`
+	UpdatedAt     time.Time            `json:"updated_At"`
 }
 
+// Identity links a user to its account at an OpenID Connect provider.
+type Identity struct {
+	Provider string    `bson:"provider" json:"provider"`
+	Subject  string    `bson:"subject" json:"subject"`
+	Email    string    `bson:"email" json:"email"`
+	LinkedAt time.Time `bson:"linked_at" json:"linked_at"`
+}
+
+// CartItems is a product in a cart. VariantID is set for products sold in variants.
 type CartItems struct {
 	ProductID primitive.ObjectID `json:"product_id"`
+	VariantID primitive.ObjectID `json:"variant_id,omitempty" bson:"variant_id,omitempty"`
 	Quantity  int                `json:"quantity"`
 }
 
+// Category is a node of the category tree. Ancestors lists the ids from the root down to the
+// parent, and Path joins their slugs with the category's own, e.g. "suv/compact-suv".
 type Category struct {
-	ID                  primitive.ObjectID `json:"_id" bson:"_id"`
-	Name                string             `json:"name" Usage:"required"`
-	General_Description string             `json:"general_description" Usage:"required"`
-	CategoryImage       string             `json:"category_image" Usage:"required"`
-	CreatedAt           time.Time          `json:"created_At"`
-	UpdatedAt           time.Time          `json:"updated_At"`
+	ID                  primitive.ObjectID   `json:"_id" bson:"_id"`
+	Name                string               `json:"name"`
+	General_Description string               `json:"general_description"`
+	CategoryImage       string               `json:"category_image"`
+	Slug                string               `json:"slug" bson:"slug"`
+	ParentID            *primitive.ObjectID  `json:"parent_id" bson:"parent_id"`
+	Ancestors           []primitive.ObjectID `json:"ancestors" bson:"ancestors"`
+	Path                string               `json:"path" bson:"path"`
+	CreatedAt           time.Time            `json:"created_At"`
+	UpdatedAt           time.Time            `json:"updated_At"`
+}
+
+// CategoryChanges are the edits made to a category; nil fields are left as they are.
+type CategoryChanges struct {
+	Name                *string
+	General_Description *string
+	CategoryImage       *string
+	Slug                *string
 }
 
 type Dimensions struct {
@@ -64,30 +94,156 @@ type ProductDesc struct {
 	Weight          int        `json:"weight"`
 }
 
+// Specs are the measurable specifications of a product, read from its description and kept in
+// the base unit of each quantity so they can be filtered and sorted on. Unknown ones are nil.
+type Specs struct {
+	Mileage     *units.Value `json:"mileage,omitempty" bson:"mileage,omitempty"`
+	Engine      *units.Value `json:"engine,omitempty" bson:"engine,omitempty"`
+	PowerOutput *units.Value `json:"power_output,omitempty" bson:"power_output,omitempty"`
+	TopSpeed    *units.Value `json:"top_speed,omitempty" bson:"top_speed,omitempty"`
+	Length      *units.Value `json:"length,omitempty" bson:"length,omitempty"`
+	Width       *units.Value `json:"width,omitempty" bson:"width,omitempty"`
+	Height      *units.Value `json:"height,omitempty" bson:"height,omitempty"`
+	Weight      *units.Value `json:"weight,omitempty" bson:"weight,omitempty"`
+}
+
+// SpecError is a description field whose text could not be read as a measurement.
+type SpecError struct {
+	Field string
+	Err   error
+}
+
+// Specs reads the measurable specifications out of the description. Fields left blank are
+// unknown; the others are reported when they cannot be read.
+func (d ProductDesc) Specs() (Specs, []SpecError) {
+	var specs Specs
+	var errs []SpecError
+
+	fields := []struct {
+		name     string
+		text     string
+		quantity units.Quantity
+		spec     **units.Value
+	}{
+		{"mileage", d.Mileage, units.FuelEconomy, &specs.Mileage},
+		{"engine", d.Engine, units.Displacement, &specs.Engine},
+		{"power_output", d.PowerOutput, units.Power, &specs.PowerOutput},
+		{"top_speed", d.TopSpeed, units.Speed, &specs.TopSpeed},
+		{"dimensions.length", d.Dimension.Length, units.Length, &specs.Length},
+		{"dimensions.width", d.Dimension.Width, units.Length, &specs.Width},
+		{"dimensions.height", d.Dimension.Height, units.Length, &specs.Height},
+	}
+	for _, f := range fields {
+		if strings.TrimSpace(f.text) == "" {
+			continue
+		}
+		v, err := units.Parse(f.text, f.quantity)
+		if err != nil {
+			errs = append(errs, SpecError{Field: f.name, Err: err})
+			continue
+		}
+		*f.spec = &v
+	}
+
+	if d.Weight > 0 {
+		specs.Weight = &units.Value{Value: float64(d.Weight), Unit: units.KG}
+	}
+
+	return specs, errs
+}
+
+// In returns the specs in the units system shows them in.
+func (s Specs) In(system units.System) Specs {
+	convert := func(v *units.Value) *units.Value {
+		if v == nil {
+			return nil
+		}
+		converted := v.In(system)
+		return &converted
+	}
+	return Specs{
+		Mileage:     convert(s.Mileage),
+		Engine:      convert(s.Engine),
+		PowerOutput: convert(s.PowerOutput),
+		TopSpeed:    convert(s.TopSpeed),
+		Length:      convert(s.Length),
+		Width:       convert(s.Width),
+		Height:      convert(s.Height),
+		Weight:      convert(s.Weight),
+	}
+}
+
 type Review struct {
 	ID         primitive.ObjectID `json:"_id" bson:"_id"`
 	ProductID  primitive.ObjectID `json:"product_id"`
+	VariantID  primitive.ObjectID `json:"variant_id,omitempty" bson:"variant_id,omitempty"`
 	Review     string             `json:"review"`
 	Rating     int                `json:"rating"`
 	CustomerID primitive.ObjectID `json:"customer_id"`
 	CreatedAt  time.Time          `json:"created_At"`
 }
 
+// Variant is one buyable configuration of a product, e.g. its red automatic in the top trim.
+// Attributes a variant leaves empty do not vary for the product.
+type Variant struct {
+	ID           primitive.ObjectID `json:"_id" bson:"_id"`
+	SKU          string             `json:"sku" bson:"sku"`
+	Color        string             `json:"color,omitempty" bson:"color,omitempty"`
+	Trim         string             `json:"trim,omitempty" bson:"trim,omitempty"`
+	Transmission string             `json:"transmission,omitempty" bson:"transmission,omitempty"`
+	FuelType     string             `json:"fuel_type,omitempty" bson:"fuel_type,omitempty"`
+	RegularPrice int                `json:"regular_price" bson:"regular_price"`
+	SalePrice    int                `json:"sale_price,omitempty" bson:"sale_price,omitempty"`
+	Stock        int                `json:"stock" bson:"stock"`
+	Images       []string           `json:"images" bson:"images"`
+}
+
+// VariantOptions are the values the variants of a product offer for each attribute.
+type VariantOptions struct {
+	Colors        []string `json:"colors" bson:"colors"`
+	Trims         []string `json:"trims" bson:"trims"`
+	Transmissions []string `json:"transmissions" bson:"transmissions"`
+	FuelTypes     []string `json:"fuel_types" bson:"fuel_types"`
+}
+
+// VariantChanges are the edits made to a variant; nil fields are left as they are.
+type VariantChanges struct {
+	SKU          *string
+	Color        *string
+	Trim         *string
+	Transmission *string
+	FuelType     *string
+	RegularPrice *int
+	SalePrice    *int
+	Stock        *int
+	Images       []string
+}
+
+// Product is a car model. When it is sold in variants, its price is the lowest of theirs, its
+// stock their total, and reviews and ratings are still kept here for all of them together.
 type Product struct {
 	ID                primitive.ObjectID `json:"_id" bson:"_id"`
-	Name              string             `json:"name" Usage:"required"`
-	Description       ProductDesc        `json:"description" Usage:"required"`
-	Category          string             `json:"category" Usage:"required"`
-	Company_Name      string             `json:"company_name" Usage:"required"`
-	Model_Name        string             `json:"model_name" Usage:"required"`
-	RegularPrice      int                `json:"regular_price" Usage:"required"`
+	Name              string             `json:"name"`
+	Description       ProductDesc        `json:"description"`
+	Specs             Specs              `json:"specs" bson:"specs"`
+	Category          string             `json:"category"`
+	CategoryID        primitive.ObjectID `json:"category_id" bson:"category_id"`
+	Company_Name      string             `json:"company_name"`
+	Model_Name        string             `json:"model_name"`
+	RegularPrice      int                `json:"regular_price"`
 	SalePrice         int                `json:"sale_price"`
 	SaleStarts        time.Time          `json:"sale_starts"`
 	SaleEnds          time.Time          `json:"sale_ends"`
-	InStock           bool               `json:"in_stock" Usage:"required"`
-	Stock             int                `json:"stock" Usage:"required"`
-	SKU               string             `json:"sku" Usage:"required"`
-	Images            []string           `json:"images" Usage:"required"`
+	SaleTimezone      string             `json:"sale_timezone,omitempty" bson:"sale_timezone,omitempty"`
+	EffectivePrice    int                `json:"effective_price" bson:"effective_price"`
+	OnSale            bool               `json:"on_sale" bson:"on_sale"`
+	PricedAt          time.Time          `json:"priced_at" bson:"priced_at,omitempty"`
+	InStock           bool               `json:"in_stock"`
+	Stock             int                `json:"stock"`
+	SKU               string             `json:"sku"`
+	Images            []string           `json:"images"`
+	Variants          []Variant          `json:"variants" bson:"variants"`
+	Options           VariantOptions     `json:"options" bson:"options"`
 	Reviews           []Review           `json:"reviews"`
 	Overall_Rating    float32            `json:"rating"`
 	Summarized_Review string             `json:"summarized_review"`
@@ -95,9 +251,21 @@ type Product struct {
 	UpdatedAt         time.Time          `json:"updated_At"`
 }
 
+// Variant returns the variant of p with the given id, or nil when p has none such.
+func (p Product) Variant(id primitive.ObjectID) *Variant {
+	for i := range p.Variants {
+		if p.Variants[i].ID == id {
+			return &p.Variants[i]
+		}
+	}
+	return nil
+}
+
 type OrderItem struct {
 	ProductID primitive.ObjectID `json:"product_id"`
+	VariantID primitive.ObjectID `json:"variant_id,omitempty" bson:"variant_id,omitempty"`
 	Quantity  int                `json:"quantity"`
+	UnitPrice int                `json:"unit_price" bson:"unit_price"`
 }
 
 type OrderItems struct {
@@ -144,17 +312,24 @@ type Payment struct {
 }
 
 type Admin struct {
-	ID        primitive.ObjectID `json:"_id" bson:"_id"`
-	Name      string             `json:"name" Usage:"required"`
-	Password  string             `json:"password" Usage:"required"`
-	Address   Address            `json:"address" Usage:"required"`
-	Website   string             `json:"website" Usage:"required"`
-	Token     string             `json:"token" Usage:"required"`
-	New_Token string             `json:"new_token" Usage:"required"`
-	Email     string             `json:"email" Usage:"required"`
-	Phone     string             `json:"phone" Usage:"required"`
-	CreatedAt time.Time          `json:"created_At"`
-	UpdatedAt time.Time          `json:"updated_At"`
+	ID                  primitive.ObjectID `json:"_id" bson:"_id"`
+	Name                string             `json:"name"`
+	Password            string             `json:"password"`
+	Address             Address            `json:"address"`
+	Website             string             `json:"website"`
+	Token               string             `json:"token"`
+	New_Token           string             `json:"new_token"`
+	Token_Family        string             `json:"token_family"`
+	Role                string             `json:"role"`
+	TOTP_Enabled        bool               `json:"totp_enabled"`
+	TOTP_Secret         string             `json:"-"`
+	TOTP_Pending_Secret string             `json:"-"`
+	TOTP_Last_Step      int64              `json:"-"`
+	Recovery_Codes      []string           `json:"-"`
+	Email               string             `json:"email"`
+	Phone               string             `json:"phone"`
+	CreatedAt           time.Time          `json:"created_At"`
+	UpdatedAt           time.Time          `json:"updated_At"`
 }
 
 type Ticket struct {
@@ -169,11 +344,19 @@ type Ticket struct {
 type CSE struct {
 	ID                primitive.ObjectID   `json:"_id" bson:"_id"`
 	CseID             string               `bson:"cse_id" json:"cse_id"`
-	Password          string               `json:"password" Usage:"required"`
+	Password          string               `json:"password"`
 	Name              string               `bson:"name" json:"name"`
 	PhoneNumber       string               `bson:"phone_number" json:"phone_number"`
 	Email             string               `bson:"email" json:"email"`
 	Status            string               `bson:"status" json:"status"` // "online" or "offline"
+	Token             string               `bson:"token" json:"token"`
+	New_Token         string               `bson:"new_token" json:"new_token"`
+	Token_Family      string               `bson:"token_family" json:"token_family"`
+	TOTPEnabled       bool                 `bson:"totp_enabled" json:"totp_enabled"`
+	TOTPSecret        string               `bson:"totp_secret,omitempty" json:"-"`
+	TOTPPendingSecret string               `bson:"totp_pending_secret,omitempty" json:"-"`
+	TOTPLastStep      int64                `bson:"totp_last_step,omitempty" json:"-"`
+	RecoveryCodes     []string             `bson:"recovery_codes,omitempty" json:"-"`
 	ActiveChats       []primitive.ObjectID `bson:"active_chats" json:"active_chats"`
 	PendingChats      []primitive.ObjectID `bson:"pending_chats" json:"pending_chats"`
 	ClosedChats       []primitive.ObjectID `bson:"closed_chats" json:"closed_chats"`
@@ -203,3 +386,194 @@ type Message struct {
 	ReceiverID primitive.ObjectID `bson:"receiver_id" json:"receiver_id"`
 	Timestamp  time.Time          `bson:"timestamp" json:"timestamp"`
 }
+
+type Role struct {
+	ID          primitive.ObjectID `bson:"_id" json:"_id"`
+	Name        string             `bson:"name" json:"name"`
+	Permissions []string           `bson:"permissions" json:"permissions"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+type AdminInvite struct {
+	ID        primitive.ObjectID `bson:"_id" json:"_id"`
+	Email     string             `bson:"email" json:"email"`
+	Role      string             `bson:"role" json:"role"`
+	InvitedBy primitive.ObjectID `bson:"invited_by" json:"invited_by"`
+	ExpiresAt time.Time          `bson:"expires_at" json:"expires_at"`
+	UsedAt    *time.Time         `bson:"used_at,omitempty" json:"used_at,omitempty"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+type PasswordReset struct {
+	ID        primitive.ObjectID `bson:"_id" json:"_id"`
+	Principal string             `bson:"principal" json:"principal"`
+	AccountID primitive.ObjectID `bson:"account_id" json:"account_id"`
+	Email     string             `bson:"email" json:"email"`
+	CodeHash  string             `bson:"code_hash" json:"-"`
+	Attempts  int                `bson:"attempts" json:"attempts"`
+	ExpiresAt time.Time          `bson:"expires_at" json:"expires_at"`
+	UsedAt    *time.Time         `bson:"used_at,omitempty" json:"used_at,omitempty"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// SecuritySettings is the single document of the settings collection holding security policies.
+type SecuritySettings struct {
+	ID              string             `bson:"_id" json:"-"`
+	RequireAdmin2FA bool               `bson:"require_admin_2fa" json:"require_admin_2fa"`
+	UpdatedBy       primitive.ObjectID `bson:"updated_by,omitempty" json:"updated_by,omitempty"`
+	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// LoginAttempt counts recent failed sign ins of one account or one IP address.
+type LoginAttempt struct {
+	ID          string    `bson:"_id" json:"key"`
+	Kind        string    `bson:"kind" json:"kind"`
+	Principal   string    `bson:"principal,omitempty" json:"principal,omitempty"`
+	Identifier  string    `bson:"identifier" json:"identifier"`
+	Failures    int       `bson:"failures" json:"failures"`
+	LastFailure time.Time `bson:"last_failure" json:"last_failure"`
+	LockedUntil time.Time `bson:"locked_until,omitempty" json:"locked_until,omitempty"`
+	ExpiresAt   time.Time `bson:"expires_at" json:"-"`
+}
+
+type SecurityEvent struct {
+	ID         primitive.ObjectID     `bson:"_id" json:"_id"`
+	Type       string                 `bson:"type" json:"type"`
+	Principal  string                 `bson:"principal,omitempty" json:"principal,omitempty"`
+	Identifier string                 `bson:"identifier,omitempty" json:"identifier,omitempty"`
+	IP         string                 `bson:"ip,omitempty" json:"ip,omitempty"`
+	Actor      primitive.ObjectID     `bson:"actor,omitempty" json:"actor,omitempty"`
+	Details    map[string]interface{} `bson:"details,omitempty" json:"details,omitempty"`
+	CreatedAt  time.Time              `bson:"created_at" json:"created_at"`
+}
+
+// LoginSession is one sign in of a user, admin or CSE on one device. Its id is the family of the
+// tokens issued for it, and only the hash of the latest refresh token is kept.
+type LoginSession struct {
+	ID          primitive.ObjectID `bson:"_id" json:"_id"`
+	Principal   string             `bson:"principal" json:"principal"`
+	SubjectID   primitive.ObjectID `bson:"subject_id" json:"subject_id"`
+	RefreshHash string             `bson:"refresh_hash" json:"-"`
+	Device      string             `bson:"device" json:"device"`
+	IP          string             `bson:"ip" json:"ip"`
+	UserAgent   string             `bson:"user_agent" json:"user_agent"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	LastSeenAt  time.Time          `bson:"last_seen_at" json:"last_seen_at"`
+	ExpiresAt   time.Time          `bson:"expires_at" json:"expires_at"`
+	RevokedAt   *time.Time         `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+}
+
+// OIDCLogin is a sign in started at an OpenID Connect provider, stored until the provider
+// redirects back. Its id is the hash of the state sent to the provider.
+type OIDCLogin struct {
+	ID        string    `bson:"_id"`
+	Provider  string    `bson:"provider"`
+	Nonce     string    `bson:"nonce"`
+	Verifier  string    `bson:"verifier"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+// APIKey lets an integration call admin endpoints within its scopes. Only the hash of the key is
+// stored; the prefix identifies it in listings and logs.
+type APIKey struct {
+	ID         primitive.ObjectID `bson:"_id" json:"_id"`
+	Name       string             `bson:"name" json:"name"`
+	Prefix     string             `bson:"prefix" json:"prefix"`
+	Hash       string             `bson:"hash" json:"-"`
+	Scopes     []string           `bson:"scopes" json:"scopes"`
+	CreatedBy  primitive.ObjectID `bson:"created_by" json:"created_by"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	ExpiresAt  time.Time          `bson:"expires_at" json:"expires_at"`
+	LastUsedAt *time.Time         `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	LastUsedIP string             `bson:"last_used_ip,omitempty" json:"last_used_ip,omitempty"`
+	RevokedAt  *time.Time         `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+}
+
+// APIKeyCall records one request made with an API key.
+type APIKeyCall struct {
+	ID        primitive.ObjectID `bson:"_id" json:"_id"`
+	KeyID     primitive.ObjectID `bson:"key_id" json:"key_id"`
+	Prefix    string             `bson:"prefix" json:"prefix"`
+	Method    string             `bson:"method" json:"method"`
+	Path      string             `bson:"path" json:"path"`
+	Status    int                `bson:"status" json:"status"`
+	IP        string             `bson:"ip" json:"ip"`
+	RequestID string             `bson:"request_id,omitempty" json:"request_id,omitempty"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// Comparison is a side by side comparison of products saved to be shared by link.
+type Comparison struct {
+	ID         primitive.ObjectID   `bson:"_id" json:"_id"`
+	ProductIDs []primitive.ObjectID `bson:"product_ids" json:"product_ids"`
+	CreatedBy  primitive.ObjectID   `bson:"created_by,omitempty" json:"-"`
+	CreatedAt  time.Time            `bson:"created_at" json:"created_at"`
+	ExpiresAt  time.Time            `bson:"expires_at" json:"expires_at"`
+}
+
+// Reasons the price of a product changes.
+const (
+	PriceListed      = "listed"
+	PriceSaleStarted = "sale_started"
+	PriceSaleEnded   = "sale_ended"
+	PriceChanged     = "price_changed"
+)
+
+// PriceChange is an entry of the price history of a product: what it sold for from ChangedAt on.
+type PriceChange struct {
+	ID             primitive.ObjectID `bson:"_id" json:"_id"`
+	ProductID      primitive.ObjectID `bson:"product_id" json:"product_id"`
+	RegularPrice   int                `bson:"regular_price" json:"regular_price"`
+	EffectivePrice int                `bson:"effective_price" json:"effective_price"`
+	PreviousPrice  int                `bson:"previous_price" json:"previous_price"`
+	OnSale         bool               `bson:"on_sale" json:"on_sale"`
+	Reason         string             `bson:"reason" json:"reason"`
+	ChangedAt      time.Time          `bson:"changed_at" json:"changed_at"`
+}
+
+// Orders a product listing can be sorted in.
+const (
+	SortNewest    = "newest"
+	SortPriceAsc  = "price_asc"
+	SortPriceDesc = "price_desc"
+	SortRating    = "rating"
+	SortPower     = "power_desc"
+	SortMileage   = "mileage_desc"
+)
+
+// SpecRange bounds a spec in its base unit; nil bounds are open.
+type SpecRange struct {
+	Min *float64
+	Max *float64
+}
+
+// ProductQuery selects one page of products. Empty and nil filters are not applied, CategoryID
+// also matches products of its subcategories, SpecRanges are keyed by the bson name of the spec,
+// and Cursor is the NextCursor of the previous page.
+type ProductQuery struct {
+	Category        string
+	CategoryID      primitive.ObjectID
+	CompanyName     string
+	FuelType        string
+	Color           string
+	Trim            string
+	Transmission    string
+	SeatingCapacity string
+	MinPrice        *int
+	MaxPrice        *int
+	InStock         *bool
+	OnSale          *bool
+	MinRating       *float64
+	SpecRanges      map[string]SpecRange
+	Sort            string
+	Limit           int
+	Cursor          string
+}
+
+// ProductPage is one page of a product listing. Total counts every product matching the filters.
+type ProductPage struct {
+	Products   []primitive.M
+	Total      int64
+	NextCursor string
+}
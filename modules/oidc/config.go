@@ -0,0 +1,60 @@
+package oidc
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Issuers of providers that only need credentials to be configured.
+var knownIssuers = map[string]string{
+	"google": "https://accounts.google.com",
+}
+
+var providerName = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+
+// FromEnv configures the providers listed in OIDC_PROVIDERS, e.g. "google,corp". Each provider
+// NAME is configured by OIDC_NAME_CLIENT_ID, OIDC_NAME_CLIENT_SECRET, OIDC_NAME_ISSUER (optional
+// for google), OIDC_NAME_SCOPES (space separated, default "openid email profile") and
+// OIDC_NAME_REDIRECT_URL, which defaults to the callback route under baseURL.
+func FromEnv(baseURL string) (map[string]*Provider, error) {
+	providers := map[string]*Provider{}
+
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !providerName.MatchString(name) {
+			return nil, fmt.Errorf("invalid OIDC provider name %q", name)
+		}
+
+		env := func(key string) string {
+			return strings.TrimSpace(os.Getenv("OIDC_" + strings.ToUpper(name) + "_" + key))
+		}
+
+		cfg := Config{
+			Name:         name,
+			Issuer:       env("ISSUER"),
+			ClientID:     env("CLIENT_ID"),
+			ClientSecret: env("CLIENT_SECRET"),
+			RedirectURL:  env("REDIRECT_URL"),
+			Scopes:       strings.Fields(env("SCOPES")),
+		}
+
+		if cfg.Issuer == "" {
+			cfg.Issuer = knownIssuers[name]
+		}
+		if cfg.Issuer == "" || cfg.ClientID == "" {
+			return nil, fmt.Errorf("OIDC provider %s needs an issuer and a client id", name)
+		}
+		if cfg.RedirectURL == "" {
+			cfg.RedirectURL = strings.TrimSuffix(baseURL, "/") + "/auth/oidc/" + name + "/callback"
+		}
+
+		providers[name] = New(cfg, nil)
+	}
+
+	return providers, nil
+}
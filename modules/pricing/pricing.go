@@ -0,0 +1,209 @@
+// Package pricing works out what products sell for. A sale runs from SaleStarts up to SaleEnds,
+// and one without a start or an end is open on that side. Sale times are instants, but admins
+// enter them as wall clock times of the store, or of the time zone of the product when it has
+// its own, which is also the zone the end of a running sale is shown in.
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/PraveenRajPurak/CarsGo-Backend/modules/model"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrBadTime is reported for sale times in none of the accepted layouts.
+var ErrBadTime = errors.New("not a date or time")
+
+// localLayouts are the layouts of sale times written without an offset.
+var localLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+const dateLayout = "2006-01-02"
+
+// Engine prices products in the time zone of the store.
+type Engine struct {
+	location *time.Location
+}
+
+func New(location *time.Location) *Engine {
+	if location == nil {
+		location = time.UTC
+	}
+	return &Engine{location: location}
+}
+
+// FromEnv reads the time zone of the store from STORE_TIMEZONE, an IANA name such as
+// "Asia/Kolkata". It defaults to UTC.
+func FromEnv() (*Engine, error) {
+	name := os.Getenv("STORE_TIMEZONE")
+	if name == "" {
+		return New(time.UTC), nil
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid STORE_TIMEZONE %q: %w", name, err)
+	}
+	return New(location), nil
+}
+
+// Location is the time zone of the store.
+func (e *Engine) Location() *time.Location {
+	return e.location
+}
+
+// Zone returns the time zone called name, or that of the store when name is empty or unknown.
+func (e *Engine) Zone(name string) *time.Location {
+	if name == "" {
+		return e.location
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return e.location
+	}
+	return location
+}
+
+// ParseTime reads a sale time. Times with an offset, e.g. "2026-11-01T09:00:00+05:30", are
+// taken as they are; those without, e.g. "2026-11-01T09:00", are wall clock times of location.
+// A date alone is the start of that day there, or for the end of a sale, the end of it. An
+// empty text is the zero time, leaving the sale open on that side.
+func ParseTime(text string, location *time.Location, end bool) (time.Time, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, text); err == nil {
+		return t.UTC(), nil
+	}
+	for _, layout := range localLayouts {
+		if t, err := time.ParseInLocation(layout, text, location); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	if t, err := time.ParseInLocation(dateLayout, text, location); err == nil {
+		if end {
+			// Midnight of the next day, wherever daylight saving time puts it.
+			t = t.AddDate(0, 0, 1)
+		}
+		return t.UTC(), nil
+	}
+
+	return time.Time{}, fmt.Errorf("%w: %q", ErrBadTime, text)
+}
+
+// Quote is what something sells for at a given time. SaleEndsAt is set while a sale with an end
+// runs, in the time zone of the sale.
+type Quote struct {
+	RegularPrice   int        `json:"regular_price"`
+	EffectivePrice int        `json:"effective_price"`
+	OnSale         bool       `json:"on_sale"`
+	SaleEndsAt     *time.Time `json:"sale_ends_at,omitempty"`
+}
+
+// SaleRunning reports whether the sale window of p is open at now.
+func SaleRunning(p model.Product, now time.Time) bool {
+	if !p.SaleStarts.IsZero() && now.Before(p.SaleStarts) {
+		return false
+	}
+	if !p.SaleEnds.IsZero() && !now.Before(p.SaleEnds) {
+		return false
+	}
+	return true
+}
+
+func (e *Engine) quote(p model.Product, regular int, sale int, now time.Time) Quote {
+	q := Quote{RegularPrice: regular, EffectivePrice: regular}
+	if sale <= 0 || sale >= regular || !SaleRunning(p, now) {
+		return q
+	}
+
+	q.EffectivePrice = sale
+	q.OnSale = true
+	if !p.SaleEnds.IsZero() {
+		ends := p.SaleEnds.In(e.Zone(p.SaleTimezone))
+		q.SaleEndsAt = &ends
+	}
+	return q
+}
+
+// Price is what p sells for at now. Products sold in variants go for their cheapest variant,
+// and are on sale while any of their variants is.
+func (e *Engine) Price(p model.Product, now time.Time) Quote {
+	if len(p.Variants) == 0 {
+		return e.quote(p, p.RegularPrice, p.SalePrice, now)
+	}
+
+	var cheapest Quote
+	var sale *Quote
+	for i, v := range p.Variants {
+		q := e.VariantPrice(p, v, now)
+		if i == 0 || q.EffectivePrice < cheapest.EffectivePrice {
+			cheapest = q
+		}
+		if q.OnSale && sale == nil {
+			sale = &q
+		}
+	}
+	if !cheapest.OnSale && sale != nil {
+		cheapest.OnSale = true
+		cheapest.SaleEndsAt = sale.SaleEndsAt
+	}
+	return cheapest
+}
+
+// VariantPrice is what variant v of p sells for at now. Variants are on sale during the sale
+// window of their product.
+func (e *Engine) VariantPrice(p model.Product, v model.Variant, now time.Time) Quote {
+	return e.quote(p, v.RegularPrice, v.SalePrice, now)
+}
+
+// ItemPrice is what an item of a cart or order sells for at now: the variant of p it names, or
+// p itself when it is not sold in variants. It reports false when the item names no such thing.
+func (e *Engine) ItemPrice(p model.Product, variantID primitive.ObjectID, now time.Time) (Quote, bool) {
+	if len(p.Variants) == 0 {
+		return e.quote(p, p.RegularPrice, p.SalePrice, now), variantID.IsZero()
+	}
+	if v := p.Variant(variantID); v != nil {
+		return e.VariantPrice(p, *v, now), true
+	}
+	return Quote{}, false
+}
+
+// Reprice compares what p sells for at now with the price stored on it, and returns the entry of
+// its price history to record when they differ. The regular price of a product on sale is not
+// what customers pay, so changing it alone is no change of price.
+func (e *Engine) Reprice(p model.Product, now time.Time) (model.PriceChange, bool) {
+	q := e.Price(p, now)
+
+	change := model.PriceChange{
+		ProductID:      p.ID,
+		RegularPrice:   q.RegularPrice,
+		EffectivePrice: q.EffectivePrice,
+		PreviousPrice:  p.EffectivePrice,
+		OnSale:         q.OnSale,
+		ChangedAt:      now,
+	}
+
+	switch {
+	case p.PricedAt.IsZero():
+		change.Reason = model.PriceListed
+	case q.OnSale && !p.OnSale:
+		change.Reason = model.PriceSaleStarted
+	case !q.OnSale && p.OnSale:
+		change.Reason = model.PriceSaleEnded
+	case q.EffectivePrice != p.EffectivePrice:
+		change.Reason = model.PriceChanged
+	default:
+		return change, false
+	}
+	return change, true
+}
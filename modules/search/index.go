@@ -0,0 +1,288 @@
+// Package search is an in memory full text index of the catalog. Documents are made of weighted
+// fields, queries are ranked with BM25, the last query term also matches as a prefix so results
+// follow what is being typed, and terms tolerate a typo or two depending on their length.
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// BM25 parameters. Documents are short, so term frequency saturates fast and length barely matters.
+const (
+	k1 = 1.2
+	b  = 0.3
+)
+
+// How much a query term matching an indexed term only as a prefix, or with typos, is worth
+// compared to an exact match.
+const (
+	prefixMatch = 0.7
+	typoMatch   = 0.5
+)
+
+// Field is one weighted part of a document, e.g. its name counting more than its description.
+type Field struct {
+	Text   string
+	Weight float64
+}
+
+// Hit is a document matching a query and how relevant it is.
+type Hit struct {
+	ID    string
+	Score float64
+}
+
+type document struct {
+	terms  map[string]float64
+	length float64
+}
+
+// Index is safe for concurrent use.
+type Index struct {
+	mu        sync.RWMutex
+	docs      map[string]*document
+	postings  map[string]map[string]float64
+	terms     []string
+	totalSize float64
+}
+
+func New() *Index {
+	return &Index{
+		docs:     map[string]*document{},
+		postings: map[string]map[string]float64{},
+	}
+}
+
+// Len returns the number of documents indexed.
+func (x *Index) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.docs)
+}
+
+// Put indexes the document id, replacing any previous version of it.
+func (x *Index) Put(id string, fields ...Field) {
+	doc := &document{terms: map[string]float64{}}
+	for _, f := range fields {
+		for _, term := range Tokenize(f.Text) {
+			doc.terms[term] += f.Weight
+			doc.length += f.Weight
+		}
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.remove(id)
+
+	x.docs[id] = doc
+	x.totalSize += doc.length
+	for term, weight := range doc.terms {
+		if x.postings[term] == nil {
+			x.postings[term] = map[string]float64{}
+			i := sort.SearchStrings(x.terms, term)
+			x.terms = append(x.terms, "")
+			copy(x.terms[i+1:], x.terms[i:])
+			x.terms[i] = term
+		}
+		x.postings[term][id] = weight
+	}
+}
+
+// Delete removes the document id, if indexed.
+func (x *Index) Delete(id string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.remove(id)
+}
+
+func (x *Index) remove(id string) {
+	doc, ok := x.docs[id]
+	if !ok {
+		return
+	}
+
+	for term := range doc.terms {
+		delete(x.postings[term], id)
+		if len(x.postings[term]) == 0 {
+			delete(x.postings, term)
+			i := sort.SearchStrings(x.terms, term)
+			x.terms = append(x.terms[:i], x.terms[i+1:]...)
+		}
+	}
+
+	x.totalSize -= doc.length
+	delete(x.docs, id)
+}
+
+// Search returns the hits for query from the most relevant, skipping offset of them and returning
+// at most limit, along with the number of documents matching it at all.
+func (x *Index) Search(query string, offset int, limit int) ([]Hit, int) {
+	tokens := Tokenize(query)
+	if len(tokens) == 0 {
+		return []Hit{}, 0
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	scores := map[string]float64{}
+	matched := map[string]int{}
+
+	for i, token := range tokens {
+		best := map[string]float64{}
+		for term, factor := range x.candidates(token, i == len(tokens)-1) {
+			idf := x.idf(term)
+			for id, weight := range x.postings[term] {
+				doc := x.docs[id]
+				norm := 1 - b + b*doc.length/x.averageLength()
+				score := factor * idf * weight * (k1 + 1) / (weight + k1*norm)
+				if score > best[id] {
+					best[id] = score
+				}
+			}
+		}
+		for id, score := range best {
+			scores[id] += score
+			matched[id]++
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		// Documents matching only some of the terms rank below those matching all of them.
+		coverage := float64(matched[id]) / float64(len(tokens))
+		hits = append(hits, Hit{ID: id, Score: score * coverage * coverage})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+
+	total := len(hits)
+	if offset >= total {
+		return []Hit{}, total
+	}
+	hits = hits[offset:]
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, total
+}
+
+// Suggest completes the last word of query with the indexed terms it starts, most common first,
+// keeping to terms found in documents that contain every previous word.
+func (x *Index) Suggest(query string, limit int) []string {
+	tokens := Tokenize(query)
+	if len(tokens) == 0 {
+		return []string{}
+	}
+	head, last := tokens[:len(tokens)-1], tokens[len(tokens)-1]
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	var within map[string]bool
+	for _, token := range head {
+		next := map[string]bool{}
+		for id := range x.postings[token] {
+			if within == nil || within[id] {
+				next[id] = true
+			}
+		}
+		within = next
+	}
+
+	type suggestion struct {
+		term  string
+		count int
+	}
+	var found []suggestion
+
+	for _, term := range x.prefixed(last) {
+		count := 0
+		for id := range x.postings[term] {
+			if within == nil || within[id] {
+				count++
+			}
+		}
+		if count > 0 {
+			found = append(found, suggestion{term: term, count: count})
+		}
+	}
+
+	sort.SliceStable(found, func(i, j int) bool { return found[i].count > found[j].count })
+
+	prefix := strings.Join(head, " ")
+	if prefix != "" {
+		prefix += " "
+	}
+
+	suggestions := []string{}
+	for _, s := range found {
+		if limit > 0 && len(suggestions) == limit {
+			break
+		}
+		suggestions = append(suggestions, prefix+s.term)
+	}
+	return suggestions
+}
+
+// candidates returns the indexed terms token matches and what each match is worth: the term
+// itself, the terms it is a prefix of when it is the word being typed, and the terms within a
+// few typos of it.
+func (x *Index) candidates(token string, typing bool) map[string]float64 {
+	found := map[string]float64{}
+	if _, ok := x.postings[token]; ok {
+		found[token] = 1
+	}
+
+	if typing {
+		for _, term := range x.prefixed(token) {
+			if _, ok := found[term]; !ok {
+				found[term] = prefixMatch
+			}
+		}
+	}
+
+	if typos := maxTypos(token); typos > 0 {
+		for _, term := range x.terms {
+			if _, ok := found[term]; ok {
+				continue
+			}
+			if d := distance(token, term, typos); d <= typos {
+				found[term] = typoMatch / float64(d)
+			}
+		}
+	}
+
+	return found
+}
+
+// prefixed returns the indexed terms starting with prefix, in order.
+func (x *Index) prefixed(prefix string) []string {
+	i := sort.SearchStrings(x.terms, prefix)
+	j := i
+	for j < len(x.terms) && strings.HasPrefix(x.terms[j], prefix) {
+		j++
+	}
+	return x.terms[i:j]
+}
+
+func (x *Index) idf(term string) float64 {
+	n, df := float64(len(x.docs)), float64(len(x.postings[term]))
+	return math.Log(1 + (n-df+0.5)/(df+0.5))
+}
+
+func (x *Index) averageLength() float64 {
+	if len(x.docs) == 0 || x.totalSize == 0 {
+		return 1
+	}
+	return x.totalSize / float64(len(x.docs))
+}
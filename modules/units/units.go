@@ -0,0 +1,281 @@
+// Package units parses, converts and formats the measured specifications of vehicles. Every
+// quantity has a base unit that values are stored and compared in: km/l, cc, bhp, km/h, mm and kg.
+package units
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Quantity is what a value measures.
+type Quantity string
+
+const (
+	FuelEconomy  Quantity = "fuel_economy"
+	Displacement Quantity = "displacement"
+	Power        Quantity = "power"
+	Speed        Quantity = "speed"
+	Length       Quantity = "length"
+	Mass         Quantity = "mass"
+)
+
+type Unit string
+
+const (
+	KmPerLitre     Unit = "km/l"
+	LitresPer100Km Unit = "l/100km"
+	MilesPerGallon Unit = "mpg"
+	CC             Unit = "cc"
+	Litre          Unit = "l"
+	CubicInch      Unit = "cu in"
+	BHP            Unit = "bhp"
+	HP             Unit = "hp"
+	PS             Unit = "ps"
+	KW             Unit = "kw"
+	KmPerHour      Unit = "km/h"
+	MilesPerHour   Unit = "mph"
+	MM             Unit = "mm"
+	CM             Unit = "cm"
+	Metre          Unit = "m"
+	Inch           Unit = "in"
+	Foot           Unit = "ft"
+	KG             Unit = "kg"
+	Pound          Unit = "lb"
+)
+
+// System is a set of units to show values in.
+type System string
+
+const (
+	Metric   System = "metric"
+	Imperial System = "imperial"
+)
+
+// unit is how to get from a unit to the base unit of its quantity: multiplying by factor, or
+// for units measuring the inverse of it, dividing factor by the value.
+type unit struct {
+	quantity Quantity
+	factor   float64
+	inverse  bool
+}
+
+var unitsByName = map[Unit]unit{
+	KmPerLitre:     {quantity: FuelEconomy, factor: 1},
+	LitresPer100Km: {quantity: FuelEconomy, factor: 100, inverse: true},
+	MilesPerGallon: {quantity: FuelEconomy, factor: 0.425143707},
+	CC:             {quantity: Displacement, factor: 1},
+	Litre:          {quantity: Displacement, factor: 1000},
+	CubicInch:      {quantity: Displacement, factor: 16.387064},
+	BHP:            {quantity: Power, factor: 1},
+	HP:             {quantity: Power, factor: 1},
+	PS:             {quantity: Power, factor: 0.98632},
+	KW:             {quantity: Power, factor: 1.34102},
+	KmPerHour:      {quantity: Speed, factor: 1},
+	MilesPerHour:   {quantity: Speed, factor: 1.609344},
+	MM:             {quantity: Length, factor: 1},
+	CM:             {quantity: Length, factor: 10},
+	Metre:          {quantity: Length, factor: 1000},
+	Inch:           {quantity: Length, factor: 25.4},
+	Foot:           {quantity: Length, factor: 304.8},
+	KG:             {quantity: Mass, factor: 1},
+	Pound:          {quantity: Mass, factor: 0.45359237},
+}
+
+// aliases are the ways units are written in the wild, e.g. in the free text specs products used
+// to be entered with.
+var aliases = map[string]Unit{
+	"km/l": KmPerLitre, "kmpl": KmPerLitre, "kpl": KmPerLitre, "km/litre": KmPerLitre, "km/liter": KmPerLitre,
+	"l/100km": LitresPer100Km, "l/100 km": LitresPer100Km, "litres/100km": LitresPer100Km, "mpg": MilesPerGallon,
+	"cc": CC, "cm3": CC, "cm³": CC,
+	"l": Litre, "litre": Litre, "liter": Litre, "litres": Litre, "liters": Litre,
+	"cu in": CubicInch, "cid": CubicInch, "in3": CubicInch, "in³": CubicInch,
+	"bhp": BHP, "hp": HP, "ps": PS, "cv": PS, "kw": KW,
+	"km/h": KmPerHour, "kmph": KmPerHour, "kph": KmPerHour, "kmh": KmPerHour, "mph": MilesPerHour,
+	"mm": MM, "cm": CM, "m": Metre, "metre": Metre, "meter": Metre, "metres": Metre, "meters": Metre,
+	"in": Inch, "inch": Inch, "inches": Inch, "\"": Inch, "ft": Foot, "feet": Foot, "foot": Foot,
+	"kg": KG, "kgs": KG, "kilogram": KG, "kilograms": KG,
+	"lb": Pound, "lbs": Pound, "pound": Pound, "pounds": Pound,
+}
+
+var bases = map[Quantity]Unit{
+	FuelEconomy:  KmPerLitre,
+	Displacement: CC,
+	Power:        BHP,
+	Speed:        KmPerHour,
+	Length:       MM,
+	Mass:         KG,
+}
+
+var imperial = map[Quantity]Unit{
+	FuelEconomy:  MilesPerGallon,
+	Displacement: CubicInch,
+	Power:        HP,
+	Speed:        MilesPerHour,
+	Length:       Inch,
+	Mass:         Pound,
+}
+
+// Errors Parse and Convert report.
+var (
+	ErrNoNumber    = errors.New("no number found")
+	ErrUnknownUnit = errors.New("unknown unit")
+	ErrMismatch    = errors.New("unit does not measure the quantity")
+)
+
+// Value is a measurement in a unit.
+type Value struct {
+	Value float64 `json:"value" bson:"value"`
+	Unit  Unit    `json:"unit" bson:"unit"`
+}
+
+func (v Value) String() string {
+	return strconv.FormatFloat(v.Value, 'f', -1, 64) + " " + string(v.Unit)
+}
+
+// Base returns the unit values of quantity are stored in.
+func Base(quantity Quantity) Unit {
+	return bases[quantity]
+}
+
+// Known reports whether quantity is one this package measures.
+func Known(quantity Quantity) bool {
+	_, ok := bases[quantity]
+	return ok
+}
+
+// number is the first number of a text and what follows it up to the next number or separator,
+// e.g. "88.5" and "bhp " in "88.5 bhp @ 6000 rpm". The 100 of "l/100km" belongs to the unit.
+var number = regexp.MustCompile(`(\d+(?:\.\d+)?|\.\d+)\s*((?:/\s*100|[^\d@,(;])*)`)
+
+// Parse reads a measurement of quantity written as free text, e.g. "18.5 kmpl", "1.2 L" or
+// "88.5 bhp @ 6000 rpm", and returns it in the base unit. A number alone is taken to be in the
+// base unit already.
+func Parse(text string, quantity Quantity) (Value, error) {
+	base, ok := bases[quantity]
+	if !ok {
+		return Value{}, fmt.Errorf("%w: %s", ErrMismatch, quantity)
+	}
+
+	// Thousands separators would otherwise split numbers like "1,197 cc" in two.
+	text = strings.ToLower(strings.ReplaceAll(text, ",", ""))
+
+	m := number.FindStringSubmatch(text)
+	if m == nil {
+		return Value{}, ErrNoNumber
+	}
+	n, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return Value{}, ErrNoNumber
+	}
+
+	name := strings.Join(strings.Fields(m[2]), " ")
+	if name == "" {
+		return Value{Value: n, Unit: base}, nil
+	}
+
+	u, ok := lookup(name)
+	if !ok {
+		return Value{}, fmt.Errorf("%w: %q", ErrUnknownUnit, name)
+	}
+
+	return Convert(Value{Value: n, Unit: u}, base)
+}
+
+// lookup finds the unit name starts with, so that "bhp at 6000 rpm" is read as bhp. Longer
+// names are tried first for "km/h" not to be read as "km".
+func lookup(name string) (Unit, bool) {
+	if u, ok := aliases[name]; ok {
+		return u, true
+	}
+
+	best, found := "", Unit("")
+	for alias, u := range aliases {
+		if len(alias) <= len(best) || !strings.HasPrefix(name, alias) {
+			continue
+		}
+		// The alias must be a whole word of name.
+		if rest := name[len(alias):]; rest != "" && rest[0] != ' ' && rest[0] != '/' && rest[0] != '.' {
+			continue
+		}
+		best, found = alias, u
+	}
+	return found, best != ""
+}
+
+// Convert returns v in unit to, which must measure the same quantity.
+func Convert(v Value, to Unit) (Value, error) {
+	from, ok := unitsByName[v.Unit]
+	if !ok {
+		return Value{}, fmt.Errorf("%w: %q", ErrUnknownUnit, v.Unit)
+	}
+	target, ok := unitsByName[to]
+	if !ok {
+		return Value{}, fmt.Errorf("%w: %q", ErrUnknownUnit, to)
+	}
+	if from.quantity != target.quantity {
+		return Value{}, fmt.Errorf("%w: %s to %s", ErrMismatch, v.Unit, to)
+	}
+
+	if v.Unit == to {
+		return v, nil
+	}
+
+	base := v.Value * from.factor
+	if from.inverse {
+		if v.Value == 0 {
+			return Value{}, fmt.Errorf("%w: %s of 0", ErrNoNumber, v.Unit)
+		}
+		base = from.factor / v.Value
+	}
+
+	converted := base / target.factor
+	if target.inverse {
+		if base == 0 {
+			return Value{}, fmt.Errorf("%w: %s of 0", ErrNoNumber, v.Unit)
+		}
+		converted = target.factor / base
+	}
+
+	return Value{Value: round(converted), Unit: to}, nil
+}
+
+// In returns v in the unit system shows its quantity in. Values in units this package does not
+// know are returned as they are.
+func (v Value) In(system System) Value {
+	u, ok := unitsByName[v.Unit]
+	if !ok {
+		return v
+	}
+
+	to := bases[u.quantity]
+	if system == Imperial {
+		to = imperial[u.quantity]
+	}
+
+	converted, err := Convert(v, to)
+	if err != nil {
+		return v
+	}
+	return converted
+}
+
+// FromSystem returns n, given in the unit system shows quantity in, in the base unit.
+func FromSystem(n float64, quantity Quantity, system System) float64 {
+	if system != Imperial {
+		return n
+	}
+	v, err := Convert(Value{Value: n, Unit: imperial[quantity]}, bases[quantity])
+	if err != nil {
+		return n
+	}
+	return v.Value
+}
+
+// round keeps two decimals, enough for any unit here without showing conversion noise.
+func round(n float64) float64 {
+	return math.Round(n*100) / 100
+}